@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/generated/clientset/versioned"
+	"github.com/NectGmbH/db-backup-controller/pkg/generated/informers/externalversions"
+)
+
+const runnerJobPollInterval = 10 * time.Second
+
+type (
+	// runnerBackupRequest mirrors the backup request expected by the
+	// runner
+	runnerBackupRequest struct {
+		JobID string `json:"jobID,omitempty"`
+	}
+
+	// runnerJob mirrors the job status reported by the runner
+	runnerJob struct {
+		ID         string     `json:"id"`
+		Status     string     `json:"status"`
+		Error      string     `json:"error,omitempty"`
+		Backup     string     `json:"backup,omitempty"`
+		Size       int64      `json:"size,omitempty"`
+		StartedAt  time.Time  `json:"startedAt"`
+		FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	}
+)
+
+// RegisterDatabaseBackupRunInformer registers the required event
+// handlers to handle changes on databaseBackupRun CRDs
+func (c *controller) RegisterDatabaseBackupRunInformer(factory externalversions.SharedInformerFactory) error {
+	informer := factory.Backup().V1().DatabaseBackupRuns().Informer()
+
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) { c.enqueue(obj, queueEntryActionSyncRun, "databaseBackupRun added") },
+		UpdateFunc: func(oldObj, obj any) {
+			oldRun, okOld := oldObj.(*v1.DatabaseBackupRun)
+			run, ok := obj.(*v1.DatabaseBackupRun)
+			if okOld && ok && oldRun.ResourceVersion == run.ResourceVersion && run.Status.Phase == v1.DatabaseBackupRunPhaseRunning {
+				// Periodic resync of a running run: It is already being
+				// polled, no need to start another poll
+				return
+			}
+
+			c.enqueue(obj, queueEntryActionSyncRun, "databaseBackupRun updated")
+		},
+	}); err != nil {
+		return errors.Wrap(err, "adding event handlers")
+	}
+
+	logrus.Info("registered DatabaseBackupRuns handlers")
+
+	c.informerSyncs = append(c.informerSyncs, informer.HasSynced)
+	return nil
+}
+
+// handleDatabaseBackupRunSync starts the backup for a new
+// DatabaseBackupRun and follows the runner job until it is finished
+func (c controller) handleDatabaseBackupRunSync(q *queueEntry) error {
+	q.Logger().Debug("handling DatabaseBackupRun sync")
+
+	run, err := q.FetchRun(c.crdClient)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			// Gone in the meantime, nothing to follow
+			return nil
+		}
+		return err
+	}
+
+	if run.DeletionTimestamp != nil || run.Status.IsFinished() {
+		// Finished runs are kept as a record, nothing to do anymore
+		return nil
+	}
+
+	_, err = c.crdClient.BackupV1().DatabaseBackups(run.Namespace).Get(context.Background(), run.Spec.DatabaseBackup, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		// Backup exists, we can talk to its runner
+
+	case k8sErrors.IsNotFound(err):
+		run.Status.SetFinished(run.Generation, metav1.Now(), fmt.Sprintf("DatabaseBackup %s not found", run.Spec.DatabaseBackup))
+		return c.updateRunStatus(run)
+
+	default:
+		return errors.Wrap(err, "fetching databaseBackup")
+	}
+
+	rssName, err := c.deriveName(run.Namespace, run.Spec.DatabaseBackup)
+	if err != nil {
+		return errors.Wrap(err, "generating resource name")
+	}
+
+	if run.Status.Phase == v1.DatabaseBackupRunPhaseRunning {
+		return c.pollDatabaseBackupRun(q, run, rssName)
+	}
+
+	return c.startDatabaseBackupRun(q, run, rssName)
+}
+
+// pollDatabaseBackupRun checks the runner job of the run and writes
+// the result into the status when finished or polls again later
+func (c controller) pollDatabaseBackupRun(q *queueEntry, run *v1.DatabaseBackupRun, rssName string) error {
+	var job runnerJob
+	status, err := c.callRunner(rssName, http.MethodGet, "/jobs/"+run.Status.JobID, nil, http.StatusOK, &job)
+	switch {
+	case err == nil:
+		// Got the job status
+
+	case status == http.StatusNotFound:
+		// The runner does not keep the jobs across restarts
+		run.Status.SetFinished(run.Generation, metav1.Now(), "job got lost in the runner (was the runner restarted?)")
+		return c.updateRunStatus(run)
+
+	default:
+		// The runner might be unreachable for a moment, we ask again
+		q.Logger().WithError(err).Warn("fetching job status from runner")
+		c.pollLater(q, "databaseBackupRun poll")
+		return nil
+	}
+
+	if job.FinishedAt == nil {
+		c.pollLater(q, "databaseBackupRun poll")
+		return nil
+	}
+
+	run.Status.BackupName = job.Backup
+	run.Status.Size = job.Size
+	run.Status.SetFinished(run.Generation, metav1.NewTime(*job.FinishedAt), job.Error)
+
+	return c.updateRunStatus(run)
+}
+
+// pollLater enqueues a fresh entry for the same action and object to
+// check the runner job again after the poll interval. Every sync of a
+// running object ends up here (i.e. triggered by status updates), so
+// only one poll is kept pending per object: Otherwise each of them
+// would start another poll chain.
+func (c controller) pollLater(q *queueEntry, reason string) {
+	if _, pending := c.pollsPending.LoadOrStore(q.pollKey(), struct{}{}); pending {
+		return
+	}
+
+	c.queue.AddAfter(&queueEntry{
+		action:   q.action,
+		key:      q.key,
+		poll:     true,
+		queuedAt: time.Now(),
+		reason:   reason,
+	}, runnerJobPollInterval)
+}
+
+// startDatabaseBackupRun requests the runner to start a backup and
+// marks the run as running. The UID of the run is used as job ID so
+// the runner returns the existing job when we retry after failing to
+// update the status instead of starting another backup.
+func (c controller) startDatabaseBackupRun(q *queueEntry, run *v1.DatabaseBackupRun, rssName string) error {
+	body, err := json.Marshal(runnerBackupRequest{JobID: string(run.UID)})
+	if err != nil {
+		return errors.Wrap(err, "marshalling backup request")
+	}
+
+	var job runnerJob
+	status, err := c.callRunner(rssName, http.MethodPost, "/backups", bytes.NewReader(body), http.StatusCreated, &job)
+	if err != nil {
+		if status == http.StatusConflict {
+			err = errors.New("another action is running in the runner")
+		}
+
+		if run.Status.Phase == "" {
+			// Make the run visible as pending while retrying to start it
+			run.Status.Phase = v1.DatabaseBackupRunPhasePending
+			if updErr := c.updateRunStatus(run); updErr != nil {
+				q.Logger().WithError(updErr).Error("updating status")
+			}
+		}
+
+		return errors.Wrap(err, "starting backup in runner")
+	}
+
+	q.Logger().WithField("job", job.ID).Info("started backup for databaseBackupRun")
+
+	// The status update triggers the next sync which starts polling
+	run.Status.SetRunning(job.ID, metav1.NewTime(job.StartedAt))
+	return c.updateRunStatus(run)
+}
+
+func (c controller) updateRunStatus(run *v1.DatabaseBackupRun) error {
+	_, err := c.crdClient.BackupV1().
+		DatabaseBackupRuns(run.Namespace).
+		UpdateStatus(context.Background(), run, metav1.UpdateOptions{})
+	return errors.Wrap(err, "updating status")
+}
+
+func (q queueEntry) FetchRun(crdClient versioned.Interface) (*v1.DatabaseBackupRun, error) {
+	ns, name, err := cache.SplitMetaNamespaceKey(q.key)
+	if err != nil {
+		return nil, errors.Wrap(err, "splitting namespace key")
+	}
+
+	run, err := crdClient.BackupV1().DatabaseBackupRuns(ns).Get(context.Background(), name, metav1.GetOptions{})
+	return run, errors.Wrap(err, "fetching backup run object")
+}
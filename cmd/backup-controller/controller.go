@@ -3,11 +3,14 @@ package main
 import (
 	"context"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
@@ -18,6 +21,7 @@ import (
 	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
 	"github.com/NectGmbH/db-backup-controller/pkg/generated/clientset/versioned"
 	"github.com/NectGmbH/db-backup-controller/pkg/generated/informers/externalversions"
+	listers "github.com/NectGmbH/db-backup-controller/pkg/generated/listers/apis/v1"
 )
 
 type (
@@ -25,13 +29,26 @@ type (
 		crdClient  versioned.Interface
 		kubeClient kubernetes.Interface
 
-		informerSyncs []cache.InformerSynced
-		queue         workqueue.RateLimitingInterface
+		backupIndexer       cache.Indexer
+		backupLister        listers.DatabaseBackupLister
+		informerSyncs       []cache.InformerSynced
+		pollsPending        *sync.Map
+		queue               workqueue.RateLimitingInterface
+		restoreLister       listers.DatabaseRestoreLister
+		statusSyncsPending  *sync.Map
+		storageClassIndexer cache.Indexer
+	}
+
+	// pollKey identifies the object a runner job poll is pending for
+	pollKey struct {
+		action queueEntryAction
+		key    string
 	}
 
 	queueEntry struct {
 		action   queueEntryAction
 		key      string
+		poll     bool
 		queuedAt time.Time
 		reason   string
 	}
@@ -43,6 +60,9 @@ const (
 	queueEntryActionAdd queueEntryAction = iota
 	queueEntryActionUpdate
 	queueEntryActionDelete
+	queueEntryActionSyncStatus
+	queueEntryActionSyncRun
+	queueEntryActionSyncRestore
 )
 
 func newController(
@@ -53,7 +73,9 @@ func newController(
 		crdClient:  crdClient,
 		kubeClient: kubeClient,
 
-		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "com.nect.db-backup"),
+		pollsPending:       new(sync.Map),
+		queue:              workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "com.nect.db-backup"),
+		statusSyncsPending: new(sync.Map),
 	}
 }
 
@@ -62,6 +84,13 @@ func newController(
 func (c *controller) RegisterDatabaseBackupInformer(factory externalversions.SharedInformerFactory) error {
 	informer := factory.Backup().V1().DatabaseBackups().Informer()
 
+	if err := informer.AddIndexers(cache.Indexers{
+		backupIndexSecret:       indexBackupBySecret,
+		backupIndexStorageClass: indexBackupByStorageClass,
+	}); err != nil {
+		return errors.Wrap(err, "adding indexers")
+	}
+
 	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj any) { c.enqueue(obj, queueEntryActionAdd, "databaseBackup added") },
 		// We don't care about it being gone, we're acting on the update
@@ -73,6 +102,8 @@ func (c *controller) RegisterDatabaseBackupInformer(factory externalversions.Sha
 
 	logrus.Info("registered DatabaseBackups handlers")
 
+	c.backupIndexer = informer.GetIndexer()
+	c.backupLister = factory.Backup().V1().DatabaseBackups().Lister()
 	c.informerSyncs = append(c.informerSyncs, informer.HasSynced)
 	return nil
 }
@@ -82,16 +113,20 @@ func (c *controller) RegisterDatabaseBackupInformer(factory externalversions.Sha
 func (c *controller) RegisterDatabaseBackupStorageClassInformer(factory externalversions.SharedInformerFactory) error {
 	informer := factory.Backup().V1().DatabaseBackupStorageClasses().Informer()
 
-	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		// We don't care about "Add", needs to have a reference
-		DeleteFunc: func(obj any) { c.enqueue(obj, queueEntryActionUpdate, "databaseBackupStorageClass deleted") },
-		UpdateFunc: func(_, obj any) { c.enqueue(obj, queueEntryActionUpdate, "databaseBackupStorageClass updated") },
-	}); err != nil {
+	if err := informer.AddIndexers(cache.Indexers{storageClassIndexSecret: indexStorageClassBySecret}); err != nil {
+		return errors.Wrap(err, "adding indexers")
+	}
+
+	// All events are passed to the DatabaseBackups using the storage
+	// class: "Add" is required for those created before their storage
+	// class which could not be reconciled until now
+	if _, err := informer.AddEventHandler(c.dependencyEventHandler("databaseBackupStorageClass")); err != nil {
 		return errors.Wrap(err, "adding event handlers")
 	}
 
 	logrus.Info("registered DatabaseBackupStorageClasses handlers")
 
+	c.storageClassIndexer = informer.GetIndexer()
 	c.informerSyncs = append(c.informerSyncs, informer.HasSynced)
 	return nil
 }
@@ -101,11 +136,13 @@ func (c *controller) RegisterDatabaseBackupStorageClassInformer(factory external
 func (c *controller) RegisterSecretInformer(factory informers.SharedInformerFactory) error {
 	informer := factory.Core().V1().Secrets().Informer()
 
-	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		// We don't care about "Add", needs to have a reference
-		DeleteFunc: func(obj any) { c.enqueue(obj, queueEntryActionUpdate, "secret deleted") },
-		UpdateFunc: func(_, obj any) { c.enqueue(obj, queueEntryActionUpdate, "secret updated") },
-	}); err != nil {
+	if err := informer.SetTransform(stripSecretData); err != nil {
+		return errors.Wrap(err, "setting transform")
+	}
+
+	// All events are passed to the DatabaseBackups referencing the
+	// secret directly or through their storage class
+	if _, err := informer.AddEventHandler(c.dependencyEventHandler("secret")); err != nil {
 		return errors.Wrap(err, "adding event handlers")
 	}
 
@@ -129,11 +166,26 @@ func (c *controller) Run(workers int, stopCh <-chan struct{}) {
 
 	logrus.WithField("workers", workers).Info("starting controller workers")
 
+	var workersDone sync.WaitGroup
 	for i := 0; i < workers; i++ {
-		go wait.Until(c.runWorker, time.Second, stopCh)
+		workersDone.Add(1)
+		go func() {
+			defer workersDone.Done()
+			wait.Until(c.runWorker, time.Second, stopCh)
+		}()
+	}
+
+	if cfg.StatusInterval > 0 {
+		go wait.Until(c.enqueueStatusSync, cfg.StatusInterval, stopCh)
 	}
 
 	<-stopCh
+
+	// Stop handing out entries and let the workers finish the ones
+	// they are working on: Handing over to another leader must not
+	// leave half-done updates behind
+	c.queue.ShutDown()
+	workersDone.Wait()
 }
 
 func (c *controller) enqueue(obj any, action queueEntryAction, reason string) {
@@ -143,7 +195,13 @@ func (c *controller) enqueue(obj any, action queueEntryAction, reason string) {
 		err  error
 	)
 
-	switch obj.(type) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		// We missed the deletion, the last known state is good enough
+		// to find the dependents
+		obj = tombstone.Obj
+	}
+
+	switch o := obj.(type) {
 	case *v1.DatabaseBackup:
 		if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
 			// We don't log the object for security reasons: It might be a secret!
@@ -153,17 +211,57 @@ func (c *controller) enqueue(obj any, action queueEntryAction, reason string) {
 
 		keys = []string{key}
 
+	case *v1.DatabaseBackupRun, *v1.DatabaseRestore:
+		if key, err = cache.MetaNamespaceKeyFunc(obj); err != nil {
+			utilruntime.HandleError(errors.Wrap(err, "getting key for object"))
+			return
+		}
+
+		keys = []string{key}
+
+	case *v1.DatabaseBackupStorageClass:
+		keys = c.backupKeysForStorageClass(o.Name)
+
+	case *corev1.Secret:
+		keys = c.backupKeysForSecret(o.Namespace, o.Name)
+
 	default:
 		utilruntime.HandleError(errors.Errorf("received %T but not prepared to handle", obj))
 	}
 
 	for _, key = range keys {
 		q := &queueEntry{action: action, key: key, queuedAt: time.Now(), reason: reason}
-		q.Logger().Debug("enqueing databaseBackup for processing")
+		q.Logger().Debug("enqueing object for processing")
 		c.queue.Add(q)
 	}
 }
 
+// enqueueStatusSync enqueues all known DatabaseBackups to fetch the
+// backup results from their runners. Backups still having a sync
+// pending (i.e. retrying an unreachable runner) are skipped as the
+// queue would otherwise grow with every interval.
+func (c *controller) enqueueStatusSync() {
+	backups, err := c.backupLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(errors.Wrap(err, "listing databaseBackups"))
+		return
+	}
+
+	for _, b := range backups {
+		key, err := cache.MetaNamespaceKeyFunc(b)
+		if err != nil {
+			utilruntime.HandleError(errors.Wrap(err, "getting key for object"))
+			continue
+		}
+
+		if _, pending := c.statusSyncsPending.LoadOrStore(key, struct{}{}); pending {
+			continue
+		}
+
+		c.enqueue(b, queueEntryActionSyncStatus, "status sync")
+	}
+}
+
 func (c *controller) processNextWorkItem() bool {
 	qei, quit := c.queue.Get()
 	if quit {
@@ -171,6 +269,12 @@ func (c *controller) processNextWorkItem() bool {
 	}
 	defer c.queue.Done(qei)
 
+	if c.queue.ShuttingDown() {
+		// Remaining entries are not processed anymore, the informers of
+		// the next leader will enqueue the objects again
+		return false
+	}
+
 	qe, ok := qei.(*queueEntry)
 	if !ok {
 		// However this happened: Queue did not contain valid entry
@@ -178,6 +282,11 @@ func (c *controller) processNextWorkItem() bool {
 		return true
 	}
 
+	if qe.poll {
+		// The poll is running now, the handler may schedule the next one
+		c.pollsPending.Delete(qe.pollKey())
+	}
+
 	var handlerFn func(*queueEntry) error
 	switch qe.action {
 	case queueEntryActionAdd:
@@ -188,17 +297,30 @@ func (c *controller) processNextWorkItem() bool {
 
 	case queueEntryActionDelete:
 		handlerFn = c.handleDatabaseBackupDelete
+
+	case queueEntryActionSyncStatus:
+		handlerFn = c.handleDatabaseBackupSyncStatus
+
+	case queueEntryActionSyncRun:
+		handlerFn = c.handleDatabaseBackupRunSync
+
+	case queueEntryActionSyncRestore:
+		handlerFn = c.handleDatabaseRestoreSync
 	}
 
 	if err := handlerFn(qe); err != nil {
 		if qe.queuedAt.After(time.Now().Add(-cfg.RescanInterval)) {
 			qe.Logger().WithError(err).Error("handling queue entry")
 			c.queue.AddRateLimited(qei)
-		} else {
-			qe.Logger().WithError(err).Error("handling queue entry, dropping after entry timeout")
+			return true
 		}
 
-		return true
+		qe.Logger().WithError(err).Error("handling queue entry, dropping after entry timeout")
+	}
+
+	if qe.action == queueEntryActionSyncStatus {
+		// Entry is done, the next interval may enqueue it again
+		c.statusSyncsPending.Delete(qe.key)
 	}
 
 	c.queue.Forget(qei)
@@ -228,4 +350,6 @@ func (q queueEntry) Logger() *logrus.Entry {
 	})
 }
 
+func (q queueEntry) pollKey() pollKey { return pollKey{action: q.action, key: q.key} }
+
 func (q queueEntry) String() string { return strings.Join([]string{q.reason, q.key}, ": ") }
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+)
+
+const (
+	// backupIndexSecret indexes DatabaseBackups by the namespaced
+	// names of the secrets they reference
+	backupIndexSecret = "secret"
+	// backupIndexStorageClass indexes DatabaseBackups by the name of
+	// their storage class
+	backupIndexStorageClass = "storageClass"
+	// storageClassIndexSecret indexes DatabaseBackupStorageClasses by
+	// the names of the secrets they reference (located in the target
+	// namespace)
+	storageClassIndexSecret = "secret"
+)
+
+func indexBackupBySecret(obj any) ([]string, error) {
+	b, ok := obj.(*v1.DatabaseBackup)
+	if !ok {
+		return nil, errors.Errorf("expected DatabaseBackup, got %T", obj)
+	}
+
+	var keys []string
+	for _, name := range b.Spec.SecretNames() {
+		keys = append(keys, b.Namespace+"/"+name)
+	}
+
+	return keys, nil
+}
+
+func indexBackupByStorageClass(obj any) ([]string, error) {
+	b, ok := obj.(*v1.DatabaseBackup)
+	if !ok {
+		return nil, errors.Errorf("expected DatabaseBackup, got %T", obj)
+	}
+
+	return []string{b.Spec.BackupStorageClass}, nil
+}
+
+func indexStorageClassBySecret(obj any) ([]string, error) {
+	sc, ok := obj.(*v1.DatabaseBackupStorageClass)
+	if !ok {
+		return nil, errors.Errorf("expected DatabaseBackupStorageClass, got %T", obj)
+	}
+
+	return sc.Spec.SecretNames(), nil
+}
+
+// stripSecretData removes the contents of secrets before they are
+// stored in the informer cache: We only need to know they changed
+// and don't want to keep all secrets of the cluster in memory
+func stripSecretData(obj any) (any, error) {
+	if s, ok := obj.(*corev1.Secret); ok {
+		s.Data = nil
+		s.StringData = nil
+	}
+
+	return obj, nil
+}
+
+// dependencyEventHandler enqueues an update of the DatabaseBackups
+// depending on the object for every event. Periodic resyncs are
+// skipped as the DatabaseBackups are resynced themselves.
+func (c *controller) dependencyEventHandler(kind string) cache.ResourceEventHandlerFuncs {
+	reason := func(obj any, event string) string {
+		// Key is only used for logging, an empty one does no harm
+		key, _ := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		return strings.Join([]string{kind, key, event}, " ")
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { c.enqueue(obj, queueEntryActionUpdate, reason(obj, "added")) },
+		DeleteFunc: func(obj any) { c.enqueue(obj, queueEntryActionUpdate, reason(obj, "deleted")) },
+		UpdateFunc: func(oldObj, obj any) {
+			oldMeta, okOld := oldObj.(metav1.Object)
+			newMeta, ok := obj.(metav1.Object)
+			if okOld && ok && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+				return
+			}
+
+			c.enqueue(obj, queueEntryActionUpdate, reason(obj, "updated"))
+		},
+	}
+}
+
+// backupKeysForSecret returns the keys of all DatabaseBackups using
+// the given secret either directly or through their storage class
+func (c *controller) backupKeysForSecret(namespace, name string) []string {
+	keys := c.backupKeysByIndex(backupIndexSecret, namespace+"/"+name)
+
+	if namespace == cfg.TargetNamespace && c.storageClassIndexer != nil {
+		storageClasses, err := c.storageClassIndexer.ByIndex(storageClassIndexSecret, name)
+		if err != nil {
+			utilruntime.HandleError(errors.Wrap(err, "looking up storage classes for secret"))
+		}
+
+		for _, obj := range storageClasses {
+			if sc, ok := obj.(*v1.DatabaseBackupStorageClass); ok {
+				keys = append(keys, c.backupKeysForStorageClass(sc.Name)...)
+			}
+		}
+	}
+
+	return uniqueSorted(keys)
+}
+
+// backupKeysForStorageClass returns the keys of all DatabaseBackups
+// stored into the given storage class
+func (c *controller) backupKeysForStorageClass(name string) []string {
+	return c.backupKeysByIndex(backupIndexStorageClass, name)
+}
+
+func (c *controller) backupKeysByIndex(index, value string) (keys []string) {
+	if c.backupIndexer == nil {
+		return nil
+	}
+
+	backups, err := c.backupIndexer.ByIndex(index, value)
+	if err != nil {
+		utilruntime.HandleError(errors.Wrapf(err, "looking up databaseBackups by %s", index))
+		return nil
+	}
+
+	for _, obj := range backups {
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			utilruntime.HandleError(errors.Wrap(err, "getting key for object"))
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func uniqueSorted(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+
+	for _, s := range in {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// isLeader reflects whether this instance is currently processing the
+// work queue and is used to report the readiness
+var isLeader atomic.Bool
+
+// handleHealthz reports the process to be alive
+func handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleReadyz reports the instance to be ready as long as it is the
+// leader: Standby instances are not doing any work
+func handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	if !isLeader.Load() {
+		http.Error(w, "not leading", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runWithLeaderElection waits for this instance to become the leader
+// and then executes run until the context is cancelled or leadership
+// is lost. On cancellation run is given the chance to finish its work
+// before the lease is released so the next leader can take over
+// immediately. Losing the lease terminates the process as another
+// instance might already be working on the same objects.
+func runWithLeaderElection(ctx context.Context, kubeClient kubernetes.Interface, run func(stopCh <-chan struct{})) error {
+	identity, err := os.Hostname()
+	if err != nil {
+		return errors.Wrap(err, "getting hostname as identity")
+	}
+
+	namespace := cfg.LeaseNamespace
+	if namespace == "" {
+		namespace = cfg.TargetNamespace
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: cfg.LeaseName, Namespace: namespace},
+		Client:     kubeClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+
+	// The election gets its own context: When shutting down the lease
+	// must only be released after run has finished
+	electionCtx, cancelElection := context.WithCancel(context.Background())
+	defer cancelElection()
+
+	go func() {
+		<-ctx.Done()
+		if !isLeader.Load() {
+			// Standby, nothing to wait for
+			cancelElection()
+		}
+	}()
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   cfg.LeaseDuration,
+		RenewDeadline:   cfg.LeaseRenewal,
+		RetryPeriod:     cfg.LeaseRetry,
+		ReleaseOnCancel: true,
+		Name:            cfg.LeaseName,
+
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				defer cancelElection()
+
+				isLeader.Store(true)
+				defer isLeader.Store(false)
+
+				logrus.WithField("identity", identity).Info("acquired leadership")
+
+				stopCh := make(chan struct{})
+				go func() {
+					select {
+					case <-ctx.Done():
+					case <-leaderCtx.Done():
+					}
+					close(stopCh)
+				}()
+
+				run(stopCh)
+			},
+
+			OnStoppedLeading: func() {
+				if ctx.Err() == nil {
+					logrus.WithField("identity", identity).Fatal("lost leadership")
+				}
+
+				logrus.WithField("identity", identity).Info("stopped leader election")
+			},
+
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					logrus.WithField("leader", leader).Info("new leader elected")
+				}
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "creating leader elector")
+	}
+
+	elector.Run(electionCtx)
+	return nil
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/bombsimon/logrusr/v4"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
@@ -27,12 +31,22 @@ var (
 		ImagePrefix     string        `flag:"image-prefix" default:"" description:"Base of the engine image to start"`
 		JSONLog         bool          `flag:"json-log" default:"false" description:"enable json-logging"`
 		Kubeconfig      string        `flag:"kubeconfig" default:"" description:"Path to a kubeconfig. Only required if out-of-cluster."`
+		LeaderElect     bool          `flag:"leader-elect" default:"true" description:"Elect a leader using a Lease to allow multiple replicas"`
+		LeaseDuration   time.Duration `flag:"lease-duration" default:"15s" description:"How long a Lease is valid without being renewed"`
+		LeaseName       string        `flag:"lease-name" default:"db-backup-controller" description:"Name of the Lease used for leader election"`
+		LeaseNamespace  string        `flag:"lease-namespace" default:"" description:"Where to create the Lease (defaults to target-namespace)"`
+		LeaseRenewal    time.Duration `flag:"lease-renew-deadline" default:"10s" description:"How long the leader retries renewing the Lease"`
+		LeaseRetry      time.Duration `flag:"lease-retry-period" default:"2s" description:"Wait time between tries to acquire / renew the Lease"`
 		Listen          string        `flag:"listen" default:":3000" description:"Port/IP to listen on"`
 		LogLevel        string        `flag:"log-level" default:"info" description:"Log level (debug, info, warn, error, fatal)"`
 		Master          string        `flag:"master" default:"" description:"The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster."` //nolint:lll // no way to shorten
 		RescanInterval  time.Duration `flag:"rescan-interval" default:"1h" description:"How often to re-scan existing resources without events"`
+		StatusInterval  time.Duration `flag:"status-interval" default:"1m" description:"How often to fetch runner backup results (0 = off)"`
 		TargetNamespace string        `flag:"target-namespace" default:"" description:"Where to create the backup resources"`
 		VersionAndExit  bool          `flag:"version" default:"false" description:"Prints current version and exits"`
+		WebhookListen   string        `flag:"webhook-listen" default:":3443" description:"Port/IP to serve the admission webhook on"`
+		WebhookTLSCert  string        `flag:"webhook-tls-cert" default:"" description:"TLS cert of the webhook server (empty = off)"`
+		WebhookTLSKey   string        `flag:"webhook-tls-key" default:"" description:"TLS key of the webhook server"`
 	}{}
 
 	version = "dev"
@@ -50,6 +64,14 @@ func initApp() error {
 	}
 	logrus.SetLevel(l)
 
+	if cfg.LeaderElect && cfg.LeaseNamespace == "" && cfg.TargetNamespace == "" {
+		return errors.New("lease-namespace or target-namespace is required for leader election")
+	}
+
+	if cfg.WebhookTLSCert != "" && cfg.WebhookTLSKey == "" {
+		return errors.New("webhook-tls-key is required when webhook-tls-cert is set")
+	}
+
 	if cfg.JSONLog {
 		logrus.SetFormatter(&logrus.JSONFormatter{})
 	}
@@ -86,6 +108,7 @@ func main() {
 	}
 
 	crdInformerFactory := externalversions.NewSharedInformerFactory(crdClient, cfg.RescanInterval)
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, cfg.RescanInterval)
 
 	ctrl := newController(
 		crdClient,
@@ -96,10 +119,29 @@ func main() {
 		logrus.WithError(err).Fatal("registering databaseBackup informer")
 	}
 
-	stopCh := make(chan struct{})
-	defer close(stopCh)
+	if err = ctrl.RegisterDatabaseBackupStorageClassInformer(crdInformerFactory); err != nil {
+		logrus.WithError(err).Fatal("registering databaseBackupStorageClass informer")
+	}
+
+	if err = ctrl.RegisterSecretInformer(kubeInformerFactory); err != nil {
+		logrus.WithError(err).Fatal("registering secret informer")
+	}
+
+	if err = ctrl.RegisterDatabaseBackupRunInformer(crdInformerFactory); err != nil {
+		logrus.WithError(err).Fatal("registering databaseBackupRun informer")
+	}
+
+	if err = ctrl.RegisterDatabaseRestoreInformer(crdInformerFactory); err != nil {
+		logrus.WithError(err).Fatal("registering databaseRestore informer")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
 
 	// metricsHandler.AddHandler()
+	http.HandleFunc("/healthz", handleHealthz)
+	http.HandleFunc("/readyz", handleReadyz)
+
 	go func() {
 		server := &http.Server{
 			Addr:              cfg.Listen,
@@ -112,8 +154,39 @@ func main() {
 		}
 	}()
 
+	if cfg.WebhookTLSCert != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/validate/databasebackups", ctrl.handleValidateDatabaseBackup)
+			mux.HandleFunc("/validate/databasebackupstorageclasses", ctrl.handleValidateDatabaseBackupStorageClass)
+
+			server := &http.Server{
+				Addr:              cfg.WebhookListen,
+				Handler:           mux,
+				ReadHeaderTimeout: time.Second,
+			}
+
+			if err := server.ListenAndServeTLS(cfg.WebhookTLSCert, cfg.WebhookTLSKey); err != nil {
+				logrus.WithError(err).Fatal("webhook server reported error")
+			}
+		}()
+	}
+
 	logrus.WithField("version", version).Info("backup-controller started")
 
-	crdInformerFactory.Start(stopCh)
-	ctrl.Run(workerProcessCount, stopCh)
+	run := func(stopCh <-chan struct{}) {
+		crdInformerFactory.Start(stopCh)
+		kubeInformerFactory.Start(stopCh)
+		ctrl.Run(workerProcessCount, stopCh)
+	}
+
+	if !cfg.LeaderElect {
+		isLeader.Store(true)
+		run(ctx.Done())
+		return
+	}
+
+	if err = runWithLeaderElection(ctx, kubeClient, run); err != nil {
+		logrus.WithError(err).Fatal("running leader election")
+	}
 }
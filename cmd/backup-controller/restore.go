@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/generated/clientset/versioned"
+	"github.com/NectGmbH/db-backup-controller/pkg/generated/informers/externalversions"
+)
+
+type (
+	// restoreClaim records a claim written by this controller which
+	// might not have reached the informer cache yet
+	restoreClaim struct {
+		key    string
+		target string
+	}
+
+	// runnerRestoreRequest mirrors the restore request expected by the
+	// runner
+	runnerRestoreRequest struct {
+		JobID      string            `json:"jobID,omitempty"`
+		Mode       string            `json:"mode"`
+		Backup     string            `json:"backup"`
+		Identities string            `json:"identities,omitempty"`
+		Location   *int              `json:"location,omitempty"`
+		Target     *v1.RestoreTarget `json:"target,omitempty"`
+	}
+)
+
+var (
+	// restoreStartLock serializes checking for conflicting restores and
+	// claiming the target database across the workers
+	restoreStartLock sync.Mutex
+	// restoreClaims contains the claims written until the informer
+	// cache contains them, guarded by restoreStartLock
+	restoreClaims = map[types.UID]restoreClaim{}
+)
+
+// RegisterDatabaseRestoreInformer registers the required event
+// handlers to handle changes on databaseRestore CRDs
+func (c *controller) RegisterDatabaseRestoreInformer(factory externalversions.SharedInformerFactory) error {
+	informer := factory.Backup().V1().DatabaseRestores().Informer()
+
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) { c.enqueue(obj, queueEntryActionSyncRestore, "databaseRestore added") },
+		UpdateFunc: func(oldObj, obj any) {
+			oldRestore, okOld := oldObj.(*v1.DatabaseRestore)
+			restore, ok := obj.(*v1.DatabaseRestore)
+			if okOld && ok && oldRestore.ResourceVersion == restore.ResourceVersion && restore.Status.IsActive() {
+				// Periodic resync of a running restore: It is already being
+				// polled, no need to start another poll
+				return
+			}
+
+			c.enqueue(obj, queueEntryActionSyncRestore, "databaseRestore updated")
+		},
+	}); err != nil {
+		return errors.Wrap(err, "adding event handlers")
+	}
+
+	logrus.Info("registered DatabaseRestores handlers")
+
+	c.restoreLister = factory.Backup().V1().DatabaseRestores().Lister()
+	c.informerSyncs = append(c.informerSyncs, informer.HasSynced)
+	return nil
+}
+
+// handleDatabaseRestoreSync starts the restore for a new
+// DatabaseRestore and follows the runner job until it is finished
+func (c controller) handleDatabaseRestoreSync(q *queueEntry) error {
+	q.Logger().Debug("handling DatabaseRestore sync")
+
+	restore, err := q.FetchRestore(c.crdClient)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			// Gone in the meantime, nothing to follow
+			return nil
+		}
+		return err
+	}
+
+	if restore.DeletionTimestamp != nil || restore.Status.IsFinished() {
+		// Finished restores are kept as a record, nothing to do anymore
+		return nil
+	}
+
+	backup, err := c.crdClient.BackupV1().
+		DatabaseBackups(restore.Namespace).
+		Get(context.Background(), restore.Spec.DatabaseBackup, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		// Backup exists, we can talk to its runner
+
+	case k8sErrors.IsNotFound(err):
+		restore.Status.SetFinished(restore.Generation, metav1.Now(), fmt.Sprintf("DatabaseBackup %s not found", restore.Spec.DatabaseBackup))
+		return c.updateRestoreStatus(restore)
+
+	default:
+		return errors.Wrap(err, "fetching databaseBackup")
+	}
+
+	rssName, err := c.deriveName(restore.Namespace, restore.Spec.DatabaseBackup)
+	if err != nil {
+		return errors.Wrap(err, "generating resource name")
+	}
+
+	if restore.Status.IsActive() {
+		return c.pollDatabaseRestore(q, restore, rssName)
+	}
+
+	return c.startDatabaseRestore(q, restore, backup, rssName)
+}
+
+// findConflictingRestore returns the key of another restore in any
+// namespace holding the claim on the given target database. It MUST
+// only be called while holding the restoreStartLock.
+func (c controller) findConflictingRestore(restore *v1.DatabaseRestore, target string) (string, error) {
+	for uid, claim := range restoreClaims {
+		if c.restoreClaimCached(uid, claim) {
+			// Informer cache caught up, the claim is checked below
+			delete(restoreClaims, uid)
+			continue
+		}
+
+		if uid != restore.UID && claim.target == target {
+			return claim.key, nil
+		}
+	}
+
+	restores, err := c.restoreLister.List(labels.Everything())
+	if err != nil {
+		return "", errors.Wrap(err, "listing databaseRestores")
+	}
+
+	for _, other := range restores {
+		if other.UID == restore.UID || !other.Status.HoldsClaim() {
+			continue
+		}
+
+		otherBackup, err := c.backupLister.DatabaseBackups(other.Namespace).Get(other.Spec.DatabaseBackup)
+		if err != nil {
+			if k8sErrors.IsNotFound(err) {
+				// Its runner is gone, it cannot be running anymore
+				continue
+			}
+			return "", errors.Wrap(err, "fetching databaseBackup of other restore")
+		}
+
+		if other.Spec.TargetDatabaseID(otherBackup.Spec) == target {
+			return other.Namespace + "/" + other.Name, nil
+		}
+	}
+
+	return "", nil
+}
+
+// restoreClaimCached tells whether the informer cache reflects the
+// claim: The restore is gone, holds the claim or is finished.
+func (c controller) restoreClaimCached(uid types.UID, claim restoreClaim) bool {
+	ns, name, err := cache.SplitMetaNamespaceKey(claim.key)
+	if err != nil {
+		return true
+	}
+
+	restore, err := c.restoreLister.DatabaseRestores(ns).Get(name)
+	if err != nil {
+		return k8sErrors.IsNotFound(err)
+	}
+
+	return restore.UID != uid || restore.Status.HoldsClaim() || restore.Status.IsFinished()
+}
+
+// pollDatabaseRestore checks the runner job of the restore and writes
+// the result into the status when finished or polls again later
+func (c controller) pollDatabaseRestore(q *queueEntry, restore *v1.DatabaseRestore, rssName string) error {
+	var job runnerJob
+	status, err := c.callRunner(rssName, http.MethodGet, "/jobs/"+restore.Status.JobID, nil, http.StatusOK, &job)
+	switch {
+	case err == nil:
+		// Got the job status
+
+	case status == http.StatusNotFound:
+		// The runner does not keep the jobs across restarts
+		restore.Status.SetFinished(restore.Generation, metav1.Now(), "job got lost in the runner (was the runner restarted?)")
+		return c.updateRestoreStatus(restore)
+
+	default:
+		// The runner might be unreachable for a moment, we ask again
+		q.Logger().WithError(err).Warn("fetching job status from runner")
+		c.pollLater(q, "databaseRestore poll")
+		return nil
+	}
+
+	if job.FinishedAt == nil {
+		if job.Backup != "" && job.Backup != restore.Status.BackupName {
+			// Make the chosen backup visible while the restore is running,
+			// the status update triggers the next poll
+			restore.Status.BackupName = job.Backup
+			return c.updateRestoreStatus(restore)
+		}
+
+		c.pollLater(q, "databaseRestore poll")
+		return nil
+	}
+
+	restore.Status.BackupName = job.Backup
+	restore.Status.SetFinished(restore.Generation, metav1.NewTime(*job.FinishedAt), job.Error)
+	return c.updateRestoreStatus(restore)
+}
+
+// startDatabaseRestore requests the runner to start the restore and
+// marks the restore as running unless another restore is running
+// against the same database. Before starting, the restore claims the
+// target database by storing the job ID (its UID) in the status: The
+// runner returns the existing job when retrying with that ID, so a
+// failed status update does not start a second restore.
+func (c controller) startDatabaseRestore(q *queueEntry, restore *v1.DatabaseRestore, backup *v1.DatabaseBackup, rssName string) error {
+	req, problem, err := c.newRunnerRestoreRequest(restore, backup)
+	if err != nil {
+		return errors.Wrap(err, "creating restore request")
+	}
+
+	if problem != "" {
+		restore.Status.SetFinished(restore.Generation, metav1.Now(), problem)
+		return c.updateRestoreStatus(restore)
+	}
+
+	restore, claimed, err := c.claimRestoreTarget(q, restore, restore.Spec.TargetDatabaseID(backup.Spec), req.JobID)
+	if err != nil || !claimed {
+		return err
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return errors.Wrap(err, "marshalling restore request")
+	}
+
+	var job runnerJob
+	status, err := c.callRunner(rssName, http.MethodPost, "/restores", bytes.NewReader(body), http.StatusCreated, &job)
+	switch {
+	case err == nil:
+		// Restore is running
+
+	case status == http.StatusBadRequest:
+		// Retrying will not help: The runner rejected the request
+		restore.Status.SetFinished(restore.Generation, metav1.Now(), errors.Wrap(err, "starting restore in runner").Error())
+		return c.updateRestoreStatus(restore)
+
+	default:
+		if status == http.StatusConflict {
+			err = errors.New("another action is running in the runner")
+		}
+
+		// The restore stays pending with its claim while retrying
+		return errors.Wrap(err, "starting restore in runner")
+	}
+
+	q.Logger().WithField("job", job.ID).Info("started restore for databaseRestore")
+
+	// The status update triggers the next sync which starts polling
+	restore.Status.SetRunning(job.ID, metav1.NewTime(job.StartedAt))
+	return c.updateRestoreStatus(restore)
+}
+
+// claimRestoreTarget claims the target database for the restore by
+// storing the job ID in its status unless another restore holds the
+// claim, which finishes the restore instead. The lock is only held
+// until the claim is persisted so the runner is called without it.
+func (c controller) claimRestoreTarget(
+	q *queueEntry,
+	restore *v1.DatabaseRestore,
+	target, jobID string,
+) (*v1.DatabaseRestore, bool, error) {
+	restoreStartLock.Lock()
+	defer restoreStartLock.Unlock()
+
+	conflict, err := c.findConflictingRestore(restore, target)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "checking for conflicting restores")
+	}
+
+	if conflict != "" {
+		restore.Status.SetFinished(restore.Generation, metav1.Now(),
+			fmt.Sprintf("DatabaseRestore %s is already restoring into the same database", conflict))
+		return nil, false, c.updateRestoreStatus(restore)
+	}
+
+	if restore.Status.JobID == jobID {
+		// Claimed by a previous attempt
+		return restore, true, nil
+	}
+
+	restore.Status.SetClaimed(jobID)
+	if restore, err = c.crdClient.BackupV1().
+		DatabaseRestores(restore.Namespace).
+		UpdateStatus(context.Background(), restore, metav1.UpdateOptions{}); err != nil {
+		return nil, false, errors.Wrap(err, "claiming target database")
+	}
+
+	restoreClaims[restore.UID] = restoreClaim{key: q.key, target: target}
+	return restore, true, nil
+}
+
+// newRunnerRestoreRequest creates the request to start the restore in
+// the runner including the identities to decrypt the backup. Problems
+// retrying will not solve are returned to fail the restore with.
+func (c controller) newRunnerRestoreRequest(
+	restore *v1.DatabaseRestore,
+	backup *v1.DatabaseBackup,
+) (req runnerRestoreRequest, problem string, err error) {
+	req = runnerRestoreRequest{
+		JobID:    string(restore.UID),
+		Mode:     "name",
+		Backup:   restore.Spec.BackupName,
+		Location: restore.Spec.SourceLocation,
+		Target:   restore.Spec.Target,
+	}
+	if restore.Spec.PointInTime != nil {
+		req.Mode = "point-in-time"
+		req.Backup = restore.Spec.PointInTime.UTC().Format(time.RFC3339)
+	}
+
+	ref := restore.Spec.IdentitiesFrom
+	if ref == nil {
+		sc, err := c.crdClient.BackupV1().
+			DatabaseBackupStorageClasses().
+			Get(context.Background(), backup.Spec.BackupStorageClass, metav1.GetOptions{})
+		switch {
+		case err == nil:
+			if restore.Spec.RequiresIdentities(sc.Spec) {
+				return req, "backups are only encrypted to recipients, identitiesFrom must be set", nil
+			}
+
+		case k8sErrors.IsNotFound(err):
+			// The runner still knows the storage class, let it try
+
+		default:
+			return req, "", errors.Wrap(err, "fetching databaseBackupStorageClass")
+		}
+
+		return req, "", nil
+	}
+
+	secret, err := c.kubeClient.CoreV1().Secrets(restore.Namespace).Get(context.Background(), ref.Name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		// Secret exists, identities need to be in it
+
+	case k8sErrors.IsNotFound(err):
+		return req, fmt.Sprintf("identities secret %s not found", ref.Name), nil
+
+	default:
+		return req, "", errors.Wrap(err, "fetching identities secret")
+	}
+
+	identities, ok := secret.Data[ref.Key]
+	if !ok {
+		return req, fmt.Sprintf("key %q not found in identities secret %s", ref.Key, ref.Name), nil
+	}
+
+	req.Identities = string(identities)
+	return req, "", nil
+}
+
+func (c controller) updateRestoreStatus(restore *v1.DatabaseRestore) error {
+	_, err := c.crdClient.BackupV1().
+		DatabaseRestores(restore.Namespace).
+		UpdateStatus(context.Background(), restore, metav1.UpdateOptions{})
+	return errors.Wrap(err, "updating status")
+}
+
+func (q queueEntry) FetchRestore(crdClient versioned.Interface) (*v1.DatabaseRestore, error) {
+	ns, name, err := cache.SplitMetaNamespaceKey(q.key)
+	if err != nil {
+		return nil, errors.Wrap(err, "splitting namespace key")
+	}
+
+	restore, err := crdClient.BackupV1().DatabaseRestores(ns).Get(context.Background(), name, metav1.GetOptions{})
+	return restore, errors.Wrap(err, "fetching restore object")
+}
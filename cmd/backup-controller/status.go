@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Luzifer/go_helpers/v2/str"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/rssgenerator"
+)
+
+const (
+	runnerErrorBodyLimit = 1024
+	runnerRequestTimeout = 5 * time.Second
+)
+
+// handleDatabaseBackupSyncStatus fetches the backup results from the
+// runner of the DatabaseBackup and writes them into its status
+func (c controller) handleDatabaseBackupSyncStatus(q *queueEntry) error {
+	q.Logger().Debug("handling DatabaseBackup status sync")
+
+	b, err := q.Fetch(c.crdClient)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			// Gone in the meantime, nothing to sync
+			return nil
+		}
+		return err
+	}
+
+	if b.DeletionTimestamp != nil || !str.StringInSlice(finalizer, b.Finalizers) {
+		// Not (yet / anymore) managed by us, there is no runner to ask
+		return nil
+	}
+
+	rssName, err := c.deriveName(b.Namespace, b.Name)
+	if err != nil {
+		return errors.Wrap(err, "generating resource name")
+	}
+
+	results, err := c.fetchBackupResults(rssName)
+	if err != nil {
+		// The runner might just be starting, we will ask again on the
+		// next sync so there is no need to retry this one
+		q.Logger().WithError(err).Warn("fetching backup results from runner")
+		return nil
+	}
+
+	status := *b.Status.DeepCopy()
+	status.SetBackupResults(b.Generation, results)
+
+	if equality.Semantic.DeepEqual(status, b.Status) {
+		q.Logger().Debug("backup results unchanged, noop")
+		return nil
+	}
+
+	b.Status = status
+	return errors.Wrap(c.updateStatus(b), "updating status")
+}
+
+// fetchBackupResults queries the status route of the runner through
+// the service generated for it
+func (c controller) fetchBackupResults(rssName string) (results v1.BackupResultStatus, err error) {
+	_, err = c.callRunner(rssName, http.MethodGet, "/status", nil, http.StatusOK, &results)
+	return results, err
+}
+
+// callRunner executes a request against the runner through the
+// service generated for it and decodes the response into out. The
+// HTTP status is returned to allow checking for specific errors.
+func (c controller) callRunner(rssName, method, path string, body io.Reader, expectStatus int, out any) (status int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), runnerRequestTimeout)
+	defer cancel()
+
+	token, err := c.runnerAPIToken(ctx, rssName)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		method,
+		fmt.Sprintf("http://%s.%s.svc:%d%s", rssName, cfg.TargetNamespace, rssgenerator.ServicePort, path),
+		body,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "executing request")
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logrus.WithError(err).Error("closing runner response body (leaked fd)")
+		}
+	}()
+
+	if resp.StatusCode != expectStatus {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, runnerErrorBodyLimit)) // Only used to enrich the error
+		return resp.StatusCode, errors.Errorf("unexpected HTTP status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	return resp.StatusCode, errors.Wrap(json.NewDecoder(resp.Body).Decode(out), "decoding response")
+}
+
+// runnerAPIToken fetches the token to authenticate at the runner API
+// from the secret generated for the runner
+func (c controller) runnerAPIToken(ctx context.Context, rssName string) (string, error) {
+	secret, err := c.kubeClient.CoreV1().Secrets(cfg.TargetNamespace).Get(ctx, rssName, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "fetching runner secret")
+	}
+
+	token := secret.Data[rssgenerator.SecretKeyAPIToken]
+	if len(token) == 0 {
+		return "", errors.New("runner secret contains no API token")
+	}
+
+	return string(token), nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+)
+
+const webhookRequestBodyLimit = 1024 * 1024 // 1MiB, same as the API server object limit for webhooks
+
+// handleValidateDatabaseBackup implements the validating admission
+// webhook for DatabaseBackups rejecting misconfigurations which would
+// otherwise only surface when the runner is started
+func (c controller) handleValidateDatabaseBackup(w http.ResponseWriter, r *http.Request) {
+	handleAdmissionReview(w, r, "DatabaseBackup", c.validateDatabaseBackupRequest)
+}
+
+// handleValidateDatabaseBackupStorageClass implements the validating
+// admission webhook for DatabaseBackupStorageClasses rejecting
+// locations the runner would not be able to write to
+func (controller) handleValidateDatabaseBackupStorageClass(w http.ResponseWriter, r *http.Request) {
+	handleAdmissionReview(w, r, "DatabaseBackupStorageClass", validateDatabaseBackupStorageClassRequest)
+}
+
+// handleAdmissionReview decodes the AdmissionReview, validates its
+// request and responds with the problems found by the validate func
+func handleAdmissionReview(
+	w http.ResponseWriter, r *http.Request, kind string,
+	validate func(context.Context, *admissionv1.AdmissionRequest) (field.ErrorList, error),
+) {
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(io.LimitReader(r.Body, webhookRequestBodyLimit)).Decode(&review); err != nil {
+		http.Error(w, errors.Wrap(err, "decoding admission review").Error(), http.StatusBadRequest)
+		return
+	}
+
+	if review.Request == nil {
+		http.Error(w, "admission review contains no request", http.StatusBadRequest)
+		return
+	}
+
+	errs, err := validate(r.Context(), review.Request)
+	if err != nil {
+		logrus.WithError(err).WithField("name", review.Request.Name).Errorf("validating %s", kind)
+		http.Error(w, "validating "+kind, http.StatusInternalServerError)
+		return
+	}
+
+	resp := &admissionv1.AdmissionResponse{UID: review.Request.UID, Allowed: len(errs) == 0}
+	if !resp.Allowed {
+		resp.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusUnprocessableEntity,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: fmt.Sprintf("%s %q is invalid: %s", kind, review.Request.Name, errs.ToAggregate().Error()),
+		}
+	}
+
+	review.Request = nil
+	review.Response = resp
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		logrus.WithError(err).Error("encoding admission review")
+	}
+}
+
+// validateDatabaseBackupRequest returns the problems found in the
+// DatabaseBackup of the request. Updates not changing the spec and
+// updates on objects being deleted are not validated as they would
+// otherwise block the controller from removing its finalizer.
+func (c controller) validateDatabaseBackupRequest(ctx context.Context, req *admissionv1.AdmissionRequest) (field.ErrorList, error) {
+	var backup v1.DatabaseBackup
+	if err := json.Unmarshal(req.Object.Raw, &backup); err != nil {
+		return nil, errors.Wrap(err, "decoding object")
+	}
+
+	if req.Operation == admissionv1.Update {
+		var old v1.DatabaseBackup
+		if err := json.Unmarshal(req.OldObject.Raw, &old); err != nil {
+			return nil, errors.Wrap(err, "decoding old object")
+		}
+
+		if backup.DeletionTimestamp != nil || equality.Semantic.DeepEqual(old.Spec, backup.Spec) {
+			return nil, nil
+		}
+	}
+
+	specPath := field.NewPath("spec")
+	errs := backup.Spec.Validate(specPath)
+
+	_, err := c.crdClient.BackupV1().DatabaseBackupStorageClasses().Get(ctx, backup.Spec.BackupStorageClass, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		// Storage class exists
+
+	case k8sErrors.IsNotFound(err):
+		errs = append(errs, field.NotFound(specPath.Child("backupStorageClass"), backup.Spec.BackupStorageClass))
+
+	default:
+		return nil, errors.Wrap(err, "fetching databaseBackupStorageClass")
+	}
+
+	return errs, nil
+}
+
+// validateDatabaseBackupStorageClassRequest returns the problems found
+// in the DatabaseBackupStorageClass of the request. Referenced secrets
+// are not required to exist yet as they might be applied after the
+// storage class (i.e. by GitOps tools).
+func validateDatabaseBackupStorageClassRequest(_ context.Context, req *admissionv1.AdmissionRequest) (field.ErrorList, error) {
+	var storageClass v1.DatabaseBackupStorageClass
+	if err := json.Unmarshal(req.Object.Raw, &storageClass); err != nil {
+		return nil, errors.Wrap(err, "decoding object")
+	}
+
+	if req.Operation == admissionv1.Update {
+		var old v1.DatabaseBackupStorageClass
+		if err := json.Unmarshal(req.OldObject.Raw, &old); err != nil {
+			return nil, errors.Wrap(err, "decoding old object")
+		}
+
+		if storageClass.DeletionTimestamp != nil || equality.Semantic.DeepEqual(old.Spec, storageClass.Spec) {
+			return nil, nil
+		}
+	}
+
+	return storageClass.Spec.Validate(field.NewPath("spec")), nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// readAPIToken loads the token the controller authenticates with. A
+// missing file results in an empty token rejecting all requests.
+func readAPIToken(filename string) (string, error) {
+	content, err := os.ReadFile(filename) //#nosec:G304 // Loading a given config-file, this is fine
+	switch {
+	case err == nil:
+		return strings.TrimSpace(string(content)), nil
+
+	case os.IsNotExist(err):
+		return "", nil
+
+	default:
+		return "", errors.Wrap(err, "reading file contents")
+	}
+}
+
+// requireAPIToken restricts the handler to the controller by requiring
+// the API token as bearer token. Without a configured token all
+// requests are rejected.
+func requireAPIToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || apiToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(apiToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -2,99 +2,352 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/compressstream"
 	"github.com/NectGmbH/db-backup-controller/pkg/cryptostream"
+	"github.com/NectGmbH/db-backup-controller/pkg/fanout"
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+	"github.com/NectGmbH/db-backup-controller/pkg/manifest"
 	"github.com/NectGmbH/db-backup-controller/pkg/storage"
 )
 
-//nolint:gocognit // Makes more sense to keep as one unit
-func executeBackup() (err error) {
+const (
+	backupFanoutQueueLength = 16
+	// backupFanoutStallTimeout is the time a location may block a
+	// single write (i.e. while uploading a part) before it is dropped
+	// so it does not stop the backup to the other locations
+	backupFanoutStallTimeout = 5 * time.Minute
+)
+
+type (
+	// backupRequest is sent by the controller to start the backup of a
+	// DatabaseBackupRun
+	backupRequest struct {
+		JobID string `json:"jobID,omitempty"`
+	}
+
+	backupTarget struct {
+		loc    v1.DatabaseBackupStorageLocation
+		logger *logrus.Entry
+
+		cryptW *cryptostream.CryptoWriteCloserV2
+		fanout *fanout.Target
+		pipe   *io.PipeWriter
+		stored *checksumWriter
+		stor   storage.Manager
+		upload chan error
+	}
+
+	// checksumWriter passes the data into the next writer keeping
+	// track of its size and checksum
+	checksumWriter struct {
+		next io.Writer
+		hash hash.Hash
+		size int64
+	}
+)
+
+func executeBackup(ctx context.Context) (err error) {
 	// * Asks requested engine to take a backup (=> ./pkg/backupengine/...)
 	// 	* Engine knows what to execute to backup $db from $host with $credentials
 	// 	* Engine stores backup to file location it is given by runner
 	// * Uploads backup to storage location (=> ./pkg/storage/...)
 	// 	* Upload location in the bucket is a generated name from the backup definition name and the namespace
+	// 	* The backup is created once (compressed if configured) and distributed to all locations, each of them encrypted on its own
 	// * Takes notes which backups exist, manages "labels" for them, if no more labels are attached removes backup
 	// 	* Can run in "single backup" mode: No labels, no management, no retention, just a single uploaded target
+	// * Writes a manifest describing the backup next to it (=> ./pkg/manifest/...)
+	// * Records the result to be reported in the DatabaseBackup status
 
-	backupName := time.Now().UTC().Format("2006-01-02T15-04-05")
+	ctx, cancel := configBackup.Spec.WithBackupTimeout(ctx)
+	defer cancel()
+
+	var (
+		startedAt  = time.Now().UTC()
+		backupName = startedAt.Format(labelmanager.EntryNameFormat)
+		cleanup    sync.WaitGroup
+		dest       = fanout.NewWriter(backupFanoutQueueLength, backupFanoutStallTimeout)
+		failed     int
+		storedSize int64
+		targets    []*backupTarget
+	)
+
+	// Keep the results to be shown in the DatabaseBackup status and
+	// reported to the DatabaseBackupRun which triggered the job
+	defer func() {
+		monitor.RegisterBackupResult(backupName, storedSize, err)
+		jobs.RecordBackup(backupName, storedSize)
+	}()
 
 	for i := range configStorage.BackupLocations {
-		loc := configStorage.BackupLocations[i]
+		target, err := newBackupTarget(ctx, configStorage.BackupLocations[i], backupName)
+		if err != nil {
+			logrus.WithField("location", configStorage.BackupLocations[i].StorageEndpoint).
+				WithError(err).Error("preparing backup location")
+			failed++
+			continue
+		}
 
-		logger := logrus.WithFields(logrus.Fields{
-			"backup":   backupName,
-			"location": loc.StorageEndpoint,
-		})
-		logger.Info("preparing backup")
+		target.fanout = dest.AddTarget(target)
+		targets = append(targets, target)
+	}
 
-		logger.Debug("engine initialized")
+	if len(targets) == 0 {
+		return errors.New("no backup location available")
+	}
 
-		stor, err := storage.New(context.Background(), &loc, &configBackup)
-		if err != nil {
-			return errors.Wrap(err, "getting storage provider")
+	logrus.WithFields(logrus.Fields{
+		"backup":    backupName,
+		"locations": len(targets),
+	}).Info("starting backup")
+
+	backupErr := createCompressedBackup(ctx, dest)
+	if err = dest.Close(); err != nil && backupErr == nil {
+		backupErr = err
+	}
+
+	for _, target := range targets {
+		if err = target.Finish(backupErr); err != nil {
+			target.logger.WithError(err).Error("backup to location failed")
+			failed++
+			continue
 		}
 
-		logger.Debug("storage initialized")
+		target.logger.Info("backup completed")
 
-		logger.Info("starting backup")
+		if storedSize == 0 {
+			// Report the size stored in the first location
+			storedSize = target.stored.size
+		}
 
-		r, w := io.Pipe()
+		if err = target.WriteManifest(ctx, backupName, startedAt); err != nil {
+			// The backup itself is fine, it just can't be inspected
+			target.logger.WithError(err).Error("writing backup manifest")
+		}
 
-		go func() {
-			if err = w.CloseWithError(func(w io.Writer) (err error) {
-				var (
-					backupDest = w
-					cryptW     *cryptostream.CryptoWriteCloser
-				)
+		// Cleanup runs as part of the job so no other action (i.e. a
+		// pin) modifies the labels while expired backups are deleted
+		cleanup.Add(1)
+		go func(target *backupTarget) {
+			defer cleanup.Done()
+			target.Cleanup()
+		}(target)
+	}
+	cleanup.Wait()
 
-				if loc.EncryptionPass.Value != "" {
-					cryptW, err = cryptostream.NewWriter(w, []byte(loc.EncryptionPass.Value))
-					if err != nil {
-						return errors.Wrap(err, "creating crypto-writer")
-					}
-					backupDest = cryptW
-				}
+	if backupErr != nil {
+		return errors.Wrap(contextError(ctx, backupErr), "creating backup")
+	}
 
-				if err = engine.CreateBackup(backupDest); err != nil {
-					return errors.Wrap(err, "creating backup")
-				}
+	if failed > 0 {
+		return errors.Errorf("backup failed for %d of %d locations", failed, len(configStorage.BackupLocations))
+	}
 
-				if cryptW == nil {
-					return nil
-				}
+	return nil
+}
 
-				return errors.Wrap(cryptW.Close(), "closing crypto writer")
-			}(w)); err != nil {
-				logger.WithError(err).Error("closing backup pipe")
-			}
-		}()
+// createCompressedBackup lets the engine write the backup into the
+// given writer, compressing it before if configured
+func createCompressedBackup(ctx context.Context, w io.Writer) error {
+	cfg := configBackup.Spec.Compression
+	if cfg == nil {
+		return errors.Wrap(engine.CreateBackup(ctx, w), "executing engine backup")
+	}
+
+	cw, err := compressstream.NewWriter(w, cfg.Codec, cfg.Level)
+	if err != nil {
+		return errors.Wrap(err, "creating compression writer")
+	}
 
-		if err = stor.UploadFromReader(context.Background(), backupName, r, -1); err != nil {
-			return errors.Wrap(err, "uploading backup to storage location")
+	if err = engine.CreateBackup(ctx, cw); err != nil {
+		return errors.Wrap(err, "executing engine backup")
+	}
+
+	return errors.Wrap(cw.Close(), "closing compression writer")
+}
+
+// newBackupTarget initializes the storage for the given location and
+// starts the upload reading from the pipe the target writes into. The
+// upload is aborted when the context is cancelled.
+func newBackupTarget(ctx context.Context, loc v1.DatabaseBackupStorageLocation, backupName string) (*backupTarget, error) {
+	var (
+		err error
+		t   = &backupTarget{
+			loc: loc,
+			logger: logrus.WithFields(logrus.Fields{
+				"backup":   backupName,
+				"location": loc.StorageEndpoint,
+			}),
+			upload: make(chan error, 1),
 		}
+	)
+	t.logger.Info("preparing backup")
 
-		// Trigger backup cleanup in background
-		go func() {
-			if err := stor.CleanupBackups(context.Background()); err != nil {
-				logger.WithError(err).Error("executing storage cleanup")
-			}
+	if t.stor, err = storage.New(ctx, &t.loc, &configBackup); err != nil {
+		return nil, errors.Wrap(err, "getting storage provider")
+	}
+
+	t.logger.Debug("storage initialized")
+
+	r, w := io.Pipe()
+	t.pipe = w
+	t.stored = &checksumWriter{next: w, hash: sha256.New()}
+
+	go func(stor storage.Manager, logger *logrus.Entry, result chan<- error) {
+		err := stor.UploadFromReader(ctx, backupName, r, -1)
+		// Make sure writes into the pipe fail when the upload is gone
+		if cErr := r.CloseWithError(errors.Wrap(err, "upload stopped")); cErr != nil {
+			logger.WithError(cErr).Error("closing backup pipe")
+		}
+		result <- err
+	}(t.stor, t.logger, t.upload)
+
+	if t.cryptW, err = newCryptoWriter(t.stored, loc); err != nil {
+		t.abort(err)
+		return nil, errors.Wrap(err, "creating crypto-writer")
+	}
 
-			if err := updateBackupCountFromLocation(loc); err != nil {
-				logger.WithError(err).Error("updating backup count metric")
+	return t, nil
+}
+
+// newCryptoWriter creates the crypto-writer for the encryption
+// configured in the location (preferring recipients over the
+// passphrase) or returns nil if the location is not encrypted
+func newCryptoWriter(w io.Writer, loc v1.DatabaseBackupStorageLocation) (*cryptostream.CryptoWriteCloserV2, error) {
+	switch {
+	case len(loc.EncryptionRecipients) > 0:
+		recipients := make([]cryptostream.Recipient, len(loc.EncryptionRecipients))
+		for i, r := range loc.EncryptionRecipients {
+			var err error
+			if recipients[i], err = cryptostream.ParseRecipient(r); err != nil {
+				return nil, errors.Wrapf(err, "parsing recipient %d", i)
 			}
-		}()
+		}
 
-		logger.Info("backup completed")
+		cw, err := cryptostream.NewRecipientWriter(w, recipients)
+		return cw, errors.Wrap(err, "creating recipient writer")
+
+	case loc.EncryptionPass.Value != "":
+		cw, err := cryptostream.NewWriterV2(w, []byte(loc.EncryptionPass.Value))
+		return cw, errors.Wrap(err, "creating passphrase writer")
+
+	default:
+		return nil, nil //nolint:nilnil // No writer is required for unencrypted locations
+	}
+}
+
+// Cleanup removes expired backups from the location and updates the
+// backup count metric afterwards
+func (t *backupTarget) Cleanup() {
+	if err := t.stor.CleanupBackups(context.Background()); err != nil {
+		t.logger.WithError(err).Error("executing storage cleanup")
 	}
 
-	return nil
+	if err := updateBackupCountFromLocation(t.loc); err != nil {
+		t.logger.WithError(err).Error("updating backup count metric")
+	}
+}
+
+// Finish closes the data stream to the location (aborting the upload
+// when the backup failed or the target was dropped) and waits for
+// the upload to complete
+func (t *backupTarget) Finish(backupErr error) (err error) {
+	switch {
+	case backupErr != nil:
+		err = backupErr
+
+	case t.fanout.Err() != nil:
+		err = t.fanout.Err()
+
+	case t.cryptW != nil:
+		err = errors.Wrap(t.cryptW.Close(), "closing crypto writer")
+	}
+
+	if err != nil {
+		t.abort(err)
+		return err
+	}
+
+	if err = t.pipe.Close(); err != nil {
+		return errors.Wrap(err, "closing backup pipe")
+	}
+
+	return errors.Wrap(<-t.upload, "uploading backup to storage location")
+}
+
+// abort closes the data stream with the given error, causing the
+// upload to fail, and waits for the upload to stop
+func (t *backupTarget) abort(reason error) {
+	if err := t.pipe.CloseWithError(reason); err != nil {
+		t.logger.WithError(err).Error("closing backup pipe")
+	}
+	<-t.upload
+}
+
+// Write implements the io.Writer interface and writes the given data
+// into the upload pipe (encrypted if configured)
+func (t *backupTarget) Write(p []byte) (int, error) {
+	if t.cryptW != nil {
+		return t.cryptW.Write(p) //nolint:wrapcheck // Error is wrapped by the fanout writer
+	}
+
+	return t.stored.Write(p) //nolint:wrapcheck // Error is wrapped by the fanout writer
+}
+
+// WriteManifest describes the backup uploaded to the location and
+// stores the manifest next to it. Single backup targets are not
+// described as the manifest would be outdated on the next backup.
+func (t *backupTarget) WriteManifest(ctx context.Context, backupName string, startedAt time.Time) error {
+	if configBackup.Spec.UseSingleBackupTarget {
+		return nil
+	}
+
+	m := manifest.Manifest{
+		Backup:          backupName,
+		DatabaseType:    configBackup.Spec.DatabaseType,
+		DatabaseVersion: configBackup.Spec.DatabaseVersion,
+		StartedAt:       startedAt,
+		FinishedAt:      time.Now().UTC(),
+		Size:            t.stored.size,
+		SHA256:          hex.EncodeToString(t.stored.hash.Sum(nil)),
+	}
+
+	if cfg := configBackup.Spec.Compression; cfg != nil {
+		m.Compression = &manifest.Compression{Codec: cfg.Codec, Level: cfg.Level}
+	}
+
+	// Mirrors the choice of newCryptoWriter
+	switch {
+	case len(t.loc.EncryptionRecipients) > 0:
+		m.Encryption = &manifest.Encryption{
+			Mode:       manifest.EncryptionModeRecipients,
+			Recipients: t.loc.EncryptionRecipients,
+		}
+
+	case t.loc.EncryptionPass.Value != "":
+		m.Encryption = &manifest.Encryption{Mode: manifest.EncryptionModePassphrase}
+	}
+
+	return errors.Wrap(t.stor.WriteManifest(ctx, m), "storing manifest")
+}
+
+// Write implements the io.Writer interface
+func (c *checksumWriter) Write(p []byte) (int, error) {
+	n, err := c.next.Write(p)
+	_, _ = c.hash.Write(p[:n]) // Writing into a hash never fails
+	c.size += int64(n)
+	return n, err //nolint:wrapcheck // Error is wrapped by the caller
 }
 
 func nextExecutionFromCron(spec string) (time.Time, error) {
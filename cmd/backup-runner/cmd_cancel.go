@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var cmdCancel = &cobra.Command{
+	Use:   "cancel [job-id]",
+	Short: "Cancels the given or the currently running job",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  cmdCancelRunE,
+}
+
+func init() {
+	cmdRoot.AddCommand(cmdCancel)
+}
+
+func cmdCancelRunE(cmd *cobra.Command, args []string) error {
+	body, err := json.Marshal(ipcPayload{
+		Action: "cancel",
+		Args:   args,
+	})
+	if err != nil {
+		return errors.Wrap(err, "marshalling IPC payload")
+	}
+
+	var status jobStatus
+	if err = ipcCall(cmd, http.MethodPost, "/ipc", bytes.NewReader(body), http.StatusOK, ipcRequestTimeout, &status); err != nil {
+		return err
+	}
+
+	wait, err := cmd.Flags().GetBool(flagWait)
+	if err != nil {
+		return errors.Wrapf(err, "getting %s flag value", flagWait)
+	}
+
+	if !wait {
+		logrus.WithField("job", status.ID).Info("cancellation requested, see runner logs for details")
+		return nil
+	}
+
+	if status, err = followJob(cmd, status.ID); err != nil {
+		return err
+	}
+
+	if status.Status != jobStatusCancelled {
+		// The job finished before the cancellation took effect
+		return errors.Errorf("job %s %s before being cancelled", status.ID, status.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const flagWait = "wait"
+
+var cmdJob = &cobra.Command{
+	Use:   "job id",
+	Short: "Shows the status of a job started through IPC (i.e. by `backup` or `restore`)",
+	Args:  cobra.ExactArgs(1),
+	RunE:  cmdJobRunE,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{cmdBackup, cmdCancel, cmdJob, cmdPin, cmdReindex, cmdRestore, cmdUnpin} {
+		cmd.Flags().Bool(flagWait, false, "wait for the job to finish printing its logs and fail if the job fails")
+	}
+
+	cmdRoot.AddCommand(cmdJob)
+}
+
+func cmdJobRunE(cmd *cobra.Command, args []string) error {
+	wait, err := cmd.Flags().GetBool(flagWait)
+	if err != nil {
+		return errors.Wrapf(err, "getting %s flag value", flagWait)
+	}
+
+	var status jobStatus
+	if wait {
+		if status, err = followJob(cmd, args[0]); err != nil {
+			return err
+		}
+
+		return jobResult(status)
+	}
+
+	if err = ipcCall(cmd, http.MethodGet, "/ipc/jobs/"+args[0], nil, http.StatusOK, ipcRequestTimeout, &status); err != nil {
+		return errors.Wrap(err, "fetching job status")
+	}
+
+	finished := "-"
+	if status.FinishedAt != nil {
+		finished = status.FinishedAt.Format(time.RFC3339)
+	}
+
+	if _, err = fmt.Fprintf(
+		cmd.OutOrStdout(), "Job:      %s\nAction:   %s\nStatus:   %s\nStarted:  %s\nFinished: %s\n",
+		status.ID, status.Action, status.Status, status.StartedAt.Format(time.RFC3339), finished,
+	); err != nil {
+		return errors.Wrap(err, "writing output")
+	}
+
+	return jobResult(status)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+	"github.com/NectGmbH/db-backup-controller/pkg/manifest"
+	"github.com/NectGmbH/db-backup-controller/pkg/storage"
+)
+
+var cmdList = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the backups available in the storage locations together with their metadata and pins",
+	RunE:  cmdListRunE,
+}
+
+func init() {
+	cmdRoot.AddCommand(cmdList)
+}
+
+func cmdListRunE(cmd *cobra.Command, _ []string) error {
+	ctx := context.Background()
+
+	for i := range configStorage.BackupLocations {
+		loc := configStorage.BackupLocations[i]
+
+		stor, err := storage.New(ctx, &loc, &configBackup)
+		if err != nil {
+			return errors.Wrapf(err, "getting storage provider for %s", loc.StorageEndpoint)
+		}
+
+		backups, err := stor.ListBackups(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "listing backups in %s", loc.StorageEndpoint)
+		}
+
+		plans, err := stor.PlanRetention(ctx, nil, false)
+		if err != nil {
+			return errors.Wrapf(err, "planning retention in %s", loc.StorageEndpoint)
+		}
+
+		retention := make(map[string]labelmanager.EntryPlan, len(plans))
+		for _, p := range plans {
+			retention[p.Entry] = p
+		}
+
+		if _, err = fmt.Fprintf(cmd.OutOrStdout(), "Location %s: %d backup(s)\n", loc.StorageEndpoint, len(backups)); err != nil {
+			return errors.Wrap(err, "writing output")
+		}
+
+		if err = printBackupList(cmd.OutOrStdout(), backups, retention); err != nil {
+			return errors.Wrap(err, "printing backups")
+		}
+	}
+
+	return nil
+}
+
+func printBackupList(w io.Writer, backups []manifest.Manifest, retention map[string]labelmanager.EntryPlan) error {
+	if len(backups) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:mnd // Just formatting
+	if _, err := fmt.Fprintln(tw, "BACKUP\tSIZE\tDATABASE\tCOMPRESSION\tENCRYPTION\tDURATION\tRETAINED UNTIL\tPINNED"); err != nil {
+		return errors.Wrap(err, "writing header")
+	}
+
+	for _, b := range backups {
+		var (
+			plan     = retention[b.Backup]
+			database = "-"
+			compress = "-"
+			encrypt  = "-"
+			duration = "-"
+			size     = "-"
+		)
+
+		if b.Size > 0 {
+			size = strconv.FormatInt(b.Size, 10)
+		}
+
+		if b.DatabaseType != "" {
+			database = b.DatabaseType + " " + b.DatabaseVersion
+		}
+
+		if b.Compression != nil {
+			compress = b.Compression.Codec
+		}
+
+		if b.Encryption != nil {
+			encrypt = b.Encryption.Mode
+		}
+
+		if !b.StartedAt.IsZero() {
+			duration = b.Duration().String()
+		}
+
+		if _, err := fmt.Fprintf(
+			tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			b.Backup, size, database, compress, encrypt, duration,
+			formatRetainedUntil(plan.RetainedUntil),
+			formatPin(plan.Pin),
+		); err != nil {
+			return errors.Wrap(err, "writing backup")
+		}
+	}
+
+	return errors.Wrap(tw.Flush(), "flushing table")
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagPinReason = "reason"
+	flagPinUntil  = "until"
+)
+
+var (
+	cmdPin = &cobra.Command{
+		Use:   "pin backup",
+		Short: "Protects the given backup from being removed by the retention cleanup",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmdPinRunE,
+	}
+
+	cmdUnpin = &cobra.Command{
+		Use:   "unpin backup",
+		Short: "Removes the pin from the given backup, its retention labels decide about its removal afterwards",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmdUnpinRunE,
+	}
+)
+
+func init() {
+	cmdPin.Flags().String(flagPinReason, "", "reason to note with the pin (i.e. a ticket of the audit)")
+	cmdPin.Flags().String(flagPinUntil, "", "point in time (RFC3339) the pin expires at, pinned until unpinned if not given")
+	cmdRoot.AddCommand(cmdPin)
+	cmdRoot.AddCommand(cmdUnpin)
+}
+
+func cmdPinRunE(cmd *cobra.Command, args []string) error {
+	reason, err := cmd.Flags().GetString(flagPinReason)
+	if err != nil {
+		return errors.Wrapf(err, "getting %s flag value", flagPinReason)
+	}
+
+	until, err := cmd.Flags().GetString(flagPinUntil)
+	if err != nil {
+		return errors.Wrapf(err, "getting %s flag value", flagPinUntil)
+	}
+
+	// Validate the expiry before sending it to the runner
+	if _, err = parsePinUntil(until); err != nil {
+		return err
+	}
+
+	return triggerIPCRequest(cmd, ipcPayload{
+		Action: "pin",
+		Args:   []string{args[0], reason, until},
+	})
+}
+
+func cmdUnpinRunE(cmd *cobra.Command, args []string) error {
+	return triggerIPCRequest(cmd, ipcPayload{
+		Action: "unpin",
+		Args:   args,
+	})
+}
+
+// parsePinUntil parses the RFC3339 expiry of a pin, returning the zero
+// time for a pin not expiring
+func parsePinUntil(until string) (time.Time, error) {
+	if until == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, until)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "parsing pin expiry")
+	}
+
+	if !t.After(time.Now()) {
+		return time.Time{}, errors.New("pin expiry must be in the future")
+	}
+
+	return t, nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var cmdReindex = &cobra.Command{
+	Use:   "reindex",
+	Short: "Rebuilds the retention labels from the backups present in the storage locations",
+	RunE:  cmdReindexRunE,
+}
+
+func init() {
+	cmdRoot.AddCommand(cmdReindex)
+}
+
+func cmdReindexRunE(cmd *cobra.Command, _ []string) (err error) {
+	return triggerIPCRequest(cmd, ipcPayload{
+		Action: "reindex",
+		Args:   nil,
+	})
+}
@@ -1,11 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"os"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+
+	"github.com/NectGmbH/db-backup-controller/pkg/cryptostream"
 )
 
-const flagRestoreMode = "mode"
+const (
+	flagRestoreIdentityFile = "identity-file"
+	flagRestoreMode         = "mode"
+)
 
 var cmdRestore = &cobra.Command{
 	Use:   "restore identifier",
@@ -14,6 +22,7 @@ var cmdRestore = &cobra.Command{
 }
 
 func init() {
+	cmdRestore.Flags().String(flagRestoreIdentityFile, "", "file containing the identities (private keys) to decrypt backups encrypted to recipients")
 	cmdRestore.Flags().String(flagRestoreMode, "point-in-time", "restore-mode to use (point-in-time / name)")
 	cmdRoot.AddCommand(cmdRestore)
 }
@@ -28,8 +37,28 @@ func cmdRestoreRunE(cmd *cobra.Command, args []string) error {
 		return errors.Wrapf(err, "getting %s flag value", flagRestoreMode)
 	}
 
+	identityFile, err := cmd.Flags().GetString(flagRestoreIdentityFile)
+	if err != nil {
+		return errors.Wrapf(err, "getting %s flag value", flagRestoreIdentityFile)
+	}
+
+	ipcArgs := []string{restoreMode, args[0]}
+	if identityFile != "" {
+		identities, err := os.ReadFile(identityFile) //#nosec:G304 // Reading the user specified identity file is intended
+		if err != nil {
+			return errors.Wrap(err, "reading identity file")
+		}
+
+		// Validate the identities before sending them to the runner
+		if _, err = cryptostream.ParseIdentities(bytes.NewReader(identities)); err != nil {
+			return errors.Wrap(err, "parsing identity file")
+		}
+
+		ipcArgs = append(ipcArgs, string(identities))
+	}
+
 	return triggerIPCRequest(cmd, ipcPayload{
 		Action: "restore",
-		Args:   []string{restoreMode, args[0]},
+		Args:   ipcArgs,
 	})
 }
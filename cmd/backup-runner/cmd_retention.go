@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+	"github.com/NectGmbH/db-backup-controller/pkg/storage"
+)
+
+const (
+	flagPlanMinKeep        = "min-keep"
+	flagPlanRelabel        = "relabel"
+	flagPlanRetention      = "retention"
+	flagPlanRetentionCount = "retention-count"
+	flagRelabelApply       = "apply"
+)
+
+var (
+	cmdRetention = &cobra.Command{
+		Use:   "retention",
+		Short: "Inspects and manages the retention of the stored backups",
+	}
+
+	cmdRetentionPlan = &cobra.Command{
+		Use:   "plan",
+		Short: "Explains which backups are kept, which are removed by the next cleanups and why",
+		RunE:  cmdRetentionPlanRunE,
+	}
+
+	cmdRetentionRelabel = &cobra.Command{
+		Use:   "relabel",
+		Short: "Re-evaluates the retention labels of all backups under the current retention config and shows the changes",
+		RunE:  cmdRetentionRelabelRunE,
+	}
+)
+
+func init() {
+	cmdRetentionPlan.Flags().Bool(flagPlanRelabel, false, "re-evaluate the labels under the retention config before")
+	cmdRetentionPlan.Flags().StringArray(flagPlanRetention, nil,
+		"retention config to evaluate as <format>=<duration> (i.e. %Y-%m=8928h), defaults to the configured one")
+	cmdRetentionPlan.Flags().StringArray(flagPlanRetentionCount, nil,
+		"retention counts to evaluate as <format>=<number> (i.e. %Y-%m-%dT%H=48), defaults to the configured ones")
+	cmdRetentionPlan.Flags().Int(flagPlanMinKeep, 0, "minimum number of backups to keep to evaluate, defaults to the configured one")
+	cmdRetention.AddCommand(cmdRetentionPlan)
+
+	cmdRetentionRelabel.Flags().Bool(flagRelabelApply, false, "apply the changes instead of only showing them")
+	cmdRetention.AddCommand(cmdRetentionRelabel)
+	cmdRoot.AddCommand(cmdRetention)
+}
+
+func cmdRetentionPlanRunE(cmd *cobra.Command, _ []string) error {
+	relabel, err := cmd.Flags().GetBool(flagPlanRelabel)
+	if err != nil {
+		return errors.Wrapf(err, "getting %s flag value", flagPlanRelabel)
+	}
+
+	retentionSpecs, err := cmd.Flags().GetStringArray(flagPlanRetention)
+	if err != nil {
+		return errors.Wrapf(err, "getting %s flag value", flagPlanRetention)
+	}
+
+	countSpecs, err := cmd.Flags().GetStringArray(flagPlanRetentionCount)
+	if err != nil {
+		return errors.Wrapf(err, "getting %s flag value", flagPlanRetentionCount)
+	}
+
+	var minKeep string
+	if cmd.Flags().Changed(flagPlanMinKeep) {
+		minKeep = cmd.Flags().Lookup(flagPlanMinKeep).Value.String()
+	}
+
+	policy, err := parseRetentionPolicy(retentionSpecs, countSpecs, minKeep)
+	if err != nil {
+		return errors.Wrap(err, "parsing retention")
+	}
+
+	for _, plan := range planRetention(context.Background(), policy, relabel) {
+		if plan.Error != "" {
+			return errors.Errorf("planning retention in %s: %s", plan.Location, plan.Error)
+		}
+
+		if _, err = fmt.Fprintf(cmd.OutOrStdout(), "Location %s: %d backup(s)\n", plan.Location, len(plan.Backups)); err != nil {
+			return errors.Wrap(err, "writing output")
+		}
+
+		if err = printEntryPlans(cmd.OutOrStdout(), plan.Backups); err != nil {
+			return errors.Wrap(err, "printing plan")
+		}
+	}
+
+	return nil
+}
+
+func cmdRetentionRelabelRunE(cmd *cobra.Command, _ []string) error {
+	apply, err := cmd.Flags().GetBool(flagRelabelApply)
+	if err != nil {
+		return errors.Wrapf(err, "getting %s flag value", flagRelabelApply)
+	}
+
+	for i := range configStorage.BackupLocations {
+		loc := configStorage.BackupLocations[i]
+
+		stor, err := storage.New(context.Background(), &loc, &configBackup)
+		if err != nil {
+			return errors.Wrapf(err, "getting storage provider for %s", loc.StorageEndpoint)
+		}
+
+		changes, err := stor.Relabel(context.Background(), apply)
+		if err != nil {
+			return errors.Wrapf(err, "relabeling backups in %s", loc.StorageEndpoint)
+		}
+
+		if _, err = fmt.Fprintf(cmd.OutOrStdout(), "Location %s: %d backup(s) changed\n", loc.StorageEndpoint, len(changes)); err != nil {
+			return errors.Wrap(err, "writing output")
+		}
+
+		if err = printLabelChanges(cmd.OutOrStdout(), changes); err != nil {
+			return errors.Wrap(err, "printing changes")
+		}
+	}
+
+	if apply {
+		return nil
+	}
+
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "Nothing was changed, use --%s to apply the changes\n", flagRelabelApply)
+	return errors.Wrap(err, "writing output")
+}
+
+func printLabelChanges(w io.Writer, changes []labelmanager.LabelChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:mnd // Just formatting
+	if _, err := fmt.Fprintln(tw, "BACKUP\tADDED LABELS\tREMOVED LABELS\tRETAINED UNTIL\tPREVIOUSLY RETAINED UNTIL"); err != nil {
+		return errors.Wrap(err, "writing header")
+	}
+
+	for _, c := range changes {
+		if _, err := fmt.Fprintf(
+			tw, "%s\t%s\t%s\t%s\t%s\n",
+			c.Entry,
+			formatList(c.AddedLabels),
+			formatList(c.RemovedLabels),
+			formatRetainedUntil(c.RetainedUntil),
+			formatRetainedUntil(c.PreviousRetainedUntil),
+		); err != nil {
+			return errors.Wrap(err, "writing change")
+		}
+	}
+
+	return errors.Wrap(tw.Flush(), "flushing table")
+}
+
+func printEntryPlans(w io.Writer, plans []labelmanager.EntryPlan) error {
+	if len(plans) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:mnd // Just formatting
+	if _, err := fmt.Fprintln(tw, "BACKUP\tREMOVED BY\tLABELS"); err != nil {
+		return errors.Wrap(err, "writing header")
+	}
+
+	for _, p := range plans {
+		removal := "next cleanup"
+		switch {
+		case p.Pin != nil:
+			removal = "pinned " + formatPin(p.Pin)
+		case p.KeptByMinKeep:
+			removal = "kept as one of the most recent backups"
+		case p.Retained && p.RetainedUntil.IsZero():
+			removal = "kept by count"
+		case p.Retained:
+			removal = "first cleanup after " + formatRetainedUntil(p.RetainedUntil)
+		}
+
+		labels := make([]string, 0, len(p.Labels))
+		for _, l := range p.Labels {
+			state := "expires"
+			switch {
+			case l.Expired && l.KeptByCount:
+				state = "kept by count, expired"
+			case l.Expired:
+				state = "expired"
+			}
+			labels = append(labels, fmt.Sprintf("%s (%s, %s %s)", l.Name, l.Format, state, l.ExpiresAt.UTC().Format(time.RFC3339)))
+		}
+
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Entry, removal, formatList(labels)); err != nil {
+			return errors.Wrap(err, "writing plan")
+		}
+	}
+
+	return errors.Wrap(tw.Flush(), "flushing table")
+}
+
+func formatList(l []string) string {
+	if len(l) == 0 {
+		return "-"
+	}
+
+	return strings.Join(l, ", ")
+}
+
+func formatRetainedUntil(t time.Time) string {
+	if t.IsZero() {
+		return "not retained"
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatPin(p *labelmanager.Pin) string {
+	if p == nil {
+		return "-"
+	}
+
+	out := "until unpinned"
+	if !p.Until.IsZero() {
+		out = "until " + p.Until.UTC().Format(time.RFC3339)
+	}
+
+	if p.Reason != "" {
+		out += fmt.Sprintf(" (%s)", p.Reason)
+	}
+
+	return out
+}
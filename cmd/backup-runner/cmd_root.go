@@ -27,6 +27,7 @@ var (
 		PersistentPreRunE: cmdRootPersistentPreRunE,
 	}
 
+	apiToken      string
 	baseURL       string
 	configBackup  v1.DatabaseBackup
 	configStorage v1.DatabaseBackupStorageClassSpec
@@ -77,6 +78,10 @@ func cmdRootPersistentPreRunE(cmd *cobra.Command, _ []string) (err error) {
 		return errors.Wrap(err, "loading storage spec from configuration")
 	}
 
+	if apiToken, err = readAPIToken(path.Join(configPath, "apiToken")); err != nil {
+		return errors.Wrap(err, "loading API token from configuration")
+	}
+
 	httpMux = mux.NewRouter()
 
 	return nil
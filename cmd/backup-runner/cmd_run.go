@@ -5,8 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -23,7 +26,19 @@ import (
 	"github.com/NectGmbH/db-backup-controller/pkg/storage"
 )
 
+const (
+	ipcLogsPollTimeout = 20 * time.Second
+	ipcRequestTimeout  = 5 * time.Second
+	requestBodyLimit   = 64 * 1024
+)
+
 type (
+	ipcLogsResponse struct {
+		Lines  []string `json:"lines"`
+		Offset int      `json:"offset"`
+		Done   bool     `json:"done"`
+	}
+
 	ipcPayload struct {
 		Action string   `json:"action"`
 		Args   []string `json:"args"`
@@ -56,6 +71,9 @@ func cmdRunRunE(cmd *cobra.Command, _ []string) (err error) {
 	// Initialize the monitoring
 	monitor = newAppMonitor()
 
+	// Collect the logs of the jobs for the IPC clients
+	logrus.AddHook(jobs)
+
 	if err = updateBackupCountFromLocation(configStorage.BackupLocations[0]); err != nil {
 		logrus.WithError(err).Error("updating backup count metric")
 	}
@@ -70,14 +88,33 @@ func cmdRunRunE(cmd *cobra.Command, _ []string) (err error) {
 		return errors.Wrap(err, "initializing backup engine")
 	}
 
-	// Add the IPC route
+	// Add the IPC routes
 	httpMux.HandleFunc("/ipc", handleIPCRequest).
 		Methods(http.MethodPost).
-		MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
-			// IPC route must be called through loopback interface
-			host, _, _ := net.SplitHostPort(r.RemoteAddr)
-			return host == "127.0.0.1" || host == "[::1]"
-		})
+		MatcherFunc(isLoopbackRequest)
+	httpMux.HandleFunc("/ipc/jobs/{id}", handleJobStatus).
+		Methods(http.MethodGet).
+		MatcherFunc(isLoopbackRequest)
+	httpMux.HandleFunc("/ipc/jobs/{id}/logs", handleJobLogs).
+		Methods(http.MethodGet).
+		MatcherFunc(isLoopbackRequest)
+
+	// Add the status route queried by the controller
+	httpMux.HandleFunc("/status", requireAPIToken(handleStatus)).
+		Methods(http.MethodGet)
+
+	// Add the on-demand backup and restore routes used by the controller
+	// to execute DatabaseBackupRuns and DatabaseRestores
+	httpMux.HandleFunc("/backups", requireAPIToken(handleBackupRequest)).
+		Methods(http.MethodPost)
+	httpMux.HandleFunc("/restores", requireAPIToken(handleRestoreRequest)).
+		Methods(http.MethodPost)
+	httpMux.HandleFunc("/jobs/{id}", requireAPIToken(handleJobStatus)).
+		Methods(http.MethodGet)
+
+	// Add the retention explain route
+	httpMux.HandleFunc("/retention/plan", handleRetentionPlan).
+		Methods(http.MethodGet)
 
 	// Register Prometheus metrics
 	httpMux.Handle("/metrics", promhttp.Handler())
@@ -89,10 +126,16 @@ func cmdRunRunE(cmd *cobra.Command, _ []string) (err error) {
 		return errors.Wrapf(err, "getting %s flag value", flagListen)
 	}
 
+	// The access logs must not end up in the job logs: Following the
+	// logs of a job would otherwise produce new log lines endlessly
+	accessLogger := logrus.New()
+	accessLogger.SetFormatter(logrus.StandardLogger().Formatter)
+	accessLogger.SetLevel(logrus.GetLevel())
+
 	var (
 		httpServer = &http.Server{
 			Addr:              listenAddr,
-			Handler:           httpHelper.NewHTTPLogHandlerWithLogger(httpMux, logrus.StandardLogger()),
+			Handler:           httpHelper.NewHTTPLogHandlerWithLogger(httpMux, accessLogger),
 			ReadHeaderTimeout: time.Second,
 		}
 		httpServerErr = make(chan error, 1)
@@ -111,7 +154,7 @@ func cmdRunRunE(cmd *cobra.Command, _ []string) (err error) {
 	for {
 		select {
 		case <-triggerAutoBackup:
-			if err := triggerRunAction("backup", nil); err != nil {
+			if _, err := startJob("", "backup", nil); err != nil {
 				logrus.WithError(err).Error("triggering automatic background backup")
 			}
 
@@ -124,6 +167,75 @@ func cmdRunRunE(cmd *cobra.Command, _ []string) (err error) {
 	}
 }
 
+// handleBackupRequest starts an on-demand backup for the controller
+// and returns the job to follow it
+func handleBackupRequest(w http.ResponseWriter, r *http.Request) {
+	var req backupRequest
+
+	dec := json.NewDecoder(io.LimitReader(r.Body, requestBodyLimit))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, errors.Wrap(err, "decoding request").Error(), http.StatusBadRequest)
+		return
+	}
+
+	//nolint:contextcheck // The job must outlive the very short lived request
+	j, err := startJob(req.JobID, "backup", nil)
+	writeJobStarted(w, j, err)
+}
+
+// handleRestoreRequest starts a restore for the controller and
+// returns the job to follow it
+func handleRestoreRequest(w http.ResponseWriter, r *http.Request) {
+	var req restoreRequest
+
+	dec := json.NewDecoder(io.LimitReader(r.Body, requestBodyLimit))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, errors.Wrap(err, "decoding request").Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	args, err := req.IPCArgs()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Validate before starting the job to fail the request instead
+	if _, err = parseRestoreArgs(args); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	//nolint:contextcheck // The job must outlive the very short lived request
+	j, err := startJob(req.JobID, "restore", args)
+	writeJobStarted(w, j, err)
+}
+
+// writeJobStarted responds with the started job or the reason it could
+// not be started
+func writeJobStarted(w http.ResponseWriter, j *job, err error) {
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusCreated, j.Status())
+
+	case errors.Is(err, errActionRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+
+	case errors.Is(err, errInvalidJobID):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+	default:
+		http.Error(w, errors.Wrap(err, "starting job").Error(), http.StatusInternalServerError)
+	}
+}
+
 func handleIPCRequest(w http.ResponseWriter, r *http.Request) {
 	var payload ipcPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
@@ -131,13 +243,87 @@ func handleIPCRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//nolint:contextcheck // Makes no sense to pass this very short lived context through
-	go func() {
-		if err := triggerRunAction(payload.Action, payload.Args); err != nil {
-			logrus.WithError(err).Error("triggering action from IPC request")
+	if payload.Action == "cancel" {
+		// Cancelling is no job on its own as it must be possible while
+		// another action is running. Arguments: job-id (optional)
+		var id string
+		if len(payload.Args) > 0 {
+			id = payload.Args[0]
 		}
-	}()
-	w.WriteHeader(http.StatusCreated)
+
+		j, err := jobs.Cancel(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, j.Status())
+		return
+	}
+
+	//nolint:contextcheck // The job must outlive the very short lived request
+	j, err := startJob("", payload.Action, payload.Args)
+	switch {
+	case err == nil:
+		// Job is running
+
+	case errors.Is(err, errActionRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+
+	default:
+		http.Error(w, errors.Wrap(err, "starting job").Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, j.Status())
+}
+
+func handleJobLogs(w http.ResponseWriter, r *http.Request) {
+	j := jobs.Get(mux.Vars(r)["id"])
+	if j == nil {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	// Long-poll: Wait for new lines if there are none yet
+	lines, next, done, updated := j.LogsSince(offset)
+	if len(lines) == 0 && !done {
+		select {
+		case <-updated:
+			lines, next, done, _ = j.LogsSince(offset)
+		case <-time.After(ipcLogsPollTimeout):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, ipcLogsResponse{
+		Lines:  lines,
+		Offset: next,
+		Done:   done,
+	})
+}
+
+func handleJobStatus(w http.ResponseWriter, r *http.Request) {
+	j := jobs.Get(mux.Vars(r)["id"])
+	if j == nil {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, j.Status())
+}
+
+// handleStatus reports the results of the backups to the controller
+// to be written into the status of the DatabaseBackup
+func handleStatus(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, monitor.BackupResults())
 }
 
 func isEncrypted() string {
@@ -147,7 +333,7 @@ func isEncrypted() string {
 	)
 
 	for _, loc := range configStorage.BackupLocations {
-		if loc.EncryptionPass.Value == "" {
+		if !isLocationEncrypted(loc) {
 			hasNoPass = true
 		} else {
 			hasPass = true
@@ -166,7 +352,93 @@ func isEncrypted() string {
 	}
 }
 
+// isLoopbackRequest ensures IPC routes are called through the
+// loopback interface
+func isLoopbackRequest(r *http.Request, _ *mux.RouteMatch) bool {
+	host, _, _ := net.SplitHostPort(r.RemoteAddr)
+	return host == "127.0.0.1" || host == "::1"
+}
+
+func isLocationEncrypted(loc v1.DatabaseBackupStorageLocation) bool {
+	return loc.EncryptionPass.Value != "" || len(loc.EncryptionRecipients) > 0
+}
+
 func triggerIPCRequest(cmd *cobra.Command, payload ipcPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "marshalling IPC payload")
+	}
+
+	var status jobStatus
+	if err = ipcCall(cmd, http.MethodPost, "/ipc", bytes.NewReader(body), http.StatusCreated, ipcRequestTimeout, &status); err != nil {
+		return err
+	}
+
+	wait, err := cmd.Flags().GetBool(flagWait)
+	if err != nil {
+		return errors.Wrapf(err, "getting %s flag value", flagWait)
+	}
+
+	if !wait {
+		logrus.WithField("job", status.ID).Info("IPC request started successfully, see runner logs for details")
+		return nil
+	}
+
+	if status, err = followJob(cmd, status.ID); err != nil {
+		return err
+	}
+
+	return jobResult(status)
+}
+
+// followJob prints the log lines of the job until it finished and
+// returns its final status
+func followJob(cmd *cobra.Command, id string) (status jobStatus, err error) {
+	var offset int
+	for {
+		var logs ipcLogsResponse
+		path := fmt.Sprintf("/ipc/jobs/%s/logs?offset=%d", id, offset)
+		if err = ipcCall(cmd, http.MethodGet, path, nil, http.StatusOK, ipcLogsPollTimeout+ipcRequestTimeout, &logs); err != nil {
+			return status, errors.Wrap(err, "fetching job logs")
+		}
+
+		for _, line := range logs.Lines {
+			if _, err = fmt.Fprintln(cmd.OutOrStdout(), line); err != nil {
+				return status, errors.Wrap(err, "writing job logs")
+			}
+		}
+
+		offset = logs.Offset
+		if logs.Done {
+			break
+		}
+	}
+
+	if err = ipcCall(cmd, http.MethodGet, "/ipc/jobs/"+id, nil, http.StatusOK, ipcRequestTimeout, &status); err != nil {
+		return status, errors.Wrap(err, "fetching job status")
+	}
+
+	return status, nil
+}
+
+// jobResult reports an error if the job did not succeed
+func jobResult(status jobStatus) error {
+	switch status.Status {
+	case jobStatusCancelled:
+		return errors.Errorf("job %s was cancelled", status.ID)
+
+	case jobStatusFailed:
+		return errors.Errorf("job %s failed: %s", status.ID, status.Error)
+
+	default:
+		return nil
+	}
+}
+
+// ipcCall executes a request against the IPC routes of the runner
+// listening on the port given in the listen flag and decodes the
+// response into out
+func ipcCall(cmd *cobra.Command, method, path string, body io.Reader, expectStatus int, timeout time.Duration, out any) error {
 	listenAddr, err := cmd.Flags().GetString(flagListen)
 	if err != nil {
 		// How?
@@ -178,15 +450,10 @@ func triggerIPCRequest(cmd *cobra.Command, payload ipcPayload) error {
 		return errors.Wrap(err, "getting port from listen address")
 	}
 
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return errors.Wrap(err, "marshalling IPC payload")
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("http://127.0.0.1:%s/ipc", port), bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("http://127.0.0.1:%s%s", port, path), body)
 	if err != nil {
 		return errors.Wrap(err, "creating IPC request")
 	}
@@ -201,46 +468,85 @@ func triggerIPCRequest(cmd *cobra.Command, payload ipcPayload) error {
 		}
 	}()
 
-	if resp.StatusCode != http.StatusCreated {
-		return errors.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	if resp.StatusCode != expectStatus {
+		msg, _ := io.ReadAll(resp.Body) // Only used to enrich the error
+		return errors.Errorf("unexpected HTTP status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
 	}
 
-	logrus.Info("IPC request started successfully, see runner logs for details")
-
-	return nil
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(out), "decoding IPC response")
 }
 
-func triggerRunAction(action string, args []string) error {
-	if !actionRunning.CompareAndSwap(false, true) {
-		// We did not switch from not-running to running: We must not run!
-		return errors.New("concurrent action running")
-	}
-	defer actionRunning.Store(false)
-
+// executeAction executes the given action with its arguments and
+// records the result in the job metrics. The action is stopped when
+// the context is cancelled.
+func executeAction(ctx context.Context, action string, args []string) (err error) {
 	switch action {
 	case "backup":
-		err := executeBackup()
-		if err != nil {
-			logrus.WithError(err).Error("executing backup action")
-		}
+		err = executeBackup(ctx)
 		monitor.RegisterJobStatus(metricsLabelValueJobTypeBackup, err == nil)
 
 	case "restore":
-		if len(args) != 2 { //nolint:mnd
+		// Arguments: restore-mode, backup-id, optional identities,
+		// optional location index, optional target (JSON)
+		var o restoreOpts
+		if o, err = parseRestoreArgs(args); err != nil {
+			return err
+		}
+
+		err = executeRestore(ctx, o)
+		monitor.RegisterJobStatus(metricsLabelValueJobTypeRestore, err == nil)
+
+	case "pin":
+		// Arguments: backup, reason, expiry (RFC3339, optional)
+		if len(args) != 3 {
 			return errors.Errorf("invalid number of arguments")
 		}
 
-		err := executeRestore(args[0], args[1])
-		if err != nil {
-			logrus.WithError(err).Error("executing restore action")
+		var until time.Time
+		if until, err = parsePinUntil(args[2]); err != nil {
+			return err
 		}
-		monitor.RegisterJobStatus(metricsLabelValueJobTypeRestore, err == nil)
+
+		err = executePin(ctx, args[0], args[1], until)
+		monitor.RegisterJobStatus(metricsLabelValueJobTypePin, err == nil)
+
+	case "unpin":
+		// Arguments: backup
+		if len(args) != 1 {
+			return errors.Errorf("invalid number of arguments")
+		}
+
+		err = executeUnpin(ctx, args[0])
+		monitor.RegisterJobStatus(metricsLabelValueJobTypeUnpin, err == nil)
+
+	case "reindex":
+		err = executeReindex(ctx)
+		monitor.RegisterJobStatus(metricsLabelValueJobTypeReindex, err == nil)
 
 	default:
-		logrus.WithError(errors.Errorf("unknown action %s", action)).Error("invalid action called")
+		return errors.Errorf("unknown action %s", action)
 	}
 
-	return nil
+	return contextError(ctx, err)
+}
+
+// contextError reports the reason the context ended (timeout or
+// cancellation) instead of the error caused by it (i.e. a killed
+// database tool) as the cause of the given error
+func contextError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil || errors.Is(err, ctx.Err()) {
+		return err
+	}
+
+	return errors.Wrap(ctx.Err(), err.Error())
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logrus.WithError(err).Error("encoding response")
+	}
 }
 
 func updateBackupCountFromLocation(loc v1.DatabaseBackupStorageLocation) error {
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	jobHistoryLength = 16
+	jobIDLength      = 8
+	// jobLogLines is the number of log lines kept per job: Older lines
+	// are dropped and reported as dropped to readers not having read
+	// them yet
+	jobLogLines = 10000
+
+	jobStatusCancelled = "cancelled"
+	jobStatusFailed    = "failed"
+	jobStatusRunning   = "running"
+	jobStatusSucceeded = "succeeded"
+)
+
+type (
+	// jobStatus describes the state of an action triggered through IPC
+	// or by the automatic backup
+	jobStatus struct {
+		ID         string     `json:"id"`
+		Action     string     `json:"action"`
+		Status     string     `json:"status"`
+		Error      string     `json:"error,omitempty"`
+		Backup     string     `json:"backup,omitempty"`
+		Size       int64      `json:"size,omitempty"`
+		StartedAt  time.Time  `json:"startedAt"`
+		FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	}
+
+	job struct {
+		cancel  context.CancelFunc
+		lock    sync.RWMutex
+		logs    logRing
+		status  jobStatus
+		updated chan struct{}
+	}
+
+	// logRing keeps the last jobLogLines log lines of a job. Lines are
+	// addressed by their offset counted from the first line ever added.
+	logRing struct {
+		lines []string
+		total int
+	}
+
+	// jobRegistry keeps track of the current and the last jobs and
+	// collects the log lines emitted while a job is running
+	jobRegistry struct {
+		lock    sync.RWMutex
+		current *job
+		jobs    []*job
+	}
+)
+
+var (
+	errActionRunning = errors.New("concurrent action running")
+	errInvalidJobID  = errors.New("invalid job id")
+	errNoJobRunning  = errors.New("no job running")
+
+	// jobIDPattern restricts the IDs chosen by clients (i.e. the UIDs
+	// of the objects the controller starts jobs for)
+	jobIDPattern = regexp.MustCompile(`^[a-zA-Z0-9-]{1,64}$`)
+
+	jobs = &jobRegistry{}
+)
+
+// startJob executes the action in the background unless another one
+// is running and returns the job to follow it. The ID is generated
+// if empty: When given by the client and a job with that ID is still
+// known it is returned instead of executing the action again, so
+// clients can safely retry their requests.
+func startJob(id, action string, args []string) (*job, error) {
+	if id != "" {
+		if !jobIDPattern.MatchString(id) {
+			return nil, errors.Wrapf(errInvalidJobID, "%q", id)
+		}
+
+		if j := jobs.Get(id); j != nil {
+			return j, nil
+		}
+	}
+
+	if !actionRunning.CompareAndSwap(false, true) {
+		// We did not switch from not-running to running: We must not run!
+		return nil, errActionRunning
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	j, err := jobs.Start(id, action, cancel)
+	if err != nil {
+		cancel()
+		actionRunning.Store(false)
+		return nil, err
+	}
+
+	go func() {
+		defer actionRunning.Store(false)
+		defer cancel()
+		jobs.Finish(j, executeAction(ctx, action, args))
+	}()
+
+	return j, nil
+}
+
+// Cancel requests the job with the given ID (or the current job if
+// the ID is empty) to stop and returns it. The job keeps running
+// until the action returned.
+func (r *jobRegistry) Cancel(id string) (*job, error) {
+	r.lock.RLock()
+	j := r.current
+	r.lock.RUnlock()
+
+	if id != "" {
+		j = r.Get(id)
+	}
+
+	if j == nil || j.Status().FinishedAt != nil {
+		return nil, errNoJobRunning
+	}
+
+	logrus.WithField("job", j.status.ID).Warnf("cancelling %s action", j.status.Action)
+	j.cancel()
+
+	return j, nil
+}
+
+// Fire implements the logrus.Hook interface and collects the log
+// lines into the currently running job
+func (r *jobRegistry) Fire(entry *logrus.Entry) error {
+	r.lock.RLock()
+	current := r.current
+	r.lock.RUnlock()
+
+	if current == nil {
+		return nil
+	}
+
+	line, err := entry.String()
+	if err != nil {
+		return errors.Wrap(err, "formatting log entry")
+	}
+
+	current.appendLog(strings.TrimRight(line, "\n"))
+	return nil
+}
+
+// Finish marks the job as finished with the result of the action
+func (r *jobRegistry) Finish(j *job, err error) {
+	if err != nil {
+		logrus.WithError(err).WithField("job", j.status.ID).Errorf("executing %s action", j.status.Action)
+	}
+
+	r.lock.Lock()
+	if r.current == j {
+		r.current = nil
+	}
+	r.lock.Unlock()
+
+	j.finish(err)
+}
+
+// Get returns the job with the given ID or nil if it is not known
+// (anymore)
+func (r *jobRegistry) Get(id string) *job {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, j := range r.jobs {
+		if j.status.ID == id {
+			return j
+		}
+	}
+
+	return nil
+}
+
+// Levels implements the logrus.Hook interface
+func (*jobRegistry) Levels() []logrus.Level { return logrus.AllLevels }
+
+// RecordBackup stores the name and the stored size of the backup
+// created by the current job to be reported in its status
+func (r *jobRegistry) RecordBackup(name string, size int64) {
+	r.lock.RLock()
+	current := r.current
+	r.lock.RUnlock()
+
+	if current == nil {
+		return
+	}
+
+	current.lock.Lock()
+	defer current.lock.Unlock()
+
+	current.status.Backup = name
+	current.status.Size = size
+}
+
+// Start registers a new running job for the action, dropping the
+// oldest job if the history is full. The cancel function is called
+// when the job is requested to stop. An empty ID is generated.
+func (r *jobRegistry) Start(id, action string, cancel context.CancelFunc) (*job, error) {
+	if id == "" {
+		rnd := make([]byte, jobIDLength)
+		if _, err := rand.Read(rnd); err != nil {
+			return nil, errors.Wrap(err, "generating job id")
+		}
+		id = hex.EncodeToString(rnd)
+	}
+
+	j := &job{
+		cancel: cancel,
+		status: jobStatus{
+			ID:        id,
+			Action:    action,
+			Status:    jobStatusRunning,
+			StartedAt: time.Now().UTC(),
+		},
+		updated: make(chan struct{}),
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.current = j
+	r.jobs = append(r.jobs, j)
+	if len(r.jobs) > jobHistoryLength {
+		r.jobs = r.jobs[len(r.jobs)-jobHistoryLength:]
+	}
+
+	return j, nil
+}
+
+// LogsSince returns the log lines starting at the given offset, the
+// offset to continue reading at, whether the job is finished and a
+// channel being closed on the next update of the job. Only the last
+// jobLogLines lines are kept, lines dropped before being read are
+// replaced by a single line telling how many were dropped.
+func (j *job) LogsSince(offset int) (lines []string, next int, done bool, updated <-chan struct{}) {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+
+	lines, next = j.logs.Since(offset)
+	return lines, next, j.status.FinishedAt != nil, j.updated
+}
+
+// Status returns a copy of the current status of the job
+func (j *job) Status() jobStatus {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+
+	return j.status
+}
+
+func (j *job) appendLog(line string) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.logs.Add(line)
+	j.notify()
+}
+
+func (j *job) finish(err error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	now := time.Now().UTC()
+	j.status.FinishedAt = &now
+	switch {
+	case err == nil:
+		j.status.Status = jobStatusSucceeded
+
+	case errors.Is(err, context.Canceled):
+		j.status.Status = jobStatusCancelled
+		j.status.Error = err.Error()
+
+	default:
+		j.status.Status = jobStatusFailed
+		j.status.Error = err.Error()
+	}
+
+	j.notify()
+}
+
+// Add appends the line replacing the oldest one if the ring is full
+func (l *logRing) Add(line string) {
+	if len(l.lines) < jobLogLines {
+		l.lines = append(l.lines, line)
+	} else {
+		l.lines[l.total%jobLogLines] = line
+	}
+	l.total++
+}
+
+// Since returns the lines starting at the given offset and the offset
+// following the last line
+func (l logRing) Since(offset int) (lines []string, next int) {
+	if offset >= l.total {
+		return nil, offset
+	}
+
+	if dropped := l.total - len(l.lines); offset < dropped {
+		lines = append(lines, fmt.Sprintf("[%d log lines dropped]", dropped-offset))
+		offset = dropped
+	}
+
+	for i := offset; i < l.total; i++ {
+		lines = append(lines, l.lines[i%jobLogLines])
+	}
+
+	return lines, l.total
+}
+
+// notify wakes up everyone waiting for an update. It MUST NOT be used
+// without previously acquiring a write-lock!
+func (j *job) notify() {
+	close(j.updated)
+	j.updated = make(chan struct{})
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
 )
 
 const (
@@ -14,7 +18,10 @@ const (
 	metricsLabelJobType         = "job_type"
 
 	metricsLabelValueJobTypeBackup  = "backup"
+	metricsLabelValueJobTypePin     = "pin"
+	metricsLabelValueJobTypeReindex = "reindex"
 	metricsLabelValueJobTypeRestore = "restore"
+	metricsLabelValueJobTypeUnpin   = "unpin"
 
 	metricsNameLastJobSuccess       = "last_job_success"
 	metricsNameLastSuccessfulBackup = "last_successful_backup"
@@ -32,6 +39,11 @@ type (
 		mGRunnerStartedAt      prometheus.Gauge
 		mGStoredBackupCount    prometheus.Gauge
 		mGVKLastJobStatus      *prometheus.GaugeVec
+
+		// results are reported to the controller to be shown in the
+		// status of the DatabaseBackup
+		results     v1.BackupResultStatus
+		resultsLock sync.RWMutex
 	}
 )
 
@@ -81,7 +93,7 @@ func newAppMonitor() *appMonitor {
 	return &am
 }
 
-func (appMonitor) InstanceConstLabels() prometheus.Labels {
+func (*appMonitor) InstanceConstLabels() prometheus.Labels {
 	return prometheus.Labels{
 		metricsLabelBackupName:      configBackup.Name,
 		metricsLabelBackupNamespace: configBackup.Namespace,
@@ -107,6 +119,43 @@ func (a *appMonitor) RegisterJobStatus(jobType string, successful bool) {
 	}
 }
 
+// BackupResults returns a copy of the results of the backups executed
+// since the runner was started
+func (a *appMonitor) BackupResults() v1.BackupResultStatus {
+	if a == nil {
+		// Monitoring is not initialized, nothing to report
+		return v1.BackupResultStatus{}
+	}
+
+	a.resultsLock.RLock()
+	defer a.resultsLock.RUnlock()
+
+	return *a.results.DeepCopy()
+}
+
+// RegisterBackupResult records the result of a backup: the name and
+// the stored size of the backup on success or the error on failure
+func (a *appMonitor) RegisterBackupResult(backupName string, size int64, err error) {
+	if a == nil {
+		// Monitoring is not initialized, drop silently
+		return
+	}
+
+	a.resultsLock.Lock()
+	defer a.resultsLock.Unlock()
+
+	now := metav1.Now()
+	if err != nil {
+		a.results.LastFailureTime = &now
+		a.results.LastFailureMessage = err.Error()
+		return
+	}
+
+	a.results.LastSuccessTime = &now
+	a.results.LastBackupName = backupName
+	a.results.LastBackupSize = size
+}
+
 func (a *appMonitor) UpdateNextScheduled(t time.Time) {
 	if a == nil {
 		// Monitoring is not initialized, drop silently
@@ -115,6 +164,12 @@ func (a *appMonitor) UpdateNextScheduled(t time.Time) {
 
 	// Copied from the implementation of SetToCurrentTime
 	a.mGNextScheduledBackup.Set(float64(t.UnixNano()) / 1e9) //nolint:mnd
+
+	a.resultsLock.Lock()
+	defer a.resultsLock.Unlock()
+
+	next := metav1.NewTime(t)
+	a.results.NextScheduledTime = &next
 }
 
 func (a *appMonitor) UpdateStoredBackupCount(n int) {
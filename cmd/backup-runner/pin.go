@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/NectGmbH/db-backup-controller/pkg/storage"
+)
+
+func executePin(ctx context.Context, backup, reason string, until time.Time) error {
+	// * Pins the backup in every storage location so the cleanup keeps
+	//   it regardless of its labels (=> ./pkg/labelmanager/...)
+
+	return forEachLocation(ctx, "pin", func(stor storage.Manager) error {
+		return errors.Wrap(stor.Pin(ctx, backup, reason, until), "pinning backup")
+	})
+}
+
+func executeUnpin(ctx context.Context, backup string) error {
+	// * Removes the pin from the backup in every storage location, the
+	//   labels decide about its retention afterwards
+
+	return forEachLocation(ctx, "unpin", func(stor storage.Manager) error {
+		return errors.Wrap(stor.Unpin(ctx, backup), "unpinning backup")
+	})
+}
+
+// forEachLocation executes fn for the storage of every location and
+// reports an error if it failed for any of them. Remaining locations
+// are skipped when the context is cancelled.
+func forEachLocation(ctx context.Context, action string, fn func(storage.Manager) error) error {
+	var failed int
+
+	for i := range configStorage.BackupLocations {
+		if ctx.Err() != nil {
+			return errors.Wrapf(ctx.Err(), "executing %s", action)
+		}
+
+		loc := configStorage.BackupLocations[i]
+
+		logger := logrus.WithField("location", loc.StorageEndpoint)
+
+		stor, err := storage.New(ctx, &loc, &configBackup)
+		if err != nil {
+			logger.WithError(err).Error("getting storage provider")
+			failed++
+			continue
+		}
+
+		if err = fn(stor); err != nil {
+			logger.WithError(err).Errorf("executing %s in location", action)
+			failed++
+			continue
+		}
+
+		logger.Infof("%s completed", action)
+	}
+
+	if failed > 0 {
+		return errors.Errorf("%s failed for %d of %d locations", action, failed, len(configStorage.BackupLocations))
+	}
+
+	return nil
+}
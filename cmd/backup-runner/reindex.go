@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/NectGmbH/db-backup-controller/pkg/storage"
+)
+
+func executeReindex(ctx context.Context) (err error) {
+	// * Lists the backups present in every storage location
+	// * Replaces the labels with the ones the RetentionPolicy would
+	//   have assigned to them (=> ./pkg/labelmanager/...)
+
+	var failed int
+
+	for i := range configStorage.BackupLocations {
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "reindexing backups")
+		}
+
+		loc := configStorage.BackupLocations[i]
+
+		logger := logrus.WithField("location", loc.StorageEndpoint)
+		logger.Info("reindexing backups")
+
+		stor, err := storage.New(ctx, &loc, &configBackup)
+		if err != nil {
+			logger.WithError(err).Error("getting storage provider")
+			failed++
+			continue
+		}
+
+		if err = stor.Reindex(ctx); err != nil {
+			logger.WithError(err).Error("reindexing location")
+			failed++
+			continue
+		}
+
+		logger.Info("reindex completed")
+	}
+
+	if err = updateBackupCountFromLocation(configStorage.BackupLocations[0]); err != nil {
+		logrus.WithError(err).Error("updating backup count metric")
+	}
+
+	if failed > 0 {
+		return errors.Errorf("reindex failed for %d of %d locations", failed, len(configStorage.BackupLocations))
+	}
+
+	return nil
+}
@@ -2,32 +2,158 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
 	"github.com/NectGmbH/db-backup-controller/pkg/backupengine"
+	"github.com/NectGmbH/db-backup-controller/pkg/backupengine/opts"
+	"github.com/NectGmbH/db-backup-controller/pkg/compressstream"
 	"github.com/NectGmbH/db-backup-controller/pkg/cryptostream"
 	"github.com/NectGmbH/db-backup-controller/pkg/storage"
-	"github.com/NectGmbH/db-backup-controller/pkg/storage/helper"
 )
 
-func executeRestore(restoreMode, backupID string) (err error) {
+type (
+	// restoreOpts describes which backup to restore from where to where
+	restoreOpts struct {
+		Mode       string
+		BackupID   string
+		Identities []cryptostream.Identity
+		// Location is the index of the only location to restore from
+		// (all locations are tried in order if negative)
+		Location int
+		// Target overrides the database to restore into
+		Target *v1.RestoreTarget
+	}
+
+	// restoreRequest is sent by the controller to start the restore
+	// of a DatabaseRestore
+	restoreRequest struct {
+		JobID      string            `json:"jobID,omitempty"`
+		Mode       string            `json:"mode"`
+		Backup     string            `json:"backup"`
+		Identities string            `json:"identities,omitempty"`
+		Location   *int              `json:"location,omitempty"`
+		Target     *v1.RestoreTarget `json:"target,omitempty"`
+	}
+)
+
+// Validate restricts the request to what the controller sends for a
+// DatabaseRestore: The target receives the credentials of the
+// DatabaseBackup so it must be a plain host and port.
+func (r restoreRequest) Validate() error {
+	if r.Mode != "name" && r.Mode != "point-in-time" {
+		return errors.Errorf("invalid restore-mode %q", r.Mode)
+	}
+
+	if r.Backup == "" {
+		return errors.New("no backup given")
+	}
+
+	if r.Target != nil {
+		if err := r.Target.Validate(field.NewPath("target")).ToAggregate(); err != nil {
+			return errors.Wrap(err, "validating target")
+		}
+	}
+
+	return nil
+}
+
+// IPCArgs converts the request into the arguments of the restore
+// action (see parseRestoreArgs)
+func (r restoreRequest) IPCArgs() ([]string, error) {
+	args := []string{r.Mode, r.Backup, r.Identities, "", ""}
+
+	if r.Location != nil {
+		args[3] = strconv.Itoa(*r.Location)
+	}
+
+	if r.Target != nil {
+		target, err := json.Marshal(r.Target)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling target")
+		}
+		args[4] = string(target)
+	}
+
+	return args, nil
+}
+
+// parseRestoreArgs parses the arguments of the restore action:
+// restore-mode, backup-id, identities, location index and target
+// (JSON) of which the last three are optional and might be empty
+func parseRestoreArgs(args []string) (o restoreOpts, err error) {
+	if len(args) < 2 || len(args) > 5 {
+		return o, errors.Errorf("invalid number of arguments")
+	}
+
+	o = restoreOpts{Mode: args[0], BackupID: args[1], Location: -1}
+
+	if len(args) > 2 && args[2] != "" {
+		if o.Identities, err = cryptostream.ParseIdentities(strings.NewReader(args[2])); err != nil {
+			return o, errors.Wrap(err, "parsing identities")
+		}
+	}
+
+	if len(args) > 3 && args[3] != "" {
+		if o.Location, err = strconv.Atoi(args[3]); err != nil {
+			return o, errors.Wrap(err, "parsing location index")
+		}
+
+		if o.Location < 0 || o.Location >= len(configStorage.BackupLocations) {
+			return o, errors.Errorf("location %d does not exist", o.Location)
+		}
+	}
+
+	if len(args) > 4 && args[4] != "" {
+		o.Target = &v1.RestoreTarget{}
+		if err = json.Unmarshal([]byte(args[4]), o.Target); err != nil {
+			return o, errors.Wrap(err, "parsing target")
+		}
+	}
+
+	return o, nil
+}
+
+func executeRestore(ctx context.Context, o restoreOpts) (err error) {
 	// Can be asked to restore a backup
 	// * Downloads backup (=> ./pkg/storage/...)
 	// * Askes engine to restore that backup (=> ./pkg/backupengine/...)
 	// * Engine knows what to execute to restore $db to $host with $credentials from file
 
+	ctx, cancel := configBackup.Spec.WithRestoreTimeout(ctx)
+	defer cancel()
+
+	restoreEngine := engine
+	if o.Target != nil {
+		if restoreEngine, err = newTargetEngine(*o.Target); err != nil {
+			return errors.Wrap(err, "initializing engine for restore target")
+		}
+	}
+
 	for i := range configStorage.BackupLocations {
+		if o.Location >= 0 && i != o.Location {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			// Don't try further locations when we ran out of time
+			return errors.Wrap(ctx.Err(), "restoring backup")
+		}
+
 		loc := configStorage.BackupLocations[i]
 
 		logger := logrus.WithField("location", loc.StorageEndpoint)
 		logger.Info("preparing restore")
 
-		if err := restoreForLocation(engine, restoreMode, &loc, backupID); err != nil {
+		if err := restoreForLocation(ctx, restoreEngine, o, &loc); err != nil {
 			logger.WithError(err).Error("restoring from location")
 			continue
 		}
@@ -39,34 +165,59 @@ func executeRestore(restoreMode, backupID string) (err error) {
 	return errors.New("no backup found to restore")
 }
 
-func restoreForLocation(engine backupengine.Implementation, restoreMode string, loc *v1.DatabaseBackupStorageLocation, backupID string) error {
-	stor, err := storage.New(context.Background(), loc, &configBackup)
+// newTargetEngine creates an engine restoring into the given target
+// instead of the configured database
+func newTargetEngine(target v1.RestoreTarget) (backupengine.Implementation, error) { //nolint:ireturn // Engines are only known by their interface
+	if configBackup.Spec.Cockroach != nil {
+		// Cockroach fetches the backup through the HTTP handler bound to
+		// the engine initialized on startup
+		return nil, errors.New("target override is not supported for cockroach")
+	}
+
+	e := backupengine.GetByName(configBackup.Spec.DatabaseType)
+	if err := e.Init(opts.InitOpts{
+		BaseURL: baseURL,
+		Spec:    configBackup.Spec.WithRestoreTarget(target),
+	}); err != nil {
+		return nil, errors.Wrap(err, "initializing backup engine")
+	}
+
+	return e, nil
+}
+
+func restoreForLocation(
+	ctx context.Context,
+	engine backupengine.Implementation,
+	o restoreOpts,
+	loc *v1.DatabaseBackupStorageLocation,
+) error {
+	stor, err := storage.New(ctx, loc, &configBackup)
 	if err != nil {
 		return errors.Wrap(err, "getting storage provider")
 	}
 
-	var (
-		r    helper.ReaderAtCloser
-		size int64
-	)
-	switch restoreMode {
+	var backupName string
+	switch o.Mode {
 	case "name":
-		if r, size, err = stor.DownloadAsReader(context.Background(), backupID); err != nil {
-			return errors.Wrap(err, "getting specified backup")
-		}
+		backupName = o.BackupID
 
 	case "point-in-time":
-		t, err := time.Parse(time.RFC3339, backupID)
+		t, err := time.Parse(time.RFC3339, o.BackupID)
 		if err != nil {
 			return errors.Wrap(err, "parsing point-in-time for RFC3339")
 		}
 
-		if r, size, err = stor.DownloadPITBackupAsReader(context.Background(), t); err != nil {
+		if backupName, err = stor.FindPITBackup(ctx, t); err != nil {
 			return errors.Wrap(err, "getting backup for point-in-time")
 		}
 
 	default:
-		return errors.Errorf("invalid restore-mode %q", restoreMode)
+		return errors.Errorf("invalid restore-mode %q", o.Mode)
+	}
+
+	r, size, err := stor.DownloadAsReader(ctx, backupName)
+	if err != nil {
+		return errors.Wrap(err, "getting backup")
 	}
 	defer func() {
 		if err := r.Close(); err != nil {
@@ -79,16 +230,48 @@ func restoreForLocation(engine backupengine.Implementation, restoreMode string,
 		backupSize             = size
 	)
 
-	if loc.EncryptionPass.Value != "" {
-		cryptR, err := cryptostream.NewReaderAt(r, []byte(loc.EncryptionPass.Value))
+	if isLocationEncrypted(*loc) {
+		cryptR, err := cryptostream.NewReaderAtWithKeys(r, size, cryptostream.Keys{
+			Passphrase: []byte(loc.EncryptionPass.Value),
+			Identities: o.Identities,
+		})
 		if err != nil {
 			return errors.Wrap(err, "creating crypto-reader")
 		}
+
+		// Better find out about a broken backup before starting to
+		// restore it than in the middle of the restore
+		if err = cryptR.Verify(); err != nil {
+			return errors.Wrap(err, "verifying backup integrity")
+		}
+
 		backupSrc = cryptR
-		backupSize = size - cryptostream.HeaderSize
+		backupSize = cryptR.Size()
 	}
 
-	if err = engine.RestoreBackup(backupSrc, backupSize); err != nil {
+	// Backups might have been compressed before encrypting them
+	compressed, err := compressstream.IsCompressed(backupSrc, backupSize)
+	if err != nil {
+		return errors.Wrap(err, "checking for compression")
+	}
+
+	if compressed {
+		compR, err := compressstream.NewReaderAt(backupSrc, backupSize)
+		if err != nil {
+			return errors.Wrap(err, "creating decompression-reader")
+		}
+		defer compR.Close() //nolint:errcheck // Only releases the codec
+
+		backupSrc = compR
+		backupSize = compR.Size()
+	}
+
+	// Report the backup chosen in this location to the DatabaseRestore
+	// which triggered the job
+	logrus.WithField("backup", backupName).Info("restoring backup")
+	jobs.RecordBackup(backupName, size)
+
+	if err = engine.RestoreBackup(ctx, backupSrc, backupSize); err != nil {
 		return errors.Wrap(err, "restoring backup")
 	}
 
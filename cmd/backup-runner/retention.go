@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+	"github.com/NectGmbH/db-backup-controller/pkg/storage"
+)
+
+type (
+	locationRetentionPlan struct {
+		Location string                   `json:"location"`
+		Backups  []labelmanager.EntryPlan `json:"backups,omitempty"`
+		Error    string                   `json:"error,omitempty"`
+	}
+)
+
+// handleRetentionPlan explains the retention of the backups in all
+// locations. The configured RetentionPolicy can be overridden for the
+// evaluation through `retention=<format>=<duration>`,
+// `count=<format>=<number>` and `minKeep=<number>` query parameters,
+// the labels are re-evaluated before when passing `relabel=true`.
+func handleRetentionPlan(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	policy, err := parseRetentionPolicy(query["retention"], query["count"], query.Get("minKeep"))
+	if err != nil {
+		http.Error(w, errors.Wrap(err, "parsing retention").Error(), http.StatusBadRequest)
+		return
+	}
+
+	plans := planRetention(r.Context(), policy, query.Get("relabel") == "true")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(plans); err != nil {
+		logrus.WithError(err).Error("encoding retention plan")
+	}
+}
+
+// parseRetentionPolicy applies the given overrides to the configured
+// RetentionPolicy: `<format>=<duration>` specs replace the durations,
+// `<format>=<number>` specs replace the counts and a non-empty minKeep
+// replaces the minimum number of backups to keep. Without overrides
+// nil is returned to use the configured policy.
+func parseRetentionPolicy(durationSpecs, countSpecs []string, minKeep string) (*labelmanager.RetentionPolicy, error) {
+	if len(durationSpecs) == 0 && len(countSpecs) == 0 && minKeep == "" {
+		return nil, nil //nolint:nilnil // No overrides means to use the configured retention
+	}
+
+	policy := configBackup.Spec.RetentionPolicy()
+
+	if len(durationSpecs) > 0 {
+		policy.Durations = labelmanager.RetentionConfig{}
+		for _, spec := range durationSpecs {
+			format, durSpec, err := cutRetentionSpec(spec, "duration")
+			if err != nil {
+				return nil, err
+			}
+
+			if policy.Durations[format], err = time.ParseDuration(durSpec); err != nil {
+				return nil, errors.Wrapf(err, "parsing duration of %q", format)
+			}
+		}
+	}
+
+	if len(countSpecs) > 0 {
+		policy.Counts = labelmanager.RetentionCounts{}
+		for _, spec := range countSpecs {
+			format, countSpec, err := cutRetentionSpec(spec, "number")
+			if err != nil {
+				return nil, err
+			}
+
+			if policy.Counts[format], err = strconv.Atoi(countSpec); err != nil {
+				return nil, errors.Wrapf(err, "parsing count of %q", format)
+			}
+		}
+	}
+
+	if minKeep != "" {
+		var err error
+		if policy.MinKeep, err = strconv.Atoi(minKeep); err != nil {
+			return nil, errors.Wrap(err, "parsing minimum number of backups to keep")
+		}
+	}
+
+	return &policy, nil
+}
+
+// cutRetentionSpec splits a `<format>=<value>` spec
+func cutRetentionSpec(spec, valueName string) (format, value string, err error) {
+	format, value, ok := strings.Cut(spec, "=")
+	if !ok || format == "" {
+		return "", "", errors.Errorf("invalid retention %q, expected <format>=<%s>", spec, valueName)
+	}
+
+	return format, value, nil
+}
+
+// planRetention explains the retention of the backups in all locations
+// under the given RetentionPolicy (the configured one if nil)
+func planRetention(ctx context.Context, policy *labelmanager.RetentionPolicy, relabel bool) []locationRetentionPlan {
+	plans := make([]locationRetentionPlan, 0, len(configStorage.BackupLocations))
+
+	for i := range configStorage.BackupLocations {
+		var (
+			loc  = configStorage.BackupLocations[i]
+			plan = locationRetentionPlan{Location: loc.StorageEndpoint}
+		)
+
+		stor, err := storage.New(ctx, &loc, &configBackup)
+		if err == nil {
+			plan.Backups, err = stor.PlanRetention(ctx, policy, relabel)
+		}
+
+		if err != nil {
+			plan.Error = err.Error()
+		}
+
+		plans = append(plans, plan)
+	}
+
+	return plans
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -12,6 +14,7 @@ import (
 	"github.com/Luzifer/rconfig/v2"
 
 	"github.com/NectGmbH/db-backup-controller/pkg/backupengine"
+	"github.com/NectGmbH/db-backup-controller/pkg/compressstream"
 	"github.com/NectGmbH/db-backup-controller/pkg/cryptostream"
 )
 
@@ -19,11 +22,13 @@ const backupDestPrivileges = 0o700
 
 var (
 	cfg = struct {
-		BackupEngine   string `flag:"backup-engine,b" default:"" description:"Which engine to use for unpacking the backup (MUST match the engine creating the backup)" validate:"nonzero"` //nolint:lll
-		DestDir        string `flag:"dest-dir,d" default:"" description:"Where to unpack the backup (MUST NOT exist)" validate:"nonzero"`
-		LogLevel       string `flag:"log-level" default:"info" description:"Log level (debug, info, warn, error, fatal)"`
-		Passphrase     string `flag:"passphrase,p" default:"" description:"Specify when unpacking an encrypted backup"`
-		VersionAndExit bool   `flag:"version" default:"false" description:"Prints current version and exits"`
+		BackupEngine     string `flag:"backup-engine,b" default:"" description:"Which engine to use for unpacking the backup (MUST match the engine creating the backup)"` //nolint:lll
+		DestDir          string `flag:"dest-dir,d" default:"" description:"Where to unpack the backup (MUST NOT exist)"`
+		GenerateIdentity bool   `flag:"generate-identity" default:"false" description:"Generates a new identity (private key) for encryption recipients and exits"` //nolint:lll
+		IdentityFile     string `flag:"identity-file,i" default:"" description:"Specify when unpacking a backup encrypted to recipients"`
+		LogLevel         string `flag:"log-level" default:"info" description:"Log level (debug, info, warn, error, fatal)"`
+		Passphrase       string `flag:"passphrase,p" default:"" description:"Specify when unpacking an encrypted backup"`
+		VersionAndExit   bool   `flag:"version" default:"false" description:"Prints current version and exits"`
 	}{}
 
 	version = "dev"
@@ -41,6 +46,10 @@ func initApp() error {
 	}
 	logrus.SetLevel(l)
 
+	if !cfg.GenerateIdentity && !cfg.VersionAndExit && (cfg.BackupEngine == "" || cfg.DestDir == "") {
+		return errors.New("backup-engine and dest-dir must be specified")
+	}
+
 	return nil
 }
 
@@ -55,6 +64,13 @@ func main() {
 		os.Exit(0)
 	}
 
+	if cfg.GenerateIdentity {
+		if err = generateIdentity(); err != nil {
+			logrus.WithError(err).Fatal("generating identity")
+		}
+		os.Exit(0)
+	}
+
 	if len(rconfig.Args()) == 1 {
 		logrus.Fatalf("usage: %s [options] <backup file>", path.Base(rconfig.Args()[0]))
 	}
@@ -87,11 +103,11 @@ func main() {
 		backupReaderAt io.ReaderAt = backup
 		backupSize                 = stat.Size()
 	)
-	if cfg.Passphrase != "" {
-		if backupReaderAt, err = cryptostream.NewReaderAt(backupReaderAt, []byte(cfg.Passphrase)); err != nil {
-			logrus.WithError(err).Fatal("opening crypto-reader")
-		}
-		backupSize -= cryptostream.HeaderSize
+	if backupReaderAt, backupSize, err = openBackupReader(backupReaderAt, backupSize); err != nil {
+		logrus.WithError(err).Fatal("opening backup")
+	}
+	if compR, ok := backupReaderAt.(*compressstream.ReaderAt); ok {
+		defer compR.Close() //nolint:errcheck // Only releases the codec
 	}
 
 	if err = engine.Unpack(backupReaderAt, backupSize, cfg.DestDir); err != nil {
@@ -100,3 +116,83 @@ func main() {
 
 	logrus.Info("backup was successfully unpacked")
 }
+
+func generateIdentity() error {
+	id, err := cryptostream.GenerateIdentity()
+	if err != nil {
+		return errors.Wrap(err, "generating identity")
+	}
+
+	_, err = fmt.Fprintf(os.Stdout, "# created: %s\n# public key: %s\n%s\n", time.Now().Format(time.RFC3339), id.Recipient(), id)
+	return errors.Wrap(err, "writing identity")
+}
+
+// openBackupReader wraps the backup into the readers required to
+// decrypt and decompress it
+func openBackupReader(backup io.ReaderAt, size int64) (r io.ReaderAt, rSize int64, err error) {
+	r, rSize = backup, size
+
+	if cfg.Passphrase != "" || cfg.IdentityFile != "" {
+		if r, rSize, err = openCryptoReader(r, rSize); err != nil {
+			return nil, 0, errors.Wrap(err, "opening crypto-reader")
+		}
+	}
+
+	if r, rSize, err = openDecompressionReader(r, rSize); err != nil {
+		return nil, 0, errors.Wrap(err, "opening decompression-reader")
+	}
+
+	return r, rSize, nil
+}
+
+func openCryptoReader(backup io.ReaderAt, size int64) (io.ReaderAt, int64, error) {
+	keys, err := loadKeys()
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "loading keys")
+	}
+
+	cryptR, err := cryptostream.NewReaderAtWithKeys(backup, size, keys)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "creating crypto-reader")
+	}
+
+	return cryptR, cryptR.Size(), nil
+}
+
+func openDecompressionReader(backup io.ReaderAt, size int64) (io.ReaderAt, int64, error) {
+	compressed, err := compressstream.IsCompressed(backup, size)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "checking for compression")
+	}
+
+	if !compressed {
+		return backup, size, nil
+	}
+
+	compR, err := compressstream.NewReaderAt(backup, size)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "creating decompression-reader")
+	}
+
+	return compR, compR.Size(), nil
+}
+
+func loadKeys() (keys cryptostream.Keys, err error) {
+	keys.Passphrase = []byte(cfg.Passphrase)
+
+	if cfg.IdentityFile == "" {
+		return keys, nil
+	}
+
+	f, err := os.Open(cfg.IdentityFile)
+	if err != nil {
+		return keys, errors.Wrap(err, "opening identity file")
+	}
+	defer f.Close() //nolint:errcheck // File is only read
+
+	if keys.Identities, err = cryptostream.ParseIdentities(f); err != nil {
+		return keys, errors.Wrap(err, "parsing identity file")
+	}
+
+	return keys, nil
+}
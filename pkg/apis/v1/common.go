@@ -3,6 +3,7 @@ package v1
 import (
 	"context"
 	"reflect"
+	"sort"
 
 	"github.com/pkg/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -66,8 +67,40 @@ func (s *Secret) CopyFromSecret(ctx context.Context, client kubernetes.Interface
 	return nil
 }
 
+func fetchSecretsRecurse(ctx context.Context, in any, client kubernetes.Interface, namespace string) error {
+	return walkSecrets(in, func(s *Secret) error {
+		return s.CopyFromSecret(ctx, client, namespace)
+	})
+}
+
+// secretNamesRecurse collects the names of all secrets referenced
+// (and not overridden by a Value) inside the given struct pointer
+func secretNamesRecurse(in any) (names []string) {
+	seen := map[string]bool{}
+
+	// The callback never fails, so neither does the walk
+	_ = walkSecrets(in, func(s *Secret) error {
+		if s.Value != "" || s.FromSecret.Name == "" || s.FromSecret.Key == "" {
+			// Would not be fetched, so not referenced
+			return nil
+		}
+
+		if !seen[s.FromSecret.Name] {
+			seen[s.FromSecret.Name] = true
+			names = append(names, s.FromSecret.Name)
+		}
+		return nil
+	})
+
+	sort.Strings(names)
+	return names
+}
+
+// walkSecrets calls fn for every Secret found in the given struct
+// pointer, its nested structs and slices of structs
+//
 //nolint:gocognit,gocyclo // Yipp, that's complex. Thats reflect magic. Not gonna split.
-func fetchSecretsRecurse(ctx context.Context, in any, client kubernetes.Interface, namespace string) (err error) {
+func walkSecrets(in any, fn func(*Secret) error) (err error) {
 	var (
 		secretType = reflect.TypeOf(Secret{})
 		vo, to     = reflect.ValueOf(in), reflect.TypeOf(in)
@@ -93,29 +126,29 @@ func fetchSecretsRecurse(ctx context.Context, in any, client kubernetes.Interfac
 
 		switch {
 		case typeField.Type == secretType:
-			if err = valField.Addr().Interface().(*Secret).CopyFromSecret(ctx, client, namespace); err != nil {
-				return errors.Wrapf(err, "fetching secrets for %s", typeField.Name)
+			if err = fn(valField.Addr().Interface().(*Secret)); err != nil {
+				return errors.Wrapf(err, "handling secret %s", typeField.Name)
 			}
 
 		case typeField.Type.Kind() == reflect.Ptr && !valField.IsNil() && valField.Elem().Type() == secretType:
-			if err = valField.Elem().Addr().Interface().(*Secret).CopyFromSecret(ctx, client, namespace); err != nil {
-				return errors.Wrapf(err, "fetching secrets for %s", typeField.Name)
+			if err = fn(valField.Elem().Addr().Interface().(*Secret)); err != nil {
+				return errors.Wrapf(err, "handling secret %s", typeField.Name)
 			}
 
 		case typeField.Type.Kind() == reflect.Struct:
-			if err = fetchSecretsRecurse(ctx, valField.Addr().Interface(), client, namespace); err != nil {
-				return errors.Wrapf(err, "fetching secrets in %s", typeField.Name)
+			if err = walkSecrets(valField.Addr().Interface(), fn); err != nil {
+				return errors.Wrapf(err, "handling secrets in %s", typeField.Name)
 			}
 
 		case typeField.Type.Kind() == reflect.Ptr && valField.Elem().Kind() == reflect.Struct:
-			if err = fetchSecretsRecurse(ctx, valField.Elem().Addr().Interface(), client, namespace); err != nil {
-				return errors.Wrapf(err, "fetching secrets in %s", typeField.Name)
+			if err = walkSecrets(valField.Elem().Addr().Interface(), fn); err != nil {
+				return errors.Wrapf(err, "handling secrets in %s", typeField.Name)
 			}
 
 		case typeField.Type.Kind() == reflect.Slice && typeField.Type.Elem().Kind() == reflect.Struct:
 			for i := 0; i < valField.Len(); i++ {
-				if err = fetchSecretsRecurse(ctx, valField.Index(i).Addr().Interface(), client, namespace); err != nil {
-					return errors.Wrapf(err, "fetching secrets in %s (idx %d)", typeField.Name, i)
+				if err = walkSecrets(valField.Index(i).Addr().Interface(), fn); err != nil {
+					return errors.Wrapf(err, "handling secrets in %s (idx %d)", typeField.Name, i)
 				}
 			}
 
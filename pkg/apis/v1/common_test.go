@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretNames(t *testing.T) {
+	backup := DatabaseBackupSpec{
+		DatabaseType: "mysql",
+		MySQL: &MySQLConfig{
+			User: Secret{FromSecret: SecretKeyRef{Name: "db-creds", Key: "user"}},
+			Pass: Secret{FromSecret: SecretKeyRef{Name: "db-creds", Key: "pass"}},
+		},
+	}
+	assert.Equal(t, []string{"db-creds"}, backup.SecretNames())
+
+	// A set value is used instead of the secret
+	backup.MySQL.User = Secret{Value: "app", FromSecret: SecretKeyRef{Name: "other", Key: "user"}}
+	assert.Equal(t, []string{"db-creds"}, backup.SecretNames())
+
+	backup.MySQL = nil
+	assert.Empty(t, backup.SecretNames())
+
+	storageClass := DatabaseBackupStorageClassSpec{
+		BackupLocations: []DatabaseBackupStorageLocation{
+			{
+				StorageAccessKeyID:     Secret{FromSecret: SecretKeyRef{Name: "s3", Key: "id"}},
+				StorageSecretAccessKey: Secret{FromSecret: SecretKeyRef{Name: "s3", Key: "key"}},
+				EncryptionPass:         Secret{FromSecret: SecretKeyRef{Name: "encryption", Key: "pass"}},
+			},
+			{
+				StorageServiceAccountJSON: Secret{FromSecret: SecretKeyRef{Name: "gcs", Key: "sa.json"}},
+				// Incomplete references are not fetched
+				EncryptionPass: Secret{FromSecret: SecretKeyRef{Name: "unused"}},
+			},
+		},
+	}
+	assert.Equal(t, []string{"encryption", "gcs", "s3"}, storageClass.SecretNames())
+}
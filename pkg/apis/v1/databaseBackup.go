@@ -2,8 +2,12 @@ package v1
 
 import (
 	"context"
+	"fmt"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
 )
 
 // FetchSecrets iterates through all Secret resources inside the
@@ -12,3 +16,86 @@ import (
 func (d *DatabaseBackupSpec) FetchSecrets(ctx context.Context, client kubernetes.Interface, namespace string) error {
 	return fetchSecretsRecurse(ctx, d, client, namespace)
 }
+
+// SecretNames returns the names of all secrets the spec references
+// to fetch values from
+func (d *DatabaseBackupSpec) SecretNames() []string {
+	return secretNamesRecurse(d)
+}
+
+// RetentionPolicy combines the retention settings of the spec into
+// the policy applied by the label manager
+func (d DatabaseBackupSpec) RetentionPolicy() labelmanager.RetentionPolicy {
+	return labelmanager.RetentionPolicy{
+		Durations: d.RetentionConfig,
+		Counts:    d.RetentionCounts,
+		MinKeep:   d.RetentionMinKeep,
+	}
+}
+
+// WithBackupTimeout limits the context to the configured BackupTimeout
+// and returns it unlimited (but cancellable) if no timeout is set
+func (d DatabaseBackupSpec) WithBackupTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withOptionalTimeout(ctx, d.BackupTimeout)
+}
+
+// WithRestoreTimeout limits the context to the configured
+// RestoreTimeout and returns it unlimited (but cancellable) if no
+// timeout is set
+func (d DatabaseBackupSpec) WithRestoreTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withOptionalTimeout(ctx, d.RestoreTimeout)
+}
+
+func withOptionalTimeout(ctx context.Context, timeout *metav1.Duration) (context.Context, context.CancelFunc) {
+	if timeout == nil || timeout.Duration <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout.Duration)
+}
+
+// DatabaseID identifies the database the backups are created from
+// by the engine type and the address of the database
+func (d DatabaseBackupSpec) DatabaseID() string {
+	var (
+		host, database string
+		port           int64
+	)
+
+	switch {
+	case d.Cockroach != nil:
+		host, port, database = d.Cockroach.Host, d.Cockroach.Port, d.Cockroach.Database
+	case d.MySQL != nil:
+		host, port, database = d.MySQL.Host, d.MySQL.Port, d.MySQL.Database
+	case d.Postgres != nil:
+		host, port, database = d.Postgres.Host, d.Postgres.Port, d.Postgres.Database
+	}
+
+	return fmt.Sprintf("%s://%s:%d/%s", d.DatabaseType, host, port, database)
+}
+
+// WithRestoreTarget returns a copy of the spec with the engine config
+// pointing to the non-empty values of the given target
+func (d DatabaseBackupSpec) WithRestoreTarget(t RestoreTarget) DatabaseBackupSpec {
+	spec := *d.DeepCopy()
+
+	override := func(host *string, port *int64) {
+		if t.Host != "" {
+			*host = t.Host
+		}
+		if t.Port != 0 {
+			*port = t.Port
+		}
+	}
+
+	switch {
+	case spec.Cockroach != nil:
+		override(&spec.Cockroach.Host, &spec.Cockroach.Port)
+	case spec.MySQL != nil:
+		override(&spec.MySQL.Host, &spec.MySQL.Port)
+	case spec.Postgres != nil:
+		override(&spec.Postgres.Host, &spec.Postgres.Port)
+	}
+
+	return spec
+}
@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// List of conditions set on a finished DatabaseBackupRun (named like
+// the ones of a batch/v1 Job to be used with `kubectl wait`)
+const (
+	DatabaseBackupRunConditionComplete = "Complete"
+	DatabaseBackupRunConditionFailed   = "Failed"
+)
+
+// IsFinished tells whether the run reached a final phase and will not
+// change anymore
+func (d DatabaseBackupRunStatus) IsFinished() bool {
+	return d.Phase == DatabaseBackupRunPhaseSucceeded || d.Phase == DatabaseBackupRunPhaseFailed
+}
+
+// SetRunning marks the run as started by the given runner job
+func (d *DatabaseBackupRunStatus) SetRunning(jobID string, startTime metav1.Time) {
+	d.Phase = DatabaseBackupRunPhaseRunning
+	d.JobID = jobID
+	d.StartTime = &startTime
+}
+
+// SetFinished marks the run as succeeded (if errMsg is empty) or
+// failed and sets the corresponding condition
+func (d *DatabaseBackupRunStatus) SetFinished(generation int64, completionTime metav1.Time, errMsg string) {
+	d.CompletionTime = &completionTime
+	d.Error = errMsg
+
+	d.Phase = DatabaseBackupRunPhaseSucceeded
+	if errMsg != "" {
+		d.Phase = DatabaseBackupRunPhaseFailed
+	}
+
+	setJobCondition(&d.Conditions, generation, errMsg, "Backup", fmt.Sprintf("Backup %s stored successfully", d.BackupName))
+}
+
+// setJobCondition sets the Complete or Failed condition (depending on
+// whether errMsg is set) with reasons prefixed by the given kind of job
+func setJobCondition(conditions *[]metav1.Condition, generation int64, errMsg, kind, successMessage string) {
+	if errMsg == "" {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               DatabaseBackupRunConditionComplete,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             kind + "Succeeded",
+			Message:            successMessage,
+		})
+		return
+	}
+
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               DatabaseBackupRunConditionFailed,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             kind + "Failed",
+		Message:            errMsg,
+	})
+}
@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDatabaseBackupRunStatus(t *testing.T) {
+	t0 := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var s DatabaseBackupRunStatus
+	assert.False(t, s.IsFinished())
+
+	s.SetRunning("abc", t0)
+	assert.Equal(t, DatabaseBackupRunPhaseRunning, s.Phase)
+	assert.Equal(t, "abc", s.JobID)
+	assert.False(t, s.IsFinished())
+
+	ok := s.DeepCopy()
+	ok.BackupName = "2024-01-01T00-00-00"
+	ok.SetFinished(1, metav1.NewTime(t0.Add(time.Minute)), "")
+	assert.Equal(t, DatabaseBackupRunPhaseSucceeded, ok.Phase)
+	assert.True(t, ok.IsFinished())
+	assert.True(t, meta.IsStatusConditionTrue(ok.Conditions, DatabaseBackupRunConditionComplete))
+	assert.Nil(t, meta.FindStatusCondition(ok.Conditions, DatabaseBackupRunConditionFailed))
+
+	failed := s.DeepCopy()
+	failed.SetFinished(1, metav1.NewTime(t0.Add(time.Minute)), "pg_dump failed")
+	assert.Equal(t, DatabaseBackupRunPhaseFailed, failed.Phase)
+	assert.Equal(t, "pg_dump failed", failed.Error)
+	assert.True(t, meta.IsStatusConditionTrue(failed.Conditions, DatabaseBackupRunConditionFailed))
+	assert.Nil(t, meta.FindStatusCondition(failed.Conditions, DatabaseBackupRunConditionComplete))
+}
@@ -12,3 +12,9 @@ import (
 func (d *DatabaseBackupStorageClassSpec) FetchSecrets(ctx context.Context, client kubernetes.Interface, namespace string) error {
 	return fetchSecretsRecurse(ctx, d, client, namespace)
 }
+
+// SecretNames returns the names of all secrets the spec references
+// to fetch values from
+func (d *DatabaseBackupStorageClassSpec) SecretNames() []string {
+	return secretNamesRecurse(d)
+}
@@ -0,0 +1,90 @@
+package v1
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// List of conditions set on a finished DatabaseRestore (named like
+// the ones of a batch/v1 Job to be used with `kubectl wait`)
+const (
+	DatabaseRestoreConditionComplete = "Complete"
+	DatabaseRestoreConditionFailed   = "Failed"
+)
+
+// TargetDatabaseID identifies the database the restore writes into:
+// the database of the DatabaseBackup with the target override applied
+// (see DatabaseBackupSpec.DatabaseID)
+func (d DatabaseRestoreSpec) TargetDatabaseID(backup DatabaseBackupSpec) string {
+	if d.Target == nil {
+		return backup.DatabaseID()
+	}
+
+	return backup.WithRestoreTarget(*d.Target).DatabaseID()
+}
+
+// RequiresIdentities tells whether the backups to restore can only be
+// decrypted using identities: All locations to restore from encrypt
+// the backups to recipients and have no passphrase for older backups.
+func (d DatabaseRestoreSpec) RequiresIdentities(storage DatabaseBackupStorageClassSpec) bool {
+	var candidates int
+	for i, loc := range storage.BackupLocations {
+		if d.SourceLocation != nil && i != *d.SourceLocation {
+			continue
+		}
+
+		candidates++
+		if len(loc.EncryptionRecipients) == 0 || loc.EncryptionPass.Value != "" || loc.EncryptionPass.FromSecret.Name != "" {
+			return false
+		}
+	}
+
+	return candidates > 0
+}
+
+// IsFinished tells whether the restore reached a final phase and will
+// not change anymore
+func (d DatabaseRestoreStatus) IsFinished() bool {
+	return d.Phase == DatabaseRestorePhaseSucceeded || d.Phase == DatabaseRestorePhaseFailed
+}
+
+// IsActive tells whether the restore was started in the runner and
+// did not finish yet
+func (d DatabaseRestoreStatus) IsActive() bool {
+	return d.Phase == DatabaseRestorePhaseRunning
+}
+
+// HoldsClaim tells whether the restore claimed its target database:
+// It is running or about to be started with the job ID set.
+func (d DatabaseRestoreStatus) HoldsClaim() bool {
+	return d.IsActive() || (d.Phase == DatabaseRestorePhasePending && d.JobID != "")
+}
+
+// SetClaimed marks the restore as pending to be started by the runner
+// job with the given ID, claiming the target database until finished
+func (d *DatabaseRestoreStatus) SetClaimed(jobID string) {
+	d.Phase = DatabaseRestorePhasePending
+	d.JobID = jobID
+}
+
+// SetRunning marks the restore as started by the given runner job
+func (d *DatabaseRestoreStatus) SetRunning(jobID string, startTime metav1.Time) {
+	d.Phase = DatabaseRestorePhaseRunning
+	d.JobID = jobID
+	d.StartTime = &startTime
+}
+
+// SetFinished marks the restore as succeeded (if errMsg is empty) or
+// failed and sets the corresponding condition
+func (d *DatabaseRestoreStatus) SetFinished(generation int64, completionTime metav1.Time, errMsg string) {
+	d.CompletionTime = &completionTime
+	d.Error = errMsg
+
+	d.Phase = DatabaseRestorePhaseSucceeded
+	if errMsg != "" {
+		d.Phase = DatabaseRestorePhaseFailed
+	}
+
+	setJobCondition(&d.Conditions, generation, errMsg, "Restore", fmt.Sprintf("Backup %s restored successfully", d.BackupName))
+}
@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDatabaseRestoreTarget(t *testing.T) {
+	backup := DatabaseBackupSpec{
+		DatabaseType: "postgres",
+		Postgres:     &PostgresConfig{Host: "db", Port: 5432, Database: "app", User: "app"},
+	}
+
+	assert.Equal(t, "postgres://db:5432/app", DatabaseRestoreSpec{}.TargetDatabaseID(backup))
+	assert.Equal(t, "postgres://staging:5432/app", DatabaseRestoreSpec{Target: &RestoreTarget{Host: "staging"}}.TargetDatabaseID(backup))
+
+	spec := backup.WithRestoreTarget(RestoreTarget{Host: "other", Port: 5433})
+	assert.Equal(t, "other", spec.Postgres.Host)
+	assert.Equal(t, int64(5433), spec.Postgres.Port)
+	assert.Equal(t, "app", spec.Postgres.Database)
+	assert.Equal(t, "app", spec.Postgres.User)
+
+	// The original spec must not be modified
+	assert.Equal(t, "db", backup.Postgres.Host)
+}
+
+func TestDatabaseRestoreStatus(t *testing.T) {
+	t0 := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var s DatabaseRestoreStatus
+	assert.False(t, s.IsActive())
+	assert.False(t, s.HoldsClaim())
+
+	s.SetClaimed("abc")
+	assert.False(t, s.IsActive())
+	assert.True(t, s.HoldsClaim())
+
+	s.SetRunning("abc", t0)
+	assert.True(t, s.IsActive())
+	assert.True(t, s.HoldsClaim())
+	assert.False(t, s.IsFinished())
+
+	s.BackupName = "2024-01-01T00-00-00"
+	s.SetFinished(1, metav1.NewTime(t0.Add(time.Minute)), "")
+	assert.Equal(t, DatabaseRestorePhaseSucceeded, s.Phase)
+	assert.False(t, s.IsActive())
+	assert.False(t, s.HoldsClaim())
+	assert.True(t, s.IsFinished())
+	assert.True(t, meta.IsStatusConditionTrue(s.Conditions, DatabaseRestoreConditionComplete))
+}
+
+func TestDatabaseRestoreRequiresIdentities(t *testing.T) {
+	storage := DatabaseBackupStorageClassSpec{
+		BackupLocations: []DatabaseBackupStorageLocation{
+			{EncryptionRecipients: []string{"dbc-x25519:key"}},
+			{EncryptionPass: Secret{Value: "pass"}, EncryptionRecipients: []string{"dbc-x25519:key"}},
+		},
+	}
+
+	// Second location has a passphrase for older backups
+	assert.False(t, DatabaseRestoreSpec{}.RequiresIdentities(storage))
+
+	first := 0
+	assert.True(t, DatabaseRestoreSpec{SourceLocation: &first}.RequiresIdentities(storage))
+
+	storage.BackupLocations[1].EncryptionPass = Secret{FromSecret: SecretKeyRef{Name: "encryption", Key: "pass"}}
+	second := 1
+	assert.False(t, DatabaseRestoreSpec{SourceLocation: &second}.RequiresIdentities(storage))
+
+	// Unencrypted locations can always be restored
+	assert.False(t, DatabaseRestoreSpec{}.RequiresIdentities(DatabaseBackupStorageClassSpec{
+		BackupLocations: []DatabaseBackupStorageLocation{{}},
+	}))
+}
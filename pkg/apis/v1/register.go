@@ -29,8 +29,12 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&DatabaseBackup{},
 		&DatabaseBackupList{},
+		&DatabaseBackupRun{},
+		&DatabaseBackupRunList{},
 		&DatabaseBackupStorageClass{},
 		&DatabaseBackupStorageClassList{},
+		&DatabaseRestore{},
+		&DatabaseRestoreList{},
 	)
 
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
@@ -1,6 +1,8 @@
 package v1
 
 import (
+	"fmt"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -9,6 +11,8 @@ import (
 type DatabaseBackupStatusCondition string
 
 const (
+	// ConditionLastBackupSucceeded represents the status condition whether the last backup executed by the runner succeeded
+	ConditionLastBackupSucceeded DatabaseBackupStatusCondition = "LastBackupSucceeded"
 	// ConditionSecretExists represents the status condition whether the secret was created successfully
 	ConditionSecretExists DatabaseBackupStatusCondition = "db-backup.nect.com/secretExists" //#nosec:G101 -- That's not a credential
 	// ConditionServiceExists represents the status condition whether the service was created successfully
@@ -20,9 +24,10 @@ const (
 )
 
 var conditionSuccessStates = map[DatabaseBackupStatusCondition]metav1.ConditionStatus{
-	ConditionSecretExists:  metav1.ConditionTrue,
-	ConditionServiceExists: metav1.ConditionTrue,
-	ConditionSTSExists:     metav1.ConditionTrue,
+	ConditionLastBackupSucceeded: metav1.ConditionTrue,
+	ConditionSecretExists:        metav1.ConditionTrue,
+	ConditionServiceExists:       metav1.ConditionTrue,
+	ConditionSTSExists:           metav1.ConditionTrue,
 }
 
 // Init initializes a new "not ready" status object
@@ -45,6 +50,41 @@ func (d *DatabaseBackupStatus) Set(condType DatabaseBackupStatusCondition, gener
 	d.CalculateReady(generation)
 }
 
+// SetBackupResults merges the results reported by the runner into
+// the status (keeping newer results already known as the runner does
+// not remember them across restarts) and sets the LastBackupSucceeded
+// condition from them. As long as no backup was executed the
+// condition stays absent and therefore does not affect the Ready
+// condition.
+func (d *DatabaseBackupStatus) SetBackupResults(generation int64, r BackupResultStatus) {
+	if isNewer(r.LastSuccessTime, d.Backups.LastSuccessTime) {
+		d.Backups.LastSuccessTime = r.LastSuccessTime
+		d.Backups.LastBackupName = r.LastBackupName
+		d.Backups.LastBackupSize = r.LastBackupSize
+	}
+
+	if isNewer(r.LastFailureTime, d.Backups.LastFailureTime) {
+		d.Backups.LastFailureTime = r.LastFailureTime
+		d.Backups.LastFailureMessage = r.LastFailureMessage
+	}
+
+	if r.NextScheduledTime != nil {
+		d.Backups.NextScheduledTime = r.NextScheduledTime
+	}
+
+	switch {
+	case isNewer(d.Backups.LastFailureTime, d.Backups.LastSuccessTime):
+		d.Set(ConditionLastBackupSucceeded, generation, metav1.ConditionFalse, "BackupFailed", d.Backups.LastFailureMessage)
+
+	case d.Backups.LastSuccessTime != nil:
+		d.Set(ConditionLastBackupSucceeded, generation, metav1.ConditionTrue, "BackupSucceeded",
+			fmt.Sprintf("Backup %s stored successfully", d.Backups.LastBackupName))
+
+	default:
+		// No backup was executed yet, nothing to tell
+	}
+}
+
 // CalculateReady takes all known conditions into account and checks
 // whether they are fine. From all those conditions the overall
 // "Ready" condition is set
@@ -116,3 +156,8 @@ func (d *DatabaseBackupStatus) set(condType DatabaseBackupStatusCondition, gener
 
 	d.Conditions = tmp
 }
+
+// isNewer tells whether t is set and after ref (or ref is not set)
+func isNewer(t, ref *metav1.Time) bool {
+	return t != nil && (ref == nil || t.After(ref.Time))
+}
@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetBackupResults(t *testing.T) {
+	var (
+		s      DatabaseBackupStatus
+		t0     = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		timeAt = func(d time.Duration) *metav1.Time { v := metav1.NewTime(t0.Add(d)); return &v }
+	)
+
+	s.Init(1)
+	for _, c := range []DatabaseBackupStatusCondition{ConditionSecretExists, ConditionServiceExists, ConditionSTSExists} {
+		s.Set(c, 1, metav1.ConditionTrue, "test", "Created")
+	}
+
+	// No backup executed yet: Condition stays absent
+	s.SetBackupResults(1, BackupResultStatus{NextScheduledTime: timeAt(time.Hour)})
+	assert.Nil(t, s.condition(ConditionLastBackupSucceeded))
+	assert.Equal(t, metav1.ConditionTrue, s.condition(conditionReady).Status)
+	assert.Equal(t, timeAt(time.Hour), s.Backups.NextScheduledTime)
+
+	s.SetBackupResults(1, BackupResultStatus{
+		LastSuccessTime: timeAt(time.Hour),
+		LastBackupName:  "2024-01-01T01-00-00",
+		LastBackupSize:  42,
+	})
+	require.NotNil(t, s.condition(ConditionLastBackupSucceeded))
+	assert.Equal(t, metav1.ConditionTrue, s.condition(ConditionLastBackupSucceeded).Status)
+	assert.Equal(t, int64(42), s.Backups.LastBackupSize)
+
+	s.SetBackupResults(1, BackupResultStatus{
+		LastSuccessTime:    timeAt(time.Hour),
+		LastBackupName:     "2024-01-01T01-00-00",
+		LastBackupSize:     42,
+		LastFailureTime:    timeAt(2 * time.Hour),
+		LastFailureMessage: "pg_dump failed",
+	})
+	assert.Equal(t, metav1.ConditionFalse, s.condition(ConditionLastBackupSucceeded).Status)
+	assert.Equal(t, "pg_dump failed", s.condition(ConditionLastBackupSucceeded).Message)
+	assert.Equal(t, metav1.ConditionFalse, s.condition(conditionReady).Status)
+
+	// Restarted runner without results must not reset the known ones
+	s.SetBackupResults(1, BackupResultStatus{})
+	assert.Equal(t, "2024-01-01T01-00-00", s.Backups.LastBackupName)
+	assert.Equal(t, metav1.ConditionFalse, s.condition(ConditionLastBackupSucceeded).Status)
+
+	s.SetBackupResults(1, BackupResultStatus{
+		LastSuccessTime: timeAt(3 * time.Hour),
+		LastBackupName:  "2024-01-01T03-00-00",
+		LastBackupSize:  23,
+	})
+	assert.Equal(t, metav1.ConditionTrue, s.condition(ConditionLastBackupSucceeded).Status)
+	assert.Equal(t, "2024-01-01T03-00-00", s.Backups.LastBackupName)
+	assert.Equal(t, "pg_dump failed", s.Backups.LastFailureMessage)
+}
+
+func (d DatabaseBackupStatus) condition(condType DatabaseBackupStatusCondition) *metav1.Condition {
+	for i := range d.Conditions {
+		if DatabaseBackupStatusCondition(d.Conditions[i].Type) == condType {
+			return &d.Conditions[i]
+		}
+	}
+
+	return nil
+}
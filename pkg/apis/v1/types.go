@@ -12,6 +12,12 @@ import (
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Last Backup",type=string,JSONPath=`.status.conditions[?(@.type=="LastBackupSucceeded")].status`
+// +kubebuilder:printcolumn:name="Last Success",type=date,JSONPath=`.status.backups.lastSuccessTime`
+// +kubebuilder:printcolumn:name="Size",type=integer,JSONPath=`.status.backups.lastBackupSize`,priority=1
+// +kubebuilder:printcolumn:name="Next Backup",type=string,format=date-time,JSONPath=`.status.backups.nextScheduledTime`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type DatabaseBackup struct {
 	metav1.TypeMeta   `json:",inline"` //revive:disable-line:struct-tag // "inline" is valid
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -69,6 +75,18 @@ type DatabaseBackupSpec struct {
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:validation:Optional
 	BackupIntervalHours int64 `json:"backupInterval"`
+	// BackupTimeout limits how long a single backup may take before
+	// it is cancelled (i.e. "2h"). If left empty the backup is not
+	// limited in time.
+	//
+	// +kubebuilder:validation:Optional
+	BackupTimeout *metav1.Duration `json:"backupTimeout,omitempty"`
+	// RestoreTimeout limits how long a single restore may take before
+	// it is cancelled (i.e. "6h"). If left empty the restore is not
+	// limited in time.
+	//
+	// +kubebuilder:validation:Optional
+	RestoreTimeout *metav1.Duration `json:"restoreTimeout,omitempty"`
 	// RetentionConfig defines the retention rules applied to store
 	// old copies of the backup in case generation-principle backups
 	// are enabled. If left to nil the default retention config is
@@ -76,6 +94,19 @@ type DatabaseBackupSpec struct {
 	//
 	// +kubebuilder:validation:Optional
 	RetentionConfig labelmanager.RetentionConfig `json:"retentionConfig"`
+	// RetentionCounts defines strftime formats and the number of the
+	// most recent labels of that format to keep regardless of their
+	// age (i.e. "%Y-%m-%dT%H": 48 keeps the last 48 hourly backups).
+	// Formats not present in the RetentionConfig are assigned as well.
+	//
+	// +kubebuilder:validation:Optional
+	RetentionCounts labelmanager.RetentionCounts `json:"retentionCounts,omitempty"`
+	// RetentionMinKeep is the number of most recent backups never to
+	// be removed, regardless of their labels being expired
+	//
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	RetentionMinKeep int `json:"retentionMinKeep,omitempty"`
 	// UseSingleBackupTarget defines whether to upload in the same
 	// place all the time (S3 server then needs to take care of
 	// rotating / revisioning backup file and backup can only restore
@@ -86,6 +117,12 @@ type DatabaseBackupSpec struct {
 	// +kubebuilder:default=false
 	// +kubebuilder:validation:Optional
 	UseSingleBackupTarget bool `json:"useSingleBackupTarget"`
+	// Compression defines how to compress the backup before it gets
+	// encrypted and uploaded. If left to nil the backup is stored
+	// uncompressed.
+	//
+	// +kubebuilder:validation:Optional
+	Compression *CompressionConfig `json:"compression,omitempty"`
 
 	// Engine config
 
@@ -112,6 +149,277 @@ type DatabaseBackupStatus struct {
 	//
 	// +kubebuilder:validation:Optional
 	Hash string `json:"hash,omitempty"`
+	// Backups contains the results of the backups as reported by the
+	// runner
+	//
+	// +kubebuilder:validation:Optional
+	Backups BackupResultStatus `json:"backups,omitempty"`
+}
+
+// BackupResultStatus contains the results of the backups executed by
+// the runner
+//
+// +kubebuilder:object:generate=true
+type BackupResultStatus struct {
+	// LastSuccessTime is the time the last successful backup finished
+	//
+	// +kubebuilder:validation:Optional
+	LastSuccessTime *metav1.Time `json:"lastSuccessTime,omitempty"`
+	// LastBackupName is the name of the last successful backup
+	//
+	// +kubebuilder:validation:Optional
+	LastBackupName string `json:"lastBackupName,omitempty"`
+	// LastBackupSize is the size of the last successful backup as
+	// stored in the backup location (in bytes)
+	//
+	// +kubebuilder:validation:Optional
+	LastBackupSize int64 `json:"lastBackupSize,omitempty"`
+	// LastFailureTime is the time the last failed backup stopped
+	//
+	// +kubebuilder:validation:Optional
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+	// LastFailureMessage describes why the last failed backup failed
+	//
+	// +kubebuilder:validation:Optional
+	LastFailureMessage string `json:"lastFailureMessage,omitempty"`
+	// NextScheduledTime is the time the next automatic backup is
+	// scheduled for
+	//
+	// +kubebuilder:validation:Optional
+	NextScheduledTime *metav1.Time `json:"nextScheduledTime,omitempty"`
+}
+
+// DatabaseBackupRun requests an on-demand backup for the
+// DatabaseBackup referenced in its spec
+//
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Database Backup",type=string,JSONPath=`.spec.databaseBackup`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Backup",type=string,JSONPath=`.status.backupName`
+// +kubebuilder:printcolumn:name="Size",type=integer,JSONPath=`.status.size`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type DatabaseBackupRun struct {
+	metav1.TypeMeta   `json:",inline"` //revive:disable-line:struct-tag // "inline" is valid
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseBackupRunSpec   `json:"spec"`
+	Status DatabaseBackupRunStatus `json:"status,omitempty"`
+}
+
+// DatabaseBackupRunList contains a list of DatabaseBackupRun
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type DatabaseBackupRunList struct {
+	metav1.TypeMeta `json:",inline"` //revive:disable-line:struct-tag // "inline" is valid
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []DatabaseBackupRun `json:"items"`
+}
+
+// DatabaseBackupRunSpec describes which DatabaseBackup to execute an
+// on-demand backup for
+//
+// +kubebuilder:object:generate=true
+type DatabaseBackupRunSpec struct {
+	// DatabaseBackup is the name of the DatabaseBackup in the same
+	// namespace to execute the backup for
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="databaseBackup is immutable"
+	DatabaseBackup string `json:"databaseBackup"`
+}
+
+// DatabaseBackupRunPhase describes the lifecycle state of a
+// DatabaseBackupRun
+type DatabaseBackupRunPhase string
+
+// List of phases a DatabaseBackupRun passes through
+const (
+	DatabaseBackupRunPhasePending   DatabaseBackupRunPhase = "Pending"
+	DatabaseBackupRunPhaseRunning   DatabaseBackupRunPhase = "Running"
+	DatabaseBackupRunPhaseSucceeded DatabaseBackupRunPhase = "Succeeded"
+	DatabaseBackupRunPhaseFailed    DatabaseBackupRunPhase = "Failed"
+)
+
+// DatabaseBackupRunStatus represents the status of a
+// DatabaseBackupRun resource
+type DatabaseBackupRunStatus struct {
+	// Phase is the current state of the backup run
+	//
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum={Pending, Running, Succeeded, Failed}
+	Phase DatabaseBackupRunPhase `json:"phase,omitempty"`
+	// JobID is the ID of the job executing the backup inside the
+	// runner
+	//
+	// +kubebuilder:validation:Optional
+	JobID string `json:"jobID,omitempty"`
+	// BackupName is the name of the backup created by this run
+	//
+	// +kubebuilder:validation:Optional
+	BackupName string `json:"backupName,omitempty"`
+	// Size is the size of the created backup as stored in the backup
+	// location (in bytes)
+	//
+	// +kubebuilder:validation:Optional
+	Size int64 `json:"size,omitempty"`
+	// StartTime is the time the runner started the backup
+	//
+	// +kubebuilder:validation:Optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the backup finished or failed
+	//
+	// +kubebuilder:validation:Optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Error describes why the backup run failed
+	//
+	// +kubebuilder:validation:Optional
+	Error string `json:"error,omitempty"`
+	// Collection of conditions
+	//
+	// +kubebuilder:validation:Optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// DatabaseRestore requests the restore of a backup of the database
+// described by the DatabaseBackup referenced in its spec
+//
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Database Backup",type=string,JSONPath=`.spec.databaseBackup`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Backup",type=string,JSONPath=`.status.backupName`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type DatabaseRestore struct {
+	metav1.TypeMeta   `json:",inline"` //revive:disable-line:struct-tag // "inline" is valid
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseRestoreSpec   `json:"spec"`
+	Status DatabaseRestoreStatus `json:"status,omitempty"`
+}
+
+// DatabaseRestoreList contains a list of DatabaseRestore
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type DatabaseRestoreList struct {
+	metav1.TypeMeta `json:",inline"` //revive:disable-line:struct-tag // "inline" is valid
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []DatabaseRestore `json:"items"`
+}
+
+// DatabaseRestoreSpec describes which backup to restore and where to
+// restore it to
+//
+// +kubebuilder:object:generate=true
+// +kubebuilder:validation:XValidation:rule="has(self.backupName) != has(self.pointInTime)",message="set exactly one of backupName and pointInTime"
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+type DatabaseRestoreSpec struct {
+	// DatabaseBackup is the name of the DatabaseBackup in the same
+	// namespace whose backups to restore
+	//
+	// +kubebuilder:validation:MinLength=1
+	DatabaseBackup string `json:"databaseBackup"`
+	// BackupName is the name of the backup to restore
+	//
+	// +kubebuilder:validation:Optional
+	BackupName string `json:"backupName,omitempty"`
+	// PointInTime selects the closest backup created before the given
+	// time to be restored
+	//
+	// +kubebuilder:validation:Optional
+	PointInTime *metav1.Time `json:"pointInTime,omitempty"`
+	// SourceLocation is the index of the location inside the
+	// backupLocations of the DatabaseBackupStorageClass to restore
+	// from. By default the locations are tried in order until the
+	// backup is found in one of them.
+	//
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	SourceLocation *int `json:"sourceLocation,omitempty"`
+	// Target overrides where to restore the backup to instead of the
+	// database it was created from
+	//
+	// +kubebuilder:validation:Optional
+	Target *RestoreTarget `json:"target,omitempty"`
+	// IdentitiesFrom references a secret in the same namespace
+	// containing the X25519 identities (one per line) to decrypt
+	// backups encrypted to the encryptionRecipients of the storage
+	// location
+	//
+	// +kubebuilder:validation:Optional
+	IdentitiesFrom *SecretKeyRef `json:"identitiesFrom,omitempty"`
+}
+
+// RestoreTarget describes the database server to restore a backup
+// into. The credentials of the DatabaseBackup are used to connect to
+// it and the database keeps the name it was backed up with as the
+// backups contain the statements to create it.
+//
+// +kubebuilder:object:generate=true
+type RestoreTarget struct {
+	// Host specifies the IP or DNS name to connect to
+	//
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Optional
+	Host string `json:"host,omitempty"`
+	// Port specifies the port to connect to
+	//
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Optional
+	Port int64 `json:"port,omitempty"`
+}
+
+// DatabaseRestorePhase describes the lifecycle state of a
+// DatabaseRestore
+type DatabaseRestorePhase string
+
+// List of phases a DatabaseRestore passes through
+const (
+	DatabaseRestorePhasePending   DatabaseRestorePhase = "Pending"
+	DatabaseRestorePhaseRunning   DatabaseRestorePhase = "Running"
+	DatabaseRestorePhaseSucceeded DatabaseRestorePhase = "Succeeded"
+	DatabaseRestorePhaseFailed    DatabaseRestorePhase = "Failed"
+)
+
+// DatabaseRestoreStatus represents the status of a DatabaseRestore
+// resource
+type DatabaseRestoreStatus struct {
+	// Phase is the current state of the restore
+	//
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum={Pending, Running, Succeeded, Failed}
+	Phase DatabaseRestorePhase `json:"phase,omitempty"`
+	// JobID is the ID of the job executing the restore inside the
+	// runner. It is set before the job is started to claim the
+	// target database.
+	//
+	// +kubebuilder:validation:Optional
+	JobID string `json:"jobID,omitempty"`
+	// BackupName is the name of the backup chosen to be restored
+	//
+	// +kubebuilder:validation:Optional
+	BackupName string `json:"backupName,omitempty"`
+	// StartTime is the time the runner started the restore
+	//
+	// +kubebuilder:validation:Optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the restore finished or failed
+	//
+	// +kubebuilder:validation:Optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Error describes why the restore failed
+	//
+	// +kubebuilder:validation:Optional
+	Error string `json:"error,omitempty"`
+	// Collection of conditions
+	//
+	// +kubebuilder:validation:Optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // CockroachConfig contains the values required for the
@@ -147,6 +455,23 @@ type CockroachConfig struct {
 	CertKey Secret `json:"certKey"`
 }
 
+// CompressionConfig contains the settings for compressing backups
+//
+// +kubebuilder:object:generate=true
+type CompressionConfig struct {
+	// Codec specifies the compression algorithm to use
+	//
+	// +kubebuilder:validation:Enum={gzip, zstd}
+	Codec string `json:"codec"`
+	// Level specifies the compression level to use (gzip: 1-9,
+	// zstd: 1-22). If left to 0 the default of the codec is used.
+	//
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=22
+	Level int `json:"level"`
+}
+
 // MySQLConfig contains the values required for the backup-engine
 // to backup a single database on a MySQL server
 //
@@ -229,20 +554,99 @@ type DatabaseBackupStorageLocation struct {
 	// StorageType defines which storage engine to load for this
 	// storage location
 	//
-	// +kubebuilder:validation:Enum={s3}
+	// +kubebuilder:validation:Enum={azureblob, filesystem, gcs, s3}
 	StorageType string `json:"storageType"`
 
-	// StorageEndpoint defines the MinIO / S3 endpoint to connect to
+	// StorageEndpoint defines the MinIO / S3 endpoint to connect to.
+	// For GCS this optionally overrides the API endpoint, for Azure
+	// it optionally overrides the blob service URL (i.e. for Azurite
+	// "http://127.0.0.1:10000/devstoreaccount1").
+	//
+	// +kubebuilder:validation:Optional
 	StorageEndpoint string `json:"storageEndpoint"`
 	// StorageAccessKeyID and StorageSecretAccessKey define the
-	// credentials to access the StorageBucket
-	StorageAccessKeyID     Secret `json:"storageAccessKeyID"`
+	// credentials to access the StorageBucket (S3 only). For Azure
+	// they define the storage account name and account key.
+	//
+	// +kubebuilder:validation:Optional
+	StorageAccessKeyID Secret `json:"storageAccessKeyID"`
+	// +kubebuilder:validation:Optional
 	StorageSecretAccessKey Secret `json:"storageSecretAccessKey"`
-	// StorageBucket defines to which bucket to upload the files
+	// StorageCredentialsSource defines where to take the credentials
+	// to access the StorageBucket from (S3 only):
+	//
+	// - static: StorageAccessKeyID / StorageSecretAccessKey
+	// - env: AWS_* / MINIO_* environment variables of the runner
+	// - iam: Web identity token (IRSA), EKS pod identity, ECS or EC2
+	//   instance metadata
+	// - chain: static (if set), env, iam in that order
+	//
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum={chain, env, iam, static}
+	// +kubebuilder:default=static
+	StorageCredentialsSource string `json:"storageCredentialsSource"`
+	// StorageAssumeRoleARN defines a role to assume using the
+	// credentials from the StorageCredentialsSource (S3 only)
+	//
+	// +kubebuilder:validation:Optional
+	StorageAssumeRoleARN string `json:"storageAssumeRoleARN"`
+	// StorageSTSEndpoint defines the STS endpoint to use for assuming
+	// the StorageAssumeRoleARN. If left empty the AWS STS endpoint for
+	// the StorageLocation is used.
+	//
+	// +kubebuilder:validation:Optional
+	StorageSTSEndpoint string `json:"storageSTSEndpoint"`
+	// StorageServiceAccountName defines a ServiceAccount in the
+	// controller namespace to run the runner pod with (i.e. one
+	// annotated for IRSA). It needs the same permissions as the
+	// default "db-backup-runner" ServiceAccount. All locations of a
+	// storage class must use the same ServiceAccount.
+	//
+	// +kubebuilder:validation:Optional
+	StorageServiceAccountName string `json:"storageServiceAccountName"`
+	// StorageServiceAccountJSON defines the service-account key (in
+	// JSON format) to access the StorageBucket (GCS only). If left
+	// empty the application default credentials are used.
+	//
+	// +kubebuilder:validation:Optional
+	StorageServiceAccountJSON Secret `json:"storageServiceAccountJSON"`
+	// StorageSASToken defines a shared access signature to access the
+	// StorageBucket (Azure only). If set it is used instead of the
+	// account key.
+	//
+	// +kubebuilder:validation:Optional
+	StorageSASToken Secret `json:"storageSASToken"`
+	// StorageBucket defines to which bucket (container for Azure) to
+	// upload the files (not used for filesystem)
+	//
+	// +kubebuilder:validation:Optional
 	StorageBucket string `json:"storageBucket"`
 	// StorageLocation defines the location the bucket exists in
 	// (i.e. "minio", "eu-west-1", ...)
+	//
+	// +kubebuilder:validation:Optional
 	StorageLocation string `json:"storageLocation"`
+	// StorageObjectLockMode defines the S3 Object Lock retention mode
+	// to protect backups with until their longest retention label
+	// expires (S3 only, not for single backup targets). The bucket
+	// must have Object Lock enabled.
+	//
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum={compliance, governance}
+	StorageObjectLockMode string `json:"storageObjectLockMode,omitempty"`
+	// StoragePath defines the directory inside the runner to write
+	// the backups to (filesystem only). If StorageVolumeClaimName is
+	// set the claim is mounted at this path.
+	//
+	// +kubebuilder:validation:Optional
+	StoragePath string `json:"storagePath"`
+	// StorageVolumeClaimName defines a PersistentVolumeClaim in the
+	// controller namespace to mount into the runner at StoragePath
+	// (filesystem only). If left empty the StoragePath must be
+	// available in the runner by other means.
+	//
+	// +kubebuilder:validation:Optional
+	StorageVolumeClaimName string `json:"storageVolumeClaimName"`
 	// StorageUseSSL defines whether to use TLS encrypted connection
 	// to storage
 	//
@@ -262,4 +666,12 @@ type DatabaseBackupStorageLocation struct {
 	//
 	// +kubebuilder:validation:Optional
 	EncryptionPass Secret `json:"encryptionPass"`
+	// EncryptionRecipients defines X25519 public keys (in the format
+	// "dbc-x25519:<base64>") the backup is encrypted to. Only the
+	// holders of the corresponding private keys are able to decrypt
+	// the backups, so the keys can be kept away from the cluster. When
+	// set the EncryptionPass is not used to encrypt new backups.
+	//
+	// +kubebuilder:validation:Optional
+	EncryptionRecipients []string `json:"encryptionRecipients,omitempty"`
 }
@@ -0,0 +1,221 @@
+package v1
+
+import (
+	"net"
+	"path"
+	"sort"
+
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/NectGmbH/db-backup-controller/pkg/cryptostream"
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+)
+
+// Validate checks the spec for misconfigurations the CRD schema
+// cannot express and which would otherwise only surface when the
+// runner is started: The engine config, schedule and retention
+// formats need to be usable.
+func (d DatabaseBackupSpec) Validate(path *field.Path) (errs field.ErrorList) {
+	errs = append(errs, d.validateEngineConfig(path)...)
+	errs = append(errs, d.validateSchedule(path)...)
+	errs = append(errs, d.validateRetention(path)...)
+	return errs
+}
+
+// Validate checks the locations to contain the settings required by
+// their storage type and the encryption recipients to be parseable as
+// the runner would otherwise fail to start or to upload the backups
+func (d DatabaseBackupStorageClassSpec) Validate(fldPath *field.Path) (errs field.ErrorList) {
+	var (
+		claimPaths     = map[string]string{}
+		serviceAccount string
+	)
+
+	for i, loc := range d.BackupLocations {
+		p := fldPath.Child("backupLocations").Index(i)
+		errs = append(errs, loc.validate(p)...)
+
+		if loc.StorageServiceAccountName != "" {
+			if serviceAccount != "" && serviceAccount != loc.StorageServiceAccountName {
+				errs = append(errs, field.Invalid(p.Child("storageServiceAccountName"), loc.StorageServiceAccountName,
+					"all locations must use the same service account"))
+			}
+			serviceAccount = loc.StorageServiceAccountName
+		}
+
+		if loc.StorageType == "filesystem" && loc.StorageVolumeClaimName != "" && path.IsAbs(loc.StoragePath) {
+			mountPath := path.Clean(loc.StoragePath)
+			if claim, ok := claimPaths[mountPath]; ok && claim != loc.StorageVolumeClaimName {
+				errs = append(errs, field.Invalid(p.Child("storagePath"), loc.StoragePath, "already used for claim "+claim))
+			}
+			claimPaths[mountPath] = loc.StorageVolumeClaimName
+		}
+	}
+
+	return errs
+}
+
+// Validate checks the target to point to a database server by IP or
+// DNS name as the credentials of the DatabaseBackup are sent to it
+func (r RestoreTarget) Validate(path *field.Path) (errs field.ErrorList) {
+	if r.Host != "" && net.ParseIP(r.Host) == nil {
+		for _, msg := range validation.IsDNS1123Subdomain(r.Host) {
+			errs = append(errs, field.Invalid(path.Child("host"), r.Host, msg))
+		}
+	}
+
+	if r.Port < 0 || r.Port > 65535 {
+		errs = append(errs, field.Invalid(path.Child("port"), r.Port, "must be a valid port number"))
+	}
+
+	return errs
+}
+
+func (l DatabaseBackupStorageLocation) validate(fldPath *field.Path) (errs field.ErrorList) {
+	switch l.StorageType {
+	case "azureblob":
+		errs = append(errs, requireString(fldPath.Child("storageBucket"), l.StorageBucket)...)
+		if !hasSecret(l.StorageSASToken) {
+			// Shared key access requires the account name and key
+			errs = append(errs, requireSecret(fldPath.Child("storageAccessKeyID"), l.StorageAccessKeyID)...)
+			errs = append(errs, requireSecret(fldPath.Child("storageSecretAccessKey"), l.StorageSecretAccessKey)...)
+		}
+
+	case "filesystem":
+		errs = append(errs, requireString(fldPath.Child("storagePath"), l.StoragePath)...)
+		if l.StorageVolumeClaimName != "" && l.StoragePath != "" && !path.IsAbs(l.StoragePath) {
+			errs = append(errs, field.Invalid(fldPath.Child("storagePath"), l.StoragePath, "must be absolute when storageVolumeClaimName is set"))
+		}
+
+	case "gcs":
+		errs = append(errs, requireString(fldPath.Child("storageBucket"), l.StorageBucket)...)
+
+	case "s3":
+		errs = append(errs, requireString(fldPath.Child("storageEndpoint"), l.StorageEndpoint)...)
+		errs = append(errs, requireString(fldPath.Child("storageBucket"), l.StorageBucket)...)
+	}
+
+	for i, recipient := range l.EncryptionRecipients {
+		if _, err := cryptostream.ParseRecipient(recipient); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("encryptionRecipients").Index(i), recipient, err.Error()))
+		}
+	}
+
+	return errs
+}
+
+func (d DatabaseBackupSpec) validateEngineConfig(path *field.Path) (errs field.ErrorList) {
+	configs := map[string]bool{
+		"cockroach": d.Cockroach != nil,
+		"mysql":     d.MySQL != nil,
+		"postgres":  d.Postgres != nil,
+	}
+
+	for _, engine := range sortedKeys(configs) {
+		switch {
+		case engine == d.DatabaseType && !configs[engine]:
+			errs = append(errs, field.Required(path.Child(engine), "must be set for databaseType "+d.DatabaseType))
+
+		case engine != d.DatabaseType && configs[engine]:
+			errs = append(errs, field.Forbidden(path.Child(engine), "must not be set for databaseType "+d.DatabaseType))
+		}
+	}
+
+	switch {
+	case d.Cockroach != nil && d.DatabaseType == "cockroach":
+		p := path.Child("cockroach")
+		errs = append(errs, validateConnection(p, d.Cockroach.Host, d.Cockroach.Port, d.Cockroach.Database)...)
+		errs = append(errs, requireString(p.Child("user"), d.Cockroach.User)...)
+		errs = append(errs, requireSecret(p.Child("cert"), d.Cockroach.Cert)...)
+		errs = append(errs, requireSecret(p.Child("certKey"), d.Cockroach.CertKey)...)
+		if !d.Cockroach.CertCAFromCluster {
+			errs = append(errs, requireSecret(p.Child("certCA"), d.Cockroach.CertCA)...)
+		}
+
+	case d.MySQL != nil && d.DatabaseType == "mysql":
+		p := path.Child("mysql")
+		errs = append(errs, validateConnection(p, d.MySQL.Host, d.MySQL.Port, d.MySQL.Database)...)
+		errs = append(errs, requireSecret(p.Child("user"), d.MySQL.User)...)
+
+	case d.Postgres != nil && d.DatabaseType == "postgres":
+		p := path.Child("postgres")
+		errs = append(errs, validateConnection(p, d.Postgres.Host, d.Postgres.Port, d.Postgres.Database)...)
+		errs = append(errs, requireString(p.Child("user"), d.Postgres.User)...)
+	}
+
+	return errs
+}
+
+func (d DatabaseBackupSpec) validateRetention(path *field.Path) (errs field.ErrorList) {
+	for _, format := range sortedKeys(d.RetentionConfig) {
+		if err := labelmanager.ValidateFormat(format); err != nil {
+			errs = append(errs, field.Invalid(path.Child("retentionConfig").Key(format), format, err.Error()))
+		}
+	}
+
+	for _, format := range sortedKeys(d.RetentionCounts) {
+		if err := labelmanager.ValidateFormat(format); err != nil {
+			errs = append(errs, field.Invalid(path.Child("retentionCounts").Key(format), format, err.Error()))
+		}
+	}
+
+	return errs
+}
+
+func (d DatabaseBackupSpec) validateSchedule(path *field.Path) (errs field.ErrorList) {
+	if d.BackupCron == "" {
+		if d.BackupIntervalHours == 0 {
+			errs = append(errs, field.Required(path.Child("backupCron"), "either backupCron or backupInterval must be set"))
+		}
+		return errs
+	}
+
+	if _, err := cron.ParseStandard(d.BackupCron); err != nil {
+		errs = append(errs, field.Invalid(path.Child("backupCron"), d.BackupCron, err.Error()))
+	}
+
+	return errs
+}
+
+func hasSecret(s Secret) bool {
+	return s.Value != "" || (s.FromSecret.Name != "" && s.FromSecret.Key != "")
+}
+
+func requireSecret(path *field.Path, s Secret) field.ErrorList {
+	if hasSecret(s) {
+		return nil
+	}
+
+	return field.ErrorList{field.Required(path, "either value or fromSecret name and key must be set")}
+}
+
+func requireString(path *field.Path, value string) field.ErrorList {
+	if value != "" {
+		return nil
+	}
+
+	return field.ErrorList{field.Required(path, "")}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func validateConnection(path *field.Path, host string, port int64, database string) (errs field.ErrorList) {
+	errs = append(errs, requireString(path.Child("host"), host)...)
+	errs = append(errs, requireString(path.Child("database"), database)...)
+
+	if port < 1 || port > 65535 {
+		errs = append(errs, field.Invalid(path.Child("port"), port, "must be a valid port number"))
+	}
+
+	return errs
+}
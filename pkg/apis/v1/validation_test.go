@@ -0,0 +1,169 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/NectGmbH/db-backup-controller/pkg/cryptostream"
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+)
+
+func TestDatabaseBackupSpecValidate(t *testing.T) {
+	valid := func() DatabaseBackupSpec {
+		return DatabaseBackupSpec{
+			DatabaseType:        "postgres",
+			BackupStorageClass:  "default",
+			BackupIntervalHours: 1,
+			Postgres: &PostgresConfig{
+				Host:     "db",
+				Port:     5432,
+				Database: "app",
+				User:     "app",
+			},
+		}
+	}
+
+	errorFields := func(spec DatabaseBackupSpec) (fields []string) {
+		for _, err := range spec.Validate(field.NewPath("spec")) {
+			fields = append(fields, err.Field)
+		}
+		return fields
+	}
+
+	assert.Empty(t, errorFields(valid()))
+
+	// Engine config needs to match the type and be complete
+	spec := valid()
+	spec.DatabaseType = "cockroach"
+	assert.Equal(t, []string{"spec.cockroach", "spec.postgres"}, errorFields(spec))
+
+	spec.Postgres = nil
+	spec.Cockroach = &CockroachConfig{
+		Host:              "crdb",
+		Port:              26257,
+		Database:          "app",
+		User:              "app",
+		Cert:              Secret{FromSecret: SecretKeyRef{Name: "certs", Key: "client.crt"}},
+		CertKey:           Secret{FromSecret: SecretKeyRef{Name: "certs"}},
+		CertCAFromCluster: true,
+	}
+	assert.Equal(t, []string{"spec.cockroach.certKey"}, errorFields(spec))
+
+	spec = valid()
+	spec.Postgres.Host = ""
+	spec.Postgres.Port = 0
+	assert.Equal(t, []string{"spec.postgres.host", "spec.postgres.port"}, errorFields(spec))
+
+	// Schedule needs to be set and parseable
+	spec = valid()
+	spec.BackupIntervalHours = 0
+	assert.Equal(t, []string{"spec.backupCron"}, errorFields(spec))
+
+	spec.BackupCron = "0 */2 * * *"
+	assert.Empty(t, errorFields(spec))
+
+	spec.BackupCron = "61 * * * *"
+	assert.Equal(t, []string{"spec.backupCron"}, errorFields(spec))
+
+	// Retention formats need to produce parseable labels
+	spec = valid()
+	spec.RetentionConfig = labelmanager.RetentionConfig{"%Y-%m-%d": time.Hour, "%Y-w%V": time.Hour}
+	spec.RetentionCounts = labelmanager.RetentionCounts{"hourly": 2}
+	assert.Equal(t, []string{"spec.retentionConfig[%Y-w%V]", "spec.retentionCounts[hourly]"}, errorFields(spec))
+}
+
+func TestDatabaseBackupStorageClassSpecValidate(t *testing.T) {
+	id, err := cryptostream.GenerateIdentity()
+	require.NoError(t, err)
+
+	valid := func() DatabaseBackupStorageClassSpec {
+		return DatabaseBackupStorageClassSpec{BackupLocations: []DatabaseBackupStorageLocation{
+			{
+				StorageType:          "s3",
+				StorageEndpoint:      "minio:9000",
+				StorageBucket:        "backups",
+				EncryptionRecipients: []string{id.Recipient().String()},
+			},
+			{
+				StorageType:            "azureblob",
+				StorageBucket:          "backups",
+				StorageAccessKeyID:     Secret{Value: "account"},
+				StorageSecretAccessKey: Secret{FromSecret: SecretKeyRef{Name: "azure", Key: "key"}},
+			},
+			{StorageType: "gcs", StorageBucket: "backups"},
+			{StorageType: "filesystem", StoragePath: "/backups", StorageVolumeClaimName: "backups"},
+		}}
+	}
+
+	errorFields := func(spec DatabaseBackupStorageClassSpec) (fields []string) {
+		for _, err := range spec.Validate(field.NewPath("spec")) {
+			fields = append(fields, err.Field)
+		}
+		return fields
+	}
+
+	assert.Empty(t, errorFields(valid()))
+
+	// Locations need the settings of their storage type
+	spec := valid()
+	spec.BackupLocations[0].StorageEndpoint = ""
+	spec.BackupLocations[1].StorageSecretAccessKey = Secret{}
+	spec.BackupLocations[2].StorageBucket = ""
+	spec.BackupLocations[3].StoragePath = "backups"
+	assert.Equal(t, []string{
+		"spec.backupLocations[0].storageEndpoint",
+		"spec.backupLocations[1].storageSecretAccessKey",
+		"spec.backupLocations[2].storageBucket",
+		"spec.backupLocations[3].storagePath",
+	}, errorFields(spec))
+
+	// Azure SAS token replaces the account key
+	spec = valid()
+	spec.BackupLocations[1].StorageAccessKeyID = Secret{}
+	spec.BackupLocations[1].StorageSecretAccessKey = Secret{}
+	spec.BackupLocations[1].StorageSASToken = Secret{Value: "sv=2022-11-02&sig=x"}
+	assert.Empty(t, errorFields(spec))
+
+	// Recipients need to be parseable
+	spec = valid()
+	spec.BackupLocations[0].EncryptionRecipients = append(spec.BackupLocations[0].EncryptionRecipients, "age1xyz")
+	assert.Equal(t, []string{"spec.backupLocations[0].encryptionRecipients[1]"}, errorFields(spec))
+
+	// Runner is started with a single service account and mounts
+	// every path only once
+	spec = valid()
+	spec.BackupLocations[0].StorageServiceAccountName = "s3-backups"
+	spec.BackupLocations[2].StorageServiceAccountName = "gcs-backups"
+	spec.BackupLocations = append(spec.BackupLocations, DatabaseBackupStorageLocation{
+		StorageType:            "filesystem",
+		StoragePath:            "/backups/",
+		StorageVolumeClaimName: "other",
+	})
+	assert.Equal(t, []string{
+		"spec.backupLocations[2].storageServiceAccountName",
+		"spec.backupLocations[4].storagePath",
+	}, errorFields(spec))
+}
+
+func TestRestoreTargetValidate(t *testing.T) {
+	errorFields := func(target RestoreTarget) (fields []string) {
+		for _, err := range target.Validate(field.NewPath("target")) {
+			fields = append(fields, err.Field)
+		}
+		return fields
+	}
+
+	assert.Empty(t, errorFields(RestoreTarget{}))
+	assert.Empty(t, errorFields(RestoreTarget{Host: "db.restore-test.svc", Port: 5432}))
+	assert.Empty(t, errorFields(RestoreTarget{Host: "10.0.0.1"}))
+	assert.Empty(t, errorFields(RestoreTarget{Host: "fd00::1"}))
+
+	assert.Equal(t, []string{"target.host"}, errorFields(RestoreTarget{Host: "db:5432"}))
+	assert.Equal(t, []string{"target.host"}, errorFields(RestoreTarget{Host: "db/../other"}))
+	assert.Equal(t, []string{"target.port"}, errorFields(RestoreTarget{Port: 65536}))
+	assert.Equal(t, []string{"target.port"}, errorFields(RestoreTarget{Port: -1}))
+}
@@ -11,6 +11,34 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupResultStatus) DeepCopyInto(out *BackupResultStatus) {
+	*out = *in
+	if in.LastSuccessTime != nil {
+		in, out := &in.LastSuccessTime, &out.LastSuccessTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastFailureTime != nil {
+		in, out := &in.LastFailureTime, &out.LastFailureTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextScheduledTime != nil {
+		in, out := &in.NextScheduledTime, &out.NextScheduledTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupResultStatus.
+func (in *BackupResultStatus) DeepCopy() *BackupResultStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupResultStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CockroachConfig) DeepCopyInto(out *CockroachConfig) {
 	*out = *in
@@ -30,6 +58,22 @@ func (in *CockroachConfig) DeepCopy() *CockroachConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CompressionConfig) DeepCopyInto(out *CompressionConfig) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CompressionConfig.
+func (in *CompressionConfig) DeepCopy() *CompressionConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CompressionConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseBackup) DeepCopyInto(out *DatabaseBackup) {
 	*out = *in
@@ -91,9 +135,127 @@ func (in *DatabaseBackupList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupRun) DeepCopyInto(out *DatabaseBackupRun) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupRun.
+func (in *DatabaseBackupRun) DeepCopy() *DatabaseBackupRun {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupRun)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseBackupRun) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupRunList) DeepCopyInto(out *DatabaseBackupRunList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseBackupRun, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupRunList.
+func (in *DatabaseBackupRunList) DeepCopy() *DatabaseBackupRunList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupRunList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseBackupRunList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupRunSpec) DeepCopyInto(out *DatabaseBackupRunSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupRunSpec.
+func (in *DatabaseBackupRunSpec) DeepCopy() *DatabaseBackupRunSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupRunSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupRunStatus) DeepCopyInto(out *DatabaseBackupRunStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupRunStatus.
+func (in *DatabaseBackupRunStatus) DeepCopy() *DatabaseBackupRunStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupRunStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseBackupSpec) DeepCopyInto(out *DatabaseBackupSpec) {
 	*out = *in
+	if in.BackupTimeout != nil {
+		in, out := &in.BackupTimeout, &out.BackupTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RestoreTimeout != nil {
+		in, out := &in.RestoreTimeout, &out.RestoreTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	if in.RetentionConfig != nil {
 		in, out := &in.RetentionConfig, &out.RetentionConfig
 		*out = make(labelmanager.RetentionConfig, len(*in))
@@ -101,6 +263,18 @@ func (in *DatabaseBackupSpec) DeepCopyInto(out *DatabaseBackupSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.RetentionCounts != nil {
+		in, out := &in.RetentionCounts, &out.RetentionCounts
+		*out = make(labelmanager.RetentionCounts, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Compression != nil {
+		in, out := &in.Compression, &out.Compression
+		*out = new(CompressionConfig)
+		**out = **in
+	}
 	if in.Cockroach != nil {
 		in, out := &in.Cockroach, &out.Cockroach
 		*out = new(CockroachConfig)
@@ -139,6 +313,7 @@ func (in *DatabaseBackupStatus) DeepCopyInto(out *DatabaseBackupStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	in.Backups.DeepCopyInto(&out.Backups)
 	return
 }
 
@@ -218,7 +393,9 @@ func (in *DatabaseBackupStorageClassSpec) DeepCopyInto(out *DatabaseBackupStorag
 	if in.BackupLocations != nil {
 		in, out := &in.BackupLocations, &out.BackupLocations
 		*out = make([]DatabaseBackupStorageLocation, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	return
 }
@@ -238,7 +415,14 @@ func (in *DatabaseBackupStorageLocation) DeepCopyInto(out *DatabaseBackupStorage
 	*out = *in
 	out.StorageAccessKeyID = in.StorageAccessKeyID
 	out.StorageSecretAccessKey = in.StorageSecretAccessKey
+	out.StorageServiceAccountJSON = in.StorageServiceAccountJSON
+	out.StorageSASToken = in.StorageSASToken
 	out.EncryptionPass = in.EncryptionPass
+	if in.EncryptionRecipients != nil {
+		in, out := &in.EncryptionRecipients, &out.EncryptionRecipients
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -252,6 +436,133 @@ func (in *DatabaseBackupStorageLocation) DeepCopy() *DatabaseBackupStorageLocati
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestore) DeepCopyInto(out *DatabaseRestore) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestore.
+func (in *DatabaseRestore) DeepCopy() *DatabaseRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseRestore) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestoreList) DeepCopyInto(out *DatabaseRestoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseRestore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestoreList.
+func (in *DatabaseRestoreList) DeepCopy() *DatabaseRestoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseRestoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestoreSpec) DeepCopyInto(out *DatabaseRestoreSpec) {
+	*out = *in
+	if in.PointInTime != nil {
+		in, out := &in.PointInTime, &out.PointInTime
+		*out = (*in).DeepCopy()
+	}
+	if in.SourceLocation != nil {
+		in, out := &in.SourceLocation, &out.SourceLocation
+		*out = new(int)
+		**out = **in
+	}
+	if in.Target != nil {
+		in, out := &in.Target, &out.Target
+		*out = new(RestoreTarget)
+		**out = **in
+	}
+	if in.IdentitiesFrom != nil {
+		in, out := &in.IdentitiesFrom, &out.IdentitiesFrom
+		*out = new(SecretKeyRef)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestoreSpec.
+func (in *DatabaseRestoreSpec) DeepCopy() *DatabaseRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestoreStatus) DeepCopyInto(out *DatabaseRestoreStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestoreStatus.
+func (in *DatabaseRestoreStatus) DeepCopy() *DatabaseRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MySQLConfig) DeepCopyInto(out *MySQLConfig) {
 	*out = *in
@@ -287,6 +598,22 @@ func (in *PostgresConfig) DeepCopy() *PostgresConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreTarget) DeepCopyInto(out *RestoreTarget) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreTarget.
+func (in *RestoreTarget) DeepCopy() *RestoreTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Secret) DeepCopyInto(out *Secret) {
 	*out = *in
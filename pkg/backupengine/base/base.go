@@ -3,6 +3,7 @@
 package base
 
 import (
+	"context"
 	_ "embed"
 	"io"
 
@@ -23,7 +24,7 @@ type (
 )
 
 // CreateBackup is not supported by this engine
-func (Engine) CreateBackup(io.Writer) error {
+func (Engine) CreateBackup(context.Context, io.Writer) error {
 	return errors.New("base-engine does not support backups")
 }
 
@@ -44,6 +45,6 @@ func (Engine) GetPodSpec() (out coreV1.PodSpec, err error) {
 func (Engine) Init(opts.InitOpts) error { return nil }
 
 // RestoreBackup is not supported by this engine
-func (Engine) RestoreBackup(helper.ReaderAtCloser) error {
+func (Engine) RestoreBackup(context.Context, helper.ReaderAtCloser) error {
 	return errors.New("base-engine does not support backups")
 }
@@ -3,6 +3,7 @@
 package cockroach
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"io"
@@ -47,7 +48,8 @@ func New() *Engine { return &Engine{} }
 
 // CreateBackup is used to instruct the backup engine to create
 // a backup. The means of doing so depends on the engine itself.
-func (e *Engine) CreateBackup(w io.Writer) error {
+// Cancelling the context cancels the BACKUP statement.
+func (e *Engine) CreateBackup(ctx context.Context, w io.Writer) error {
 	if e.hdl != nil {
 		// A listener is there, backup might be in progress
 		return errors.New("backup listener still active")
@@ -80,7 +82,7 @@ func (e *Engine) CreateBackup(w io.Writer) error {
 	}()
 
 	// This is fine-ish, we validate the name not to contain bad stuff
-	if _, err = db.Exec(fmt.Sprintf("BACKUP DATABASE %s TO $1", e.spec.Cockroach.Database),
+	if _, err = db.ExecContext(ctx, fmt.Sprintf("BACKUP DATABASE %s TO $1", e.spec.Cockroach.Database),
 		u.String(),
 	); err != nil {
 		return errors.Wrap(err, "executing backup")
@@ -126,6 +128,10 @@ func (e *Engine) GetPodSpec(imagePrefix string) (coreV1.PodSpec, error) {
 // one-shot initialization tasks like registering new HTTP
 // handlers
 func (e *Engine) Init(options opts.InitOpts) error {
+	if options.Spec.Cockroach == nil {
+		return errors.New("cockroach config not available")
+	}
+
 	if err := e.baseEngine.Init(options); err != nil {
 		return errors.Wrap(err, "initializing base engine")
 	}
@@ -147,8 +153,9 @@ func (e *Engine) Init(options opts.InitOpts) error {
 // RestoreBackup receives an io.Reader with the contents of the
 // backup to be restored. The means of doing so depends on the
 // engine itself. The contents of the reader will be the same
-// the engine provided during the CreateBackup result
-func (e *Engine) RestoreBackup(r io.ReaderAt, size int64) error {
+// the engine provided during the CreateBackup result. Cancelling
+// the context cancels the RESTORE statement.
+func (e *Engine) RestoreBackup(ctx context.Context, r io.ReaderAt, size int64) error {
 	if e.hdl != nil {
 		// A listener is there, restore might be in progress
 		return errors.New("backup sender still active")
@@ -179,7 +186,7 @@ func (e *Engine) RestoreBackup(r io.ReaderAt, size int64) error {
 	}()
 
 	// This is fine-ish, we validate the name not to contain bad stuff
-	if _, err = db.Exec(fmt.Sprintf("RESTORE DATABASE %s FROM $1", e.spec.Cockroach.Database),
+	if _, err = db.ExecContext(ctx, fmt.Sprintf("RESTORE DATABASE %s FROM $1", e.spec.Cockroach.Database),
 		u.String(),
 	); err != nil {
 		return errors.Wrap(err, "starting restore")
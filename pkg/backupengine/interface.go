@@ -3,6 +3,7 @@
 package backupengine
 
 import (
+	"context"
 	"io"
 
 	coreV1 "k8s.io/api/core/v1"
@@ -15,7 +16,9 @@ type (
 	Implementation interface {
 		// CreateBackup is used to instruct the backup engine to create
 		// a backup. The means of doing so depends on the engine itself.
-		CreateBackup(io.Writer) error
+		// When the context is cancelled the engine MUST stop the backup
+		// and return as soon as possible.
+		CreateBackup(ctx context.Context, w io.Writer) error
 		// GetPodSpec generates a pod-spec from the given backup
 		// specificiation containing required volume mounts from secrets
 		// or envFrom definitions (and possible other special cases).
@@ -29,8 +32,9 @@ type (
 		// the backup to be restored and the size of the backup. The
 		// means of doing so depends on the engine itself. The contents
 		// of the reader will be the same the engine provided during
-		// the CreateBackup result
-		RestoreBackup(r io.ReaderAt, size int64) error
+		// the CreateBackup result. When the context is cancelled the
+		// engine MUST stop the restore and return as soon as possible.
+		RestoreBackup(ctx context.Context, r io.ReaderAt, size int64) error
 		// RestoreBackup receives an io.ReaderAt with the contents of
 		// the backup to be restored, the size of the backup and a
 		// destination folder (already exists) to unpack the backup
@@ -0,0 +1,142 @@
+// Package mysql contains the implementation of the backupengine
+// for MySQL
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	coreV1 "k8s.io/api/core/v1"
+
+	backupControllerV1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/backupengine/base"
+	"github.com/NectGmbH/db-backup-controller/pkg/backupengine/opts"
+)
+
+// cancelWaitDelay is the time mysqldump / mysql get to release their
+// output after being killed because the context was cancelled
+const cancelWaitDelay = 10 * time.Second
+
+type (
+	// Engine implements backupengine interface
+	Engine struct {
+		baseEngine base.Engine
+		spec       backupControllerV1.DatabaseBackupSpec
+	}
+)
+
+// New creates a new Engine instance
+func New() *Engine { return &Engine{} }
+
+// CreateBackup is used to instruct the backup engine to create
+// a backup. The means of doing so depends on the engine itself.
+func (e *Engine) CreateBackup(ctx context.Context, w io.Writer) error {
+	// https://dev.mysql.com/doc/refman/8.0/en/mysqldump.html
+	//#nosec:G204 // Backing up the user-specified db is intentional
+	cmd := exec.CommandContext(
+		ctx,
+		"mysqldump",
+		"--single-transaction",  // Dump a consistent snapshot of InnoDB tables without locking them
+		"--routines",            // Include stored procedures and functions
+		"--triggers",            // Include triggers for each dumped table
+		"--events",              // Include Event Scheduler events
+		"--hex-blob",            // Dump binary columns using hexadecimal notation
+		"--no-tablespaces",      // Tablespace info requires PROCESS privilege and is not needed for a restore
+		"--set-gtid-purged=OFF", // Don't add GTID_PURGED as it breaks restoring into a server with GTID history
+		"--databases",           // Begin the output with a command to create the database itself and switch to it
+		"--user", e.spec.MySQL.User.Value,
+		e.spec.MySQL.Database,
+	)
+
+	cmd.Env = e.env()
+
+	cmd.Stderr = os.Stderr
+	cmd.WaitDelay = cancelWaitDelay
+	cmd.Stdout = w
+
+	return errors.Wrap(cmd.Run(), "running mysqldump")
+}
+
+// GetPodSpec generates a pod-spec from the given backup
+// specificiation containing required volume mounts from secrets
+// or envFrom definitions (and possible other special cases).
+// The mounted secret will be added by the controller.
+func (e *Engine) GetPodSpec(imagePrefix string) (coreV1.PodSpec, error) {
+	podSpec, err := e.baseEngine.GetPodSpec()
+	if err != nil {
+		return podSpec, errors.Wrap(err, "getting base spec")
+	}
+
+	// Set mysql image
+	podSpec.Containers[0].Image = strings.Join([]string{imagePrefix, "mysql", e.spec.DatabaseVersion}, "-")
+
+	return podSpec, nil
+}
+
+// Init is called once per backup engine and allows to execute
+// one-shot initialization tasks like registering new HTTP
+// handlers
+func (e *Engine) Init(options opts.InitOpts) error {
+	if options.Spec.MySQL == nil {
+		return errors.New("mysql config not available")
+	}
+
+	if err := e.baseEngine.Init(options); err != nil {
+		return errors.Wrap(err, "initializing base engine")
+	}
+
+	e.spec = options.Spec
+
+	return nil
+}
+
+// RestoreBackup receives an io.ReaderAt with the contents of
+// the backup to be restored and the size of the backup. The
+// means of doing so depends on the engine itself. The contents
+// of the reader will be the same the engine provided during
+// the CreateBackup result
+func (e *Engine) RestoreBackup(ctx context.Context, r io.ReaderAt, size int64) error {
+	//#nosec:G204 // Restoring as the user-specified user is intentional
+	cmd := exec.CommandContext(ctx, "mysql", "--user", e.spec.MySQL.User.Value)
+
+	cmd.Env = e.env()
+
+	cmd.Stdin = io.NewSectionReader(r, 0, size)
+	cmd.Stderr = os.Stderr
+	cmd.WaitDelay = cancelWaitDelay
+
+	return errors.Wrap(cmd.Run(), "running mysql")
+}
+
+// Unpack takes the backed up contents and puts them into a single
+// SQL file
+func (Engine) Unpack(r io.ReaderAt, size int64, destDir string) error {
+	f, err := os.Create(path.Join(destDir, "backup.sql")) //#nosec:G304 // It's intended to write to user specified location
+	if err != nil {
+		return errors.Wrap(err, "creating output file")
+	}
+
+	if _, err = io.Copy(f, io.NewSectionReader(r, 0, size)); err != nil {
+		return errors.Wrap(err, "copying file contents")
+	}
+
+	return errors.Wrap(f.Close(), "closing output file")
+}
+
+// env assembles the environment for the mysql client tools: The user
+// cannot be passed through the environment and therefore is given as
+// a parameter to the respective command
+func (e *Engine) env() []string {
+	return []string{
+		fmt.Sprintf("MYSQL_HOST=%s", e.spec.MySQL.Host),
+		fmt.Sprintf("MYSQL_TCP_PORT=%d", e.spec.MySQL.Port),
+		fmt.Sprintf("MYSQL_PWD=%s", e.spec.MySQL.Pass.Value),
+	}
+}
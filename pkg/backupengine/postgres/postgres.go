@@ -3,12 +3,14 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	coreV1 "k8s.io/api/core/v1"
@@ -18,6 +20,10 @@ import (
 	"github.com/NectGmbH/db-backup-controller/pkg/backupengine/opts"
 )
 
+// cancelWaitDelay is the time pg_dump / psql get to release their
+// output after being killed because the context was cancelled
+const cancelWaitDelay = 10 * time.Second
+
 type (
 	// Engine implements backupengine interface
 	Engine struct {
@@ -31,10 +37,11 @@ func New() *Engine { return &Engine{} }
 
 // CreateBackup is used to instruct the backup engine to create
 // a backup. The means of doing so depends on the engine itself.
-func (e *Engine) CreateBackup(w io.Writer) error {
+func (e *Engine) CreateBackup(ctx context.Context, w io.Writer) error {
 	// https://www.postgresql.org/docs/current/app-pgdump.html
 	//#nosec:G204 // Backing up the user-specified db is intentional
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		"pg_dump",
 		"--create",       // Begin the output with a command to create the database itself and reconnect to the created database.
 		"--format=plain", // Use a plain SQL file
@@ -49,6 +56,7 @@ func (e *Engine) CreateBackup(w io.Writer) error {
 	}
 
 	cmd.Stderr = os.Stderr
+	cmd.WaitDelay = cancelWaitDelay
 	cmd.Stdout = w
 
 	return errors.Wrap(cmd.Run(), "running pg_dump")
@@ -92,8 +100,8 @@ func (e *Engine) Init(options opts.InitOpts) error {
 // means of doing so depends on the engine itself. The contents
 // of the reader will be the same the engine provided during
 // the CreateBackup result
-func (e *Engine) RestoreBackup(r io.ReaderAt, size int64) error {
-	cmd := exec.Command("psql", "-v", "ON_ERROR_STOP=1")
+func (e *Engine) RestoreBackup(ctx context.Context, r io.ReaderAt, size int64) error {
+	cmd := exec.CommandContext(ctx, "psql", "-v", "ON_ERROR_STOP=1")
 
 	cmd.Env = []string{
 		fmt.Sprintf("PGHOST=%s", e.spec.Postgres.Host),
@@ -104,6 +112,7 @@ func (e *Engine) RestoreBackup(r io.ReaderAt, size int64) error {
 
 	cmd.Stdin = io.NewSectionReader(r, 0, size)
 	cmd.Stderr = os.Stderr
+	cmd.WaitDelay = cancelWaitDelay
 
 	return errors.Wrap(cmd.Run(), "running psql")
 }
@@ -2,6 +2,7 @@ package backupengine
 
 import (
 	"github.com/NectGmbH/db-backup-controller/pkg/backupengine/cockroach"
+	"github.com/NectGmbH/db-backup-controller/pkg/backupengine/mysql"
 	"github.com/NectGmbH/db-backup-controller/pkg/backupengine/postgres"
 )
 
@@ -12,6 +13,9 @@ func GetByName(name string) Implementation { //nolint:ireturn // Returning the i
 	case "cockroach":
 		return cockroach.New()
 
+	case "mysql":
+		return mysql.New()
+
 	case "postgres", "postgresql", "psql":
 		return postgres.New()
 
@@ -0,0 +1,186 @@
+// Package compressstream implements a seekable compression format
+// for backups: The data is split into frames which are compressed
+// independently and an index of the frames is appended to the
+// stream so the decompressed data can be read through an io.ReaderAt
+// without decompressing the whole stream.
+//
+// The stream has the following layout:
+//
+//	header   16 byte  magic "DBCCmprs", codec, 3 reserved bytes,
+//	                  uint32 frame size (big endian)
+//	frames   n byte   compressed frames
+//	index    4 byte   uint32 compressed size per frame (big endian)
+//	trailer  24 byte  uint64 frame count, uint64 decompressed size
+//	                  (both big endian), magic "DBCCmpIx"
+package compressstream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+const (
+	// CodecGzip compresses the frames using gzip
+	CodecGzip = "gzip"
+	// CodecZstd compresses the frames using zstd
+	CodecZstd = "zstd"
+
+	defaultFrameSize = 4 * 1024 * 1024
+	maxFrameSize     = 64 * 1024 * 1024
+
+	headerSize     = 16
+	indexEntrySize = 4
+	trailerSize    = 24
+)
+
+var (
+	header        = []byte("DBCCmprs")
+	trailerHeader = []byte("DBCCmpIx")
+
+	codecIDs = map[string]byte{
+		CodecGzip: 1,
+		CodecZstd: 2, //nolint:mnd // Codec identifier
+	}
+)
+
+type (
+	codec interface {
+		Compress(dst, src []byte) ([]byte, error)
+		Decompress(dst, src []byte) ([]byte, error)
+		// Close releases the resources held by the codec, it must not
+		// be used afterwards
+		Close() error
+	}
+
+	gzipCodec struct {
+		level int
+	}
+
+	zstdCodec struct {
+		dec *zstd.Decoder
+		enc *zstd.Encoder
+	}
+)
+
+// IsCompressed checks whether the given stream starts with the header
+// of this format and therefore needs to be read through a ReaderAt
+func IsCompressed(r io.ReaderAt, size int64) (bool, error) {
+	if size < headerSize+trailerSize {
+		return false, nil
+	}
+
+	hdrBuf := make([]byte, len(header))
+	if _, err := r.ReadAt(hdrBuf, 0); err != nil {
+		return false, errors.Wrap(err, "reading header from stream")
+	}
+
+	return bytes.Equal(hdrBuf, header), nil
+}
+
+// ValidateCodec checks whether the given codec is known and can be
+// used with the given level (0 = codec default)
+func ValidateCodec(name string, level int) error {
+	id, ok := codecIDs[name]
+	if !ok {
+		return errors.Errorf("unknown codec %q", name)
+	}
+
+	c, err := newCodec(id, level)
+	if err != nil {
+		return err
+	}
+
+	return c.Close()
+}
+
+func newCodec(id byte, level int) (codec, error) {
+	switch id {
+	case codecIDs[CodecGzip]:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+			return nil, errors.Errorf("invalid gzip level %d", level)
+		}
+		return gzipCodec{level: level}, nil
+
+	case codecIDs[CodecZstd]:
+		encLevel := zstd.SpeedDefault
+		if level != 0 {
+			encLevel = zstd.EncoderLevelFromZstd(level)
+		}
+
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(encLevel), zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, errors.Wrap(err, "creating zstd encoder")
+		}
+
+		// Frames are decoded using DecodeAll, so no concurrent stream
+		// decoding goroutines are needed
+		dec, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxFrameSize))
+		if err != nil {
+			enc.Close() //nolint:errcheck,gosec // Encoder was never used
+			return nil, errors.Wrap(err, "creating zstd decoder")
+		}
+
+		return zstdCodec{dec: dec, enc: enc}, nil
+
+	default:
+		return nil, errors.Errorf("unknown codec %d", id)
+	}
+}
+
+func (g gzipCodec) Compress(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+
+	w, err := gzip.NewWriterLevel(buf, g.level)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating gzip writer")
+	}
+
+	if _, err = w.Write(src); err != nil {
+		return nil, errors.Wrap(err, "compressing data")
+	}
+
+	if err = w.Close(); err != nil {
+		return nil, errors.Wrap(err, "closing gzip writer")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Close() error { return nil }
+
+func (gzipCodec) Decompress(dst, src []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating gzip reader")
+	}
+
+	// Frames never exceed the max frame size, so there is no need
+	// to read more than that (and the frame size is checked later)
+	buf := bytes.NewBuffer(dst)
+	if _, err = io.Copy(buf, io.LimitReader(r, maxFrameSize+1)); err != nil {
+		return nil, errors.Wrap(err, "decompressing data")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (z zstdCodec) Close() error {
+	z.dec.Close()
+	return errors.Wrap(z.enc.Close(), "closing zstd encoder")
+}
+
+func (z zstdCodec) Compress(dst, src []byte) ([]byte, error) {
+	return z.enc.EncodeAll(src, dst), nil
+}
+
+func (z zstdCodec) Decompress(dst, src []byte) ([]byte, error) {
+	data, err := z.dec.DecodeAll(src, dst)
+	return data, errors.Wrap(err, "decompressing data")
+}
@@ -0,0 +1,135 @@
+package compressstream
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFrameSize = 1024
+
+func TestRoundTrip(t *testing.T) {
+	// Compressible data: Random words from a small dictionary
+	var (
+		rawData = bytes.NewBuffer(nil)
+		words   = []string{"backup", "restore", "database", "cockroach", "postgres", "mysql"}
+		rng     = rand.New(rand.NewSource(1)) //#nosec:G404 // Test data, no crypto
+	)
+	for rawData.Len() < 10*testFrameSize+6 {
+		_, err := rawData.WriteString(words[rng.Intn(len(words))] + " ")
+		require.NoError(t, err)
+	}
+
+	for _, codec := range []string{CodecGzip, CodecZstd} {
+		t.Run(codec, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			w, err := newWriterWithFrameSize(buf, codec, 0, testFrameSize)
+			require.NoError(t, err)
+
+			for _, chunk := range splitData(rawData.Bytes(), 15, 2000, 1, 987) {
+				_, err = w.Write(chunk)
+				require.NoError(t, err)
+			}
+			require.NoError(t, w.Close())
+
+			assert.Less(t, buf.Len(), rawData.Len(), "data is compressed")
+
+			stream := bytes.NewReader(buf.Bytes())
+			isCompressed, err := IsCompressed(stream, stream.Size())
+			require.NoError(t, err)
+			assert.True(t, isCompressed)
+
+			r, err := NewReaderAt(stream, stream.Size())
+			require.NoError(t, err)
+			assert.Equal(t, int64(rawData.Len()), r.Size())
+
+			vData, err := io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+			require.NoError(t, err)
+			assert.Equal(t, rawData.Bytes(), vData)
+
+			// Random access across frame borders
+			vData = make([]byte, 2*testFrameSize)
+			n, err := r.ReadAt(vData, 3*testFrameSize-34)
+			require.NoError(t, err)
+			assert.Equal(t, len(vData), n)
+			assert.Equal(t, rawData.Bytes()[3*testFrameSize-34:5*testFrameSize-34], vData)
+
+			// Read beyond the end
+			n, err = r.ReadAt(vData, r.Size()-6)
+			assert.ErrorIs(t, err, io.EOF)
+			assert.Equal(t, 6, n)
+
+			assert.NoError(t, r.Close())
+		})
+	}
+}
+
+func TestEmptyStream(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, CodecZstd, 0)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	r, err := NewReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), r.Size())
+	assert.NoError(t, r.Close())
+}
+
+func TestCloseReleasesCodec(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 20; i++ {
+		buf := new(bytes.Buffer)
+		w, err := NewWriter(buf, CodecZstd, 0)
+		require.NoError(t, err)
+		_, err = w.Write([]byte("data"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		r, err := NewReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		_, err = r.ReadAt(make([]byte, 4), 0)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+	}
+
+	// Encoders and decoders must not keep their goroutines running
+	assert.Eventually(t, func() bool { return runtime.NumGoroutine() <= before+2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestInvalidStreams(t *testing.T) {
+	isCompressed, err := IsCompressed(bytes.NewReader(bytes.Repeat([]byte("a"), 100)), 100)
+	require.NoError(t, err)
+	assert.False(t, isCompressed)
+
+	buf := new(bytes.Buffer)
+	w, err := newWriterWithFrameSize(buf, CodecGzip, 0, testFrameSize)
+	require.NoError(t, err)
+	_, err = w.Write(bytes.Repeat([]byte("a"), 3*testFrameSize))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	truncated := buf.Bytes()[:buf.Len()-1]
+	_, err = NewReaderAt(bytes.NewReader(truncated), int64(len(truncated)))
+	assert.Error(t, err, "truncated trailer")
+
+	assert.Error(t, ValidateCodec("lzma", 0), "unknown codec")
+	assert.Error(t, ValidateCodec(CodecGzip, 12), "invalid level")
+	assert.NoError(t, ValidateCodec(CodecZstd, 19))
+}
+
+func splitData(data []byte, sizes ...int) (chunks [][]byte) {
+	for i := 0; len(data) > 0; i++ {
+		n := min(sizes[i%len(sizes)], len(data))
+		chunks = append(chunks, data[:n])
+		data = data[n:]
+	}
+	return chunks
+}
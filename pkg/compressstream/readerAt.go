@@ -0,0 +1,212 @@
+package compressstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// ReaderAt implements an io.ReaderAt on a stream compressed
+	// using the format specified in this package
+	ReaderAt struct {
+		codec     codec
+		frameSize int64
+		frames    []frameInfo
+		size      int64
+
+		next io.ReaderAt
+
+		// Sequential reads are usually smaller than a frame, so the
+		// last decompressed frame is kept to not decompress it repeatedly
+		cacheLock  sync.Mutex
+		cacheFrame int
+		cacheData  []byte
+	}
+
+	frameInfo struct {
+		offset int64
+		size   int64
+	}
+)
+
+var (
+	_ io.ReaderAt = (*ReaderAt)(nil)
+	_ io.Closer   = (*ReaderAt)(nil)
+)
+
+// NewReaderAt creates a new ReaderAt on the given io.ReaderAt
+// containing size bytes and reads the frame index from the stream.
+// The ReaderAt must be closed after use to release the codec.
+func NewReaderAt(next io.ReaderAt, size int64) (*ReaderAt, error) {
+	if size < headerSize+trailerSize {
+		return nil, errors.New("stream is too short")
+	}
+
+	hdr := make([]byte, headerSize)
+	if _, err := next.ReadAt(hdr, 0); err != nil {
+		return nil, errors.Wrap(err, "reading header")
+	}
+	if !bytes.Equal(hdr[:len(header)], header) {
+		return nil, errors.New("stream does not have proper header")
+	}
+
+	r := &ReaderAt{
+		frameSize: int64(binary.BigEndian.Uint32(hdr[12:])),
+		next:      next,
+
+		cacheFrame: -1,
+	}
+
+	if r.frameSize == 0 || r.frameSize > maxFrameSize {
+		return nil, errors.Errorf("invalid frame size %d", r.frameSize)
+	}
+
+	var err error
+	if r.codec, err = newCodec(hdr[len(header)], 0); err != nil {
+		return nil, errors.Wrap(err, "creating codec")
+	}
+
+	if err = r.readIndex(size); err != nil {
+		r.codec.Close() //nolint:errcheck,gosec // Reading the index already failed
+		return nil, errors.Wrap(err, "reading index")
+	}
+
+	return r, nil
+}
+
+// Close implements the io.Closer interface and releases the codec.
+// The underlying io.ReaderAt is NOT closed.
+func (r *ReaderAt) Close() error {
+	return errors.Wrap(r.codec.Close(), "closing codec")
+}
+
+// ReadAt implements the io.ReaderAt interface
+func (r *ReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	for n < len(p) && off+int64(n) < r.size {
+		var (
+			pos   = off + int64(n)
+			idx   = int(pos / r.frameSize)
+			frame []byte
+		)
+
+		if frame, err = r.cachedFrame(idx); err != nil {
+			return n, err
+		}
+
+		n += copy(p[n:], frame[pos-int64(idx)*r.frameSize:])
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// Size returns the size of the decompressed data
+func (r *ReaderAt) Size() int64 { return r.size }
+
+func (r *ReaderAt) cachedFrame(idx int) ([]byte, error) {
+	r.cacheLock.Lock()
+	if r.cacheFrame == idx {
+		defer r.cacheLock.Unlock()
+		return r.cacheData, nil
+	}
+	r.cacheLock.Unlock()
+
+	data, err := r.readFrame(idx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The cached data is never modified, therefore it is safe to
+	// replace it while others are still reading the old data
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+	r.cacheFrame, r.cacheData = idx, data
+
+	return data, nil
+}
+
+func (r *ReaderAt) readFrame(idx int) ([]byte, error) {
+	var (
+		info = r.frames[idx]
+		buf  = make([]byte, info.size)
+	)
+
+	if _, err := r.next.ReadAt(buf, info.offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, errors.Wrapf(err, "reading frame %d", idx)
+	}
+
+	data, err := r.codec.Decompress(make([]byte, 0, r.frameSize), buf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decompressing frame %d", idx)
+	}
+
+	expected := r.frameSize
+	if idx == len(r.frames)-1 {
+		expected = r.size - int64(idx)*r.frameSize
+	}
+
+	if int64(len(data)) != expected {
+		return nil, errors.Errorf("frame %d has size %d, expected %d", idx, len(data), expected)
+	}
+
+	return data, nil
+}
+
+func (r *ReaderAt) readIndex(size int64) error {
+	trailer := make([]byte, trailerSize)
+	if _, err := r.next.ReadAt(trailer, size-trailerSize); err != nil && !errors.Is(err, io.EOF) {
+		return errors.Wrap(err, "reading trailer")
+	}
+	if !bytes.Equal(trailer[16:], trailerHeader) {
+		return errors.New("stream does not have proper trailer")
+	}
+
+	var (
+		frames = binary.BigEndian.Uint64(trailer[0:8])
+		dSize  = binary.BigEndian.Uint64(trailer[8:16])
+	)
+
+	// The index must fit between header and trailer and the frames
+	// must be able to hold the decompressed data
+	if frames > uint64(size-headerSize-trailerSize)/indexEntrySize ||
+		dSize > frames*uint64(r.frameSize) || (frames > 0 && dSize <= (frames-1)*uint64(r.frameSize)) {
+		return errors.New("invalid trailer")
+	}
+
+	var (
+		indexStart = size - trailerSize - int64(frames)*indexEntrySize //#nosec:G115 // Checked above
+		index      = make([]byte, int64(frames)*indexEntrySize)        //#nosec:G115 // Checked above
+		offset     = int64(headerSize)
+	)
+
+	if _, err := r.next.ReadAt(index, indexStart); err != nil && !errors.Is(err, io.EOF) {
+		return errors.Wrap(err, "reading index")
+	}
+
+	r.frames = make([]frameInfo, frames)
+	for i := range r.frames {
+		r.frames[i] = frameInfo{
+			offset: offset,
+			size:   int64(binary.BigEndian.Uint32(index[i*indexEntrySize:])),
+		}
+		offset += r.frames[i].size
+	}
+
+	if offset != indexStart {
+		return errors.New("frame sizes do not match stream size")
+	}
+
+	r.size = int64(dSize) //#nosec:G115 // Checked above
+	return nil
+}
@@ -0,0 +1,137 @@
+package compressstream
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Writer implements an io.WriteCloser compressing the data written
+	// to it into the format specified in this package
+	Writer struct {
+		codec     codec
+		frameSize int
+		next      io.Writer
+
+		buf     []byte
+		compBuf []byte
+		closed  bool
+		index   []byte
+		frames  uint64
+		written uint64
+	}
+)
+
+var _ io.WriteCloser = (*Writer)(nil)
+
+// NewWriter creates a new Writer using the given codec and level
+// (0 = codec default) and writes the header to the underlying writer
+func NewWriter(next io.Writer, codecName string, level int) (*Writer, error) {
+	return newWriterWithFrameSize(next, codecName, level, defaultFrameSize)
+}
+
+func newWriterWithFrameSize(next io.Writer, codecName string, level, frameSize int) (*Writer, error) {
+	id, ok := codecIDs[codecName]
+	if !ok {
+		return nil, errors.Errorf("unknown codec %q", codecName)
+	}
+
+	c, err := newCodec(id, level)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating codec")
+	}
+
+	hdr := make([]byte, headerSize)
+	copy(hdr, header)
+	hdr[len(header)] = id
+	binary.BigEndian.PutUint32(hdr[12:], uint32(frameSize)) //#nosec:G115 // Frame size is a constant
+
+	if _, err = next.Write(hdr); err != nil {
+		c.Close() //nolint:errcheck,gosec // Writing the header already failed
+		return nil, errors.Wrap(err, "writing header")
+	}
+
+	return &Writer{
+		codec:     c,
+		frameSize: frameSize,
+		next:      next,
+
+		buf: make([]byte, 0, frameSize),
+	}, nil
+}
+
+// Close implements the io.Closer interface and MUST be called after
+// all writes are finished as it writes the last frame and the index
+// required to read the stream. The underlying writer is NOT closed.
+func (w *Writer) Close() (err error) {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	defer func() {
+		if cErr := w.codec.Close(); cErr != nil && err == nil {
+			err = errors.Wrap(cErr, "closing codec")
+		}
+	}()
+
+	if len(w.buf) > 0 {
+		if err := w.writeFrame(); err != nil {
+			return err
+		}
+	}
+
+	trailer := make([]byte, trailerSize)
+	binary.BigEndian.PutUint64(trailer[0:8], w.frames)
+	binary.BigEndian.PutUint64(trailer[8:16], w.written)
+	copy(trailer[16:], trailerHeader)
+
+	if _, err := w.next.Write(append(w.index, trailer...)); err != nil {
+		return errors.Wrap(err, "writing index")
+	}
+
+	return nil
+}
+
+// Write implements the io.Writer interface. The data is buffered
+// until a full frame is available, see Close for how to write the
+// remaining data.
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if w.closed {
+		return 0, errors.New("write to closed writer")
+	}
+
+	for len(p) > 0 {
+		copied := min(w.frameSize-len(w.buf), len(p))
+		w.buf = append(w.buf, p[:copied]...)
+		p = p[copied:]
+		n += copied
+
+		if len(w.buf) == w.frameSize {
+			if err = w.writeFrame(); err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (w *Writer) writeFrame() (err error) {
+	if w.compBuf, err = w.codec.Compress(w.compBuf[:0], w.buf); err != nil {
+		return errors.Wrap(err, "compressing frame")
+	}
+
+	if _, err = w.next.Write(w.compBuf); err != nil {
+		return errors.Wrap(err, "writing frame")
+	}
+
+	w.index = binary.BigEndian.AppendUint32(w.index, uint32(len(w.compBuf))) //#nosec:G115 // Compressed frames are way smaller than 4GiB
+	w.frames++
+	w.written += uint64(len(w.buf))
+	w.buf = w.buf[:0]
+
+	return nil
+}
@@ -1,8 +1,32 @@
 // Package cryptostream implements transparent encryption on ReaderAt
 // and Writer streams in order to enforce encrypted backups
+//
+// Two stream formats are known to this package:
+//
+//   - v1 consists of the header, an 8 byte salt and the AES-256-CTR
+//     encrypted data. It does not provide any integrity protection and
+//     is only written through NewWriterV1 (or the deprecated NewWriter).
+//   - v2 consists of the header (carrying the chunk size and the key
+//     derivation parameters) and the data split into chunks, each of
+//     them encrypted and authenticated using AES-256-GCM. The chunks
+//     can be decrypted independently and the last chunk is marked as
+//     such, so modifications and truncation of the stream are detected.
+//     The key is either derived from a passphrase or randomly generated
+//     and wrapped for one or more X25519 recipients, so the backup can
+//     be written without having access to the keys to read it.
+//
+// NewReaderAtWithKeys detects the format of the stream and is able to
+// read both of them.
 package cryptostream
 
-import "crypto/aes"
+import (
+	"bytes"
+	"crypto/aes"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
 
 const (
 	cryptoIVLen      = 16
@@ -14,6 +38,123 @@ const (
 var header = []byte("DBCCrypt")
 
 // HeaderSize represents the metadata size prepended to the data
-// stream and needs to be subtracted when passing the payload size
-// by using the file size of the encrypted data
+// stream in the v1 format. Use CryptoReaderAt.Size to determine the
+// payload size of an encrypted stream.
 const HeaderSize = aes.BlockSize
+
+type (
+	// CryptoReaderAt implements an io.ReaderAt on an encrypted data
+	// stream in any of the formats defined in this library
+	CryptoReaderAt struct {
+		next    versionedReaderAt
+		size    int64
+		version int
+	}
+
+	// Keys contains the secrets available to decrypt a stream: Which
+	// of them is used depends on how the stream was encrypted
+	Keys struct {
+		// Passphrase is used for v1 streams and v2 streams written
+		// through NewWriterV2
+		Passphrase []byte
+		// Identities are used for v2 streams written through
+		// NewRecipientWriter
+		Identities []Identity
+	}
+
+	// IntegrityError is returned when a chunk of a v2 stream fails
+	// the authentication and therefore has been modified or corrupted
+	IntegrityError struct {
+		// Chunk is the index of the affected chunk
+		Chunk int64
+		// Offset is the offset of the chunk in the decrypted data
+		Offset int64
+	}
+
+	versionedReaderAt interface {
+		io.ReaderAt
+		Verify() error
+	}
+)
+
+var (
+	_ io.ReaderAt = CryptoReaderAt{}
+
+	// ErrIntegrityCheckFailed is wrapped by all IntegrityErrors and can
+	// be used to check whether an error was caused by a corrupted stream
+	ErrIntegrityCheckFailed = errors.New("integrity check failed")
+)
+
+// NewReaderAt creates a new CryptoReaderAt on the given io.ReaderAt,
+// reads header and salt and verifies the stream follows the v1 format.
+// The size of the data is not known to it, so Size returns -1.
+//
+// Deprecated: Use NewReaderAtWithKeys to read streams in all formats.
+func NewReaderAt(next io.ReaderAt, pass []byte) (CryptoReaderAt, error) {
+	r, err := newReaderAtV1(next, pass)
+	if err != nil {
+		return CryptoReaderAt{}, err
+	}
+
+	return CryptoReaderAt{next: r, size: -1, version: 1}, nil
+}
+
+// NewReaderAtWithKeys creates a new CryptoReaderAt on the given
+// io.ReaderAt containing size bytes, detects the format of the stream
+// by its header and verifies the stream follows the format specified
+// in this library. All secrets available to decrypt the stream are
+// passed as keys.
+func NewReaderAtWithKeys(next io.ReaderAt, size int64, keys Keys) (*CryptoReaderAt, error) {
+	// Validate this is a stream we can work on
+	hdrBuf := make([]byte, len(header))
+	if _, err := next.ReadAt(hdrBuf, 0); err != nil {
+		return nil, errors.Wrap(err, "reading header from stream")
+	}
+
+	switch {
+	case bytes.Equal(hdrBuf, header):
+		if len(keys.Passphrase) == 0 {
+			return nil, errors.New("v1 stream requires a passphrase")
+		}
+
+		r, err := newReaderAtV1(next, keys.Passphrase)
+		if err != nil {
+			return nil, errors.Wrap(err, "opening v1 stream")
+		}
+		return &CryptoReaderAt{next: r, size: size - HeaderSize, version: 1}, nil
+
+	case bytes.Equal(hdrBuf, headerV2):
+		r, err := newReaderAtV2(next, size, keys)
+		if err != nil {
+			return nil, errors.Wrap(err, "opening v2 stream")
+		}
+		return &CryptoReaderAt{next: r, size: r.size, version: 2}, nil //nolint:mnd // Format version
+
+	default:
+		return nil, errors.New("stream does not have proper header")
+	}
+}
+
+// ReadAt implements the io.ReaderAt interface
+func (c CryptoReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	return c.next.ReadAt(p, off) //nolint:wrapcheck // Errors are created within this package
+}
+
+// Size returns the size of the decrypted data
+func (c CryptoReaderAt) Size() int64 { return c.size }
+
+// Verify reads the whole stream and checks its integrity. As the v1
+// format does not contain any integrity information, streams in that
+// format are never reported to be broken.
+func (c CryptoReaderAt) Verify() error {
+	return errors.Wrap(c.next.Verify(), "verifying stream")
+}
+
+// Version returns the format version of the stream
+func (c CryptoReaderAt) Version() int { return c.version }
+
+func (e IntegrityError) Error() string {
+	return fmt.Sprintf("chunk %d (offset %d): %s", e.Chunk, e.Offset, ErrIntegrityCheckFailed)
+}
+
+func (IntegrityError) Unwrap() error { return ErrIntegrityCheckFailed }
@@ -0,0 +1,100 @@
+package cryptostream
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// The v2 header has the following layout:
+//
+//	magic       8 byte  "DBCCryp2"
+//	chunk size  4 byte  uint32, big endian, size of the plaintext chunks
+//	key type    1 byte  how to obtain the key (keyType...)
+//	key length  2 byte  uint16, big endian, length of the key block
+//	key block   n byte  key type specific data (salt for passphrases,
+//	                    wrapped keys for recipients)
+//
+// The whole header is used as additional data for every chunk so it
+// cannot be modified without breaking the authentication.
+const (
+	defaultChunkSize = 64 * 1024
+	maxChunkSize     = 16 * 1024 * 1024
+	saltLengthV2     = 16
+
+	headerV2FixedLen = 15
+
+	keyTypePassphrase byte = 1
+	keyTypeRecipients byte = 2
+
+	nonceFinalFlag byte = 1
+)
+
+var headerV2 = []byte("DBCCryp2")
+
+type (
+	headerV2Info struct {
+		chunkSize int64
+		keyType   byte
+		keyBlock  []byte
+
+		raw []byte
+	}
+)
+
+func buildHeaderV2(chunkSize int, keyType byte, keyBlock []byte) []byte {
+	hdr := make([]byte, headerV2FixedLen, headerV2FixedLen+len(keyBlock))
+	copy(hdr, headerV2)
+	binary.BigEndian.PutUint32(hdr[8:12], uint32(chunkSize)) //#nosec:G115 // Chunk size is limited by maxChunkSize
+	hdr[12] = keyType
+	binary.BigEndian.PutUint16(hdr[13:15], uint16(len(keyBlock))) //#nosec:G115 // Key blocks are way smaller than 64k
+	return append(hdr, keyBlock...)
+}
+
+func readHeaderV2(next io.ReaderAt) (headerV2Info, error) {
+	fixed := make([]byte, headerV2FixedLen)
+	if _, err := next.ReadAt(fixed, 0); err != nil {
+		return headerV2Info{}, errors.Wrap(err, "reading header from stream")
+	}
+
+	info := headerV2Info{
+		chunkSize: int64(binary.BigEndian.Uint32(fixed[8:12])),
+		keyType:   fixed[12],
+		keyBlock:  make([]byte, binary.BigEndian.Uint16(fixed[13:15])),
+	}
+
+	if info.chunkSize == 0 || info.chunkSize > maxChunkSize {
+		return headerV2Info{}, errors.Errorf("invalid chunk size %d", info.chunkSize)
+	}
+
+	if _, err := next.ReadAt(info.keyBlock, headerV2FixedLen); err != nil {
+		return headerV2Info{}, errors.Wrap(err, "reading key block from stream")
+	}
+
+	info.raw = append(fixed, info.keyBlock...) //nolint:gocritic // Fixed part is not used afterwards
+	return info, nil
+}
+
+// chunkNonce generates the nonce for the chunk with the given index:
+// As every stream uses its own key the nonce only needs to be unique
+// within the stream and consists of the chunk index and a flag marking
+// the last chunk (nonceFinalFlag) in order to detect truncated streams
+func chunkNonce(idx int64, flags byte) []byte {
+	nonce := make([]byte, 12)                            //nolint:mnd // Standard GCM nonce size
+	binary.BigEndian.PutUint64(nonce[3:11], uint64(idx)) //#nosec:G115 // Index is never negative
+	nonce[11] = flags
+	return nonce
+}
+
+func newChunkAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating cipher")
+	}
+
+	aead, err := cipher.NewGCM(block)
+	return aead, errors.Wrap(err, "creating GCM")
+}
@@ -17,17 +17,29 @@ func deriveKeyIV(pass, salt []byte) (key, iv []byte, err error) {
 	return rawKey[:cryptoKeyLen], rawKey[cryptoKeyLen : cryptoIVLen+cryptoKeyLen], nil
 }
 
-func getRandomSalt() (salt []byte, err error) {
-	salt = make([]byte, saltLength)
+func deriveKey(pass, salt []byte) (key []byte, err error) {
+	if len(salt) != saltLengthV2 {
+		return nil, errors.Errorf("invalid salt length %d", len(salt))
+	}
+
+	return pbkdf2.Key(pass, salt, pbkdf2Iterations, cryptoKeyLen, sha512.New), nil
+}
 
-	n, err := rand.Read(salt)
+func getRandomBytes(length int) (data []byte, err error) {
+	data = make([]byte, length)
+
+	n, err := rand.Read(data)
 	if err != nil {
 		return nil, errors.Wrap(err, "reading random bytes")
 	}
 
-	if n != saltLength {
-		return nil, errors.Errorf("incomplete salt read %d / %d", n, saltLength)
+	if n != length {
+		return nil, errors.Errorf("incomplete random read %d / %d", n, length)
 	}
 
-	return salt, nil
+	return data, nil
+}
+
+func getRandomSalt() (salt []byte, err error) {
+	return getRandomBytes(saltLength)
 }
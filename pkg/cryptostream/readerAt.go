@@ -11,10 +11,9 @@ import (
 )
 
 type (
-	// CryptoReaderAt implements an io.ReaderAt on an AES-256-CTR
-	// encrypted data stream. The data stream is expected to have the
-	// format defined in this library.
-	CryptoReaderAt struct {
+	// readerAtV1 implements an io.ReaderAt on an AES-256-CTR
+	// encrypted data stream in the v1 format
+	readerAtV1 struct {
 		aes *aesctrat.AesCtr
 		iv  []byte
 
@@ -22,40 +21,40 @@ type (
 	}
 )
 
-var _ io.ReaderAt = CryptoReaderAt{}
+var _ versionedReaderAt = readerAtV1{}
 
-// NewReaderAt creates a new CryptoReaderAt on the given io.ReaderAt,
-// reads header and salt and verifies the stream follows the format
-// specified in this library.
-func NewReaderAt(next io.ReaderAt, pass []byte) (CryptoReaderAt, error) {
+// newReaderAtV1 creates a new readerAtV1 on the given io.ReaderAt,
+// reads header and salt and verifies the stream follows the v1
+// format specified in this library.
+func newReaderAtV1(next io.ReaderAt, pass []byte) (readerAtV1, error) {
 	// Validate this is a stream we can work on
 	hdrBuf := make([]byte, len(header))
 	_, err := next.ReadAt(hdrBuf, 0)
 	if err != nil {
-		return CryptoReaderAt{}, errors.Wrap(err, "reading header from stream")
+		return readerAtV1{}, errors.Wrap(err, "reading header from stream")
 	}
 	if !bytes.Equal(hdrBuf, header) {
-		return CryptoReaderAt{}, errors.New("stream does not have proper header")
+		return readerAtV1{}, errors.New("stream does not have proper header")
 	}
 
 	// Get the salt from the stream
 	salt := make([]byte, saltLength)
 	n, err := next.ReadAt(salt, int64(len(header)))
 	if err != nil {
-		return CryptoReaderAt{}, errors.Wrap(err, "reading salt from stream")
+		return readerAtV1{}, errors.Wrap(err, "reading salt from stream")
 	}
 	if n != saltLength {
-		return CryptoReaderAt{}, errors.Errorf("read %d of %d byte salt", n, saltLength)
+		return readerAtV1{}, errors.Errorf("read %d of %d byte salt", n, saltLength)
 	}
 
 	// Create IV / Key from pass and salt
 	key, iv, err := deriveKeyIV(pass, salt)
 	if err != nil {
-		return CryptoReaderAt{}, errors.Wrap(err, "deriving key/iv")
+		return readerAtV1{}, errors.Wrap(err, "deriving key/iv")
 	}
 
 	// return everything
-	return CryptoReaderAt{
+	return readerAtV1{
 		aes: aesctrat.NewAesCtr(key),
 		iv:  iv,
 
@@ -64,7 +63,7 @@ func NewReaderAt(next io.ReaderAt, pass []byte) (CryptoReaderAt, error) {
 }
 
 // ReadAt implements the io.ReaderAt interface
-func (c CryptoReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+func (c readerAtV1) ReadAt(p []byte, off int64) (n int, err error) {
 	// We've been asked to read at a position {off} in the stream. What
 	// they don't know: We've added {aes.BlockSize} bytes before that
 	// therefore we need to shift that position by {aes.BlockSize}.
@@ -112,3 +111,8 @@ func (c CryptoReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
 	ePos := int(math.Min(float64(n), float64(intOff-readStartOff+int64(len(p)))))
 	return copy(p, data[intOff-readStartOff:ePos]), nil
 }
+
+// Verify implements the versionedReaderAt interface: The v1 format
+// does not contain any integrity information so there is nothing to
+// verify
+func (readerAtV1) Verify() error { return nil }
@@ -0,0 +1,191 @@
+package cryptostream
+
+import (
+	"crypto/cipher"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// readerAtV2 implements an io.ReaderAt on a chunked AES-256-GCM
+	// encrypted data stream in the v2 format
+	readerAtV2 struct {
+		aead   cipher.AEAD
+		header []byte
+
+		chunkSize  int64
+		chunks     int64
+		encEnd     int64
+		sealedSize int64
+		size       int64
+
+		next io.ReaderAt
+
+		// Sequential reads are usually smaller than a chunk, so the
+		// last decrypted chunk is kept to not decrypt it repeatedly
+		cacheLock  sync.Mutex
+		cacheChunk int64
+		cacheData  []byte
+	}
+)
+
+var _ versionedReaderAt = (*readerAtV2)(nil)
+
+// newReaderAtV2 creates a new readerAtV2 on the given io.ReaderAt,
+// reads the header, obtains the key using the matching one of the
+// given keys and derives the payload size from the stream size
+func newReaderAtV2(next io.ReaderAt, size int64, keys Keys) (*readerAtV2, error) {
+	hdr, err := readHeaderV2(next)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading header")
+	}
+
+	var key []byte
+	switch hdr.keyType {
+	case keyTypePassphrase:
+		if len(keys.Passphrase) == 0 {
+			return nil, errors.New("stream is encrypted using a passphrase but none was given")
+		}
+		if key, err = deriveKey(keys.Passphrase, hdr.keyBlock); err != nil {
+			return nil, errors.Wrap(err, "deriving key")
+		}
+
+	case keyTypeRecipients:
+		if len(keys.Identities) == 0 {
+			return nil, errors.New("stream is encrypted to recipients but no identity was given")
+		}
+		if key, err = unwrapFileKey(hdr.keyBlock, keys.Identities); err != nil {
+			return nil, errors.Wrap(err, "unwrapping key")
+		}
+
+	default:
+		return nil, errors.Errorf("unsupported key type %d", hdr.keyType)
+	}
+
+	aead, err := newChunkAEAD(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating AEAD")
+	}
+
+	r := &readerAtV2{
+		aead:   aead,
+		header: hdr.raw,
+
+		chunkSize:  hdr.chunkSize,
+		encEnd:     size,
+		sealedSize: hdr.chunkSize + int64(aead.Overhead()),
+
+		next: next,
+
+		cacheChunk: -1,
+	}
+
+	// Every stream contains at least one (possibly empty) chunk and
+	// only the last chunk may be shorter than the chunk size
+	var (
+		encSize = size - int64(len(hdr.raw))
+		rest    = encSize % r.sealedSize
+	)
+
+	r.chunks = encSize / r.sealedSize
+	switch {
+	case rest == 0 && r.chunks > 0:
+		r.size = r.chunks * r.chunkSize
+
+	case rest >= int64(aead.Overhead()):
+		r.chunks++
+		r.size = (r.chunks-1)*r.chunkSize + rest - int64(aead.Overhead())
+
+	default:
+		return nil, errors.New("stream is truncated")
+	}
+
+	return r, nil
+}
+
+// ReadAt implements the io.ReaderAt interface
+func (r *readerAtV2) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	for n < len(p) && off+int64(n) < r.size {
+		var (
+			pos   = off + int64(n)
+			idx   = pos / r.chunkSize
+			chunk []byte
+		)
+
+		if chunk, err = r.cachedChunk(idx); err != nil {
+			return n, err
+		}
+
+		n += copy(p[n:], chunk[pos-idx*r.chunkSize:])
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// Verify decrypts all chunks of the stream and returns the first
+// integrity error encountered
+func (r *readerAtV2) Verify() error {
+	for idx := int64(0); idx < r.chunks; idx++ {
+		if _, err := r.openChunk(idx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *readerAtV2) cachedChunk(idx int64) ([]byte, error) {
+	r.cacheLock.Lock()
+	if r.cacheChunk == idx {
+		defer r.cacheLock.Unlock()
+		return r.cacheData, nil
+	}
+	r.cacheLock.Unlock()
+
+	data, err := r.openChunk(idx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The cached data is never modified, therefore it is safe to
+	// replace it while others are still reading the old data
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+	r.cacheChunk, r.cacheData = idx, data
+
+	return data, nil
+}
+
+func (r *readerAtV2) openChunk(idx int64) ([]byte, error) {
+	var (
+		start = int64(len(r.header)) + idx*r.sealedSize
+		buf   = make([]byte, min(r.sealedSize, r.encEnd-start))
+	)
+
+	n, err := r.next.ReadAt(buf, start)
+	if err != nil && (!errors.Is(err, io.EOF) || n < len(buf)) {
+		return nil, errors.Wrapf(err, "reading chunk %d", idx)
+	}
+
+	var nonceFlags byte
+	if idx == r.chunks-1 {
+		nonceFlags = nonceFinalFlag
+	}
+
+	data, err := r.aead.Open(buf[:0], chunkNonce(idx, nonceFlags), buf, r.header)
+	if err != nil {
+		return nil, IntegrityError{Chunk: idx, Offset: idx * r.chunkSize}
+	}
+
+	return data, nil
+}
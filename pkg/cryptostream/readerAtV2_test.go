@@ -0,0 +1,148 @@
+package cryptostream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChunkSize = 64
+
+var testPass = []byte("password")
+
+func TestReaderAtV2(t *testing.T) {
+	rawData, testData := getTestStreamV2(t)
+
+	t.Run("detect format", func(t *testing.T) {
+		r := openTestStream(t, testData)
+		assert.Equal(t, 2, r.Version())
+		assert.Equal(t, int64(len(rawData)), r.Size())
+		assert.NoError(t, r.Verify())
+	})
+
+	t.Run("read everything", func(t *testing.T) {
+		r := openTestStream(t, testData)
+
+		vData, err := io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+		require.NoError(t, err)
+
+		assert.Equal(t, rawData, vData)
+	})
+
+	t.Run("read across chunks", func(t *testing.T) {
+		r := openTestStream(t, testData)
+
+		vData := make([]byte, 2*testChunkSize)
+		n, err := r.ReadAt(vData, 34)
+		require.NoError(t, err)
+		assert.Equal(t, len(vData), n)
+
+		assert.Equal(t, rawData[34:34+2*testChunkSize], vData)
+	})
+
+	t.Run("read beyond end", func(t *testing.T) {
+		r := openTestStream(t, testData)
+
+		vData := make([]byte, 16)
+		n, err := r.ReadAt(vData, r.Size()-6)
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, 6, n)
+
+		assert.Equal(t, rawData[len(rawData)-6:], vData[:n])
+	})
+
+	t.Run("read v1 stream", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		w, err := NewWriterV1(buf, testPass)
+		require.NoError(t, err)
+		_, err = w.Write(rawData)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		r := openTestStream(t, buf.Bytes())
+		assert.Equal(t, 1, r.Version())
+		assert.Equal(t, int64(len(rawData)), r.Size())
+		assert.NoError(t, r.Verify())
+
+		vData, err := io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+		require.NoError(t, err)
+		assert.Equal(t, rawData, vData)
+	})
+
+	t.Run("deprecated reader rejects v2 stream", func(t *testing.T) {
+		_, err := NewReaderAt(bytes.NewReader(testData), testPass)
+		assert.Error(t, err)
+	})
+}
+
+func TestReaderAtV2Integrity(t *testing.T) {
+	_, testData := getTestStreamV2(t)
+
+	t.Run("detect modification", func(t *testing.T) {
+		data := bytes.Clone(testData)
+		data[len(data)-3*testChunkSize] ^= 0x1
+
+		r := openTestStream(t, data)
+
+		_, err := r.ReadAt(make([]byte, 16), 0)
+		assert.NoError(t, err, "first chunk is not affected")
+
+		var intErr IntegrityError
+		_, err = io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+		require.ErrorAs(t, err, &intErr)
+		assert.ErrorIs(t, err, ErrIntegrityCheckFailed)
+		assert.Equal(t, int64(7), intErr.Chunk)
+
+		assert.ErrorIs(t, r.Verify(), ErrIntegrityCheckFailed)
+	})
+
+	t.Run("detect truncation", func(t *testing.T) {
+		// Cut off the last chunk: The stream is still valid in its
+		// structure but the last chunk is not marked as such
+		r := openTestStream(t, testData[:len(testData)-6-16])
+		assert.ErrorIs(t, r.Verify(), ErrIntegrityCheckFailed)
+
+		// Cut into a chunk leaving less than its authentication tag
+		cut := testData[:len(testData)-6-16-70]
+		_, err := NewReaderAtWithKeys(bytes.NewReader(cut), int64(len(cut)), Keys{Passphrase: testPass})
+		assert.Error(t, err, "incomplete chunk")
+	})
+
+	t.Run("detect modified header", func(t *testing.T) {
+		data := bytes.Clone(testData)
+		data[headerV2FixedLen] ^= 0x1 // Modify salt
+
+		assert.ErrorIs(t, openTestStream(t, data).Verify(), ErrIntegrityCheckFailed)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		r, err := NewReaderAtWithKeys(bytes.NewReader(testData), int64(len(testData)), Keys{Passphrase: []byte("wrong")})
+		require.NoError(t, err)
+		assert.ErrorIs(t, r.Verify(), ErrIntegrityCheckFailed)
+	})
+}
+
+func getTestStreamV2(t *testing.T) (rawData, encData []byte) {
+	rawData = make([]byte, 10*testChunkSize+6)
+	_, err := rand.Read(rawData)
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	w, err := newPassphraseWriter(buf, testPass, testChunkSize)
+	require.NoError(t, err)
+	_, err = w.Write(rawData)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return rawData, buf.Bytes()
+}
+
+func openTestStream(t *testing.T, data []byte) *CryptoReaderAt {
+	r, err := NewReaderAtWithKeys(bytes.NewReader(data), int64(len(data)), Keys{Passphrase: testPass})
+	require.NoError(t, err)
+	return r
+}
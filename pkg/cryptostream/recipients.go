@@ -0,0 +1,242 @@
+package cryptostream
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Streams encrypted to recipients use a random file key which is
+// wrapped for every recipient: An ephemeral X25519 key is generated,
+// the wrapping key is derived from the shared secret with the
+// recipient using HKDF-SHA256 and the file key is sealed using
+// AES-256-GCM. The key block of the header contains one stanza per
+// recipient:
+//
+//	ephemeral public key  32 byte
+//	wrapped file key      48 byte (32 byte key + 16 byte tag)
+const (
+	identityPrefix  = "DBC-X25519-IDENTITY:"
+	recipientPrefix = "dbc-x25519:"
+
+	x25519KeyLen       = 32
+	recipientStanzaLen = x25519KeyLen + cryptoKeyLen + 16
+	maxRecipients      = 0xffff / recipientStanzaLen
+	wrapKeyInfo        = "db-backup-controller/cryptostream/x25519"
+)
+
+type (
+	// Identity is a X25519 private key able to decrypt streams which
+	// were encrypted to its Recipient
+	Identity struct {
+		key *ecdh.PrivateKey
+	}
+
+	// Recipient is a X25519 public key streams can be encrypted to
+	// without having access to the Identity required to decrypt them
+	Recipient struct {
+		key *ecdh.PublicKey
+	}
+)
+
+// GenerateIdentity creates a new random Identity
+func GenerateIdentity() (Identity, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return Identity{}, errors.Wrap(err, "generating key")
+	}
+
+	return Identity{key: key}, nil
+}
+
+// ParseIdentities reads identities from the given reader, one per
+// line. Empty lines and lines starting with a # are ignored.
+func ParseIdentities(r io.Reader) ([]Identity, error) {
+	var (
+		ids     []Identity
+		scanner = bufio.NewScanner(r)
+	)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		id, err := ParseIdentity(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing identity %d", len(ids)+1)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading identities")
+	}
+
+	if len(ids) == 0 {
+		return nil, errors.New("no identities found")
+	}
+
+	return ids, nil
+}
+
+// ParseIdentity parses an identity in the format produced by
+// Identity.String
+func ParseIdentity(s string) (Identity, error) {
+	raw, err := decodeKey(s, identityPrefix)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	key, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return Identity{}, errors.Wrap(err, "parsing private key")
+	}
+
+	return Identity{key: key}, nil
+}
+
+// ParseRecipient parses a recipient in the format produced by
+// Recipient.String
+func ParseRecipient(s string) (Recipient, error) {
+	raw, err := decodeKey(s, recipientPrefix)
+	if err != nil {
+		return Recipient{}, err
+	}
+
+	key, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return Recipient{}, errors.Wrap(err, "parsing public key")
+	}
+
+	return Recipient{key: key}, nil
+}
+
+// Recipient returns the public key belonging to the Identity
+func (i Identity) Recipient() Recipient {
+	return Recipient{key: i.key.PublicKey()}
+}
+
+// String encodes the Identity into its text representation
+func (i Identity) String() string {
+	return identityPrefix + base64.StdEncoding.EncodeToString(i.key.Bytes())
+}
+
+// String encodes the Recipient into its text representation
+func (r Recipient) String() string {
+	return recipientPrefix + base64.StdEncoding.EncodeToString(r.key.Bytes())
+}
+
+func decodeKey(s, prefix string) ([]byte, error) {
+	if !strings.HasPrefix(s, prefix) {
+		return nil, errors.Errorf("key does not start with %q", prefix)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, prefix))
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding key")
+	}
+
+	if len(raw) != x25519KeyLen {
+		return nil, errors.Errorf("invalid key length %d", len(raw))
+	}
+
+	return raw, nil
+}
+
+// newWrapAEAD derives the key wrapping the file key from the shared
+// secret of the ephemeral key and the recipient. As every wrapping
+// key is only used once a static nonce is used for sealing.
+func newWrapAEAD(shared, ephemeralPub, recipientPub []byte) (cipher.AEAD, error) {
+	wrapKey := make([]byte, cryptoKeyLen)
+	kdf := hkdf.New(sha256.New, shared, append(append([]byte{}, ephemeralPub...), recipientPub...), []byte(wrapKeyInfo))
+	if _, err := io.ReadFull(kdf, wrapKey); err != nil {
+		return nil, errors.Wrap(err, "deriving wrap key")
+	}
+
+	block, err := aes.NewCipher(wrapKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating cipher")
+	}
+
+	aead, err := cipher.NewGCM(block)
+	return aead, errors.Wrap(err, "creating GCM")
+}
+
+func unwrapFileKey(keyBlock []byte, identities []Identity) ([]byte, error) {
+	if len(keyBlock) == 0 || len(keyBlock)%recipientStanzaLen != 0 {
+		return nil, errors.Errorf("invalid key block length %d", len(keyBlock))
+	}
+
+	for pos := 0; pos < len(keyBlock); pos += recipientStanzaLen {
+		var (
+			stanza       = keyBlock[pos : pos+recipientStanzaLen]
+			ephemeralPub = stanza[:x25519KeyLen]
+			wrapped      = stanza[x25519KeyLen:]
+		)
+
+		ephemeral, err := ecdh.X25519().NewPublicKey(ephemeralPub)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing ephemeral key")
+		}
+
+		for _, id := range identities {
+			shared, err := id.key.ECDH(ephemeral)
+			if err != nil {
+				// Low-order point, this stanza cannot belong to anyone
+				break
+			}
+
+			aead, err := newWrapAEAD(shared, ephemeralPub, id.key.PublicKey().Bytes())
+			if err != nil {
+				return nil, err
+			}
+
+			if fileKey, err := aead.Open(nil, make([]byte, aead.NonceSize()), wrapped, nil); err == nil {
+				return fileKey, nil
+			}
+		}
+	}
+
+	return nil, errors.New("none of the identities is a recipient of the stream")
+}
+
+func wrapFileKey(fileKey []byte, recipients []Recipient) ([]byte, error) {
+	if len(recipients) == 0 || len(recipients) > maxRecipients {
+		return nil, errors.Errorf("invalid number of recipients %d", len(recipients))
+	}
+
+	keyBlock := make([]byte, 0, len(recipients)*recipientStanzaLen)
+	for _, r := range recipients {
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "generating ephemeral key")
+		}
+
+		shared, err := ephemeral.ECDH(r.key)
+		if err != nil {
+			return nil, errors.Wrap(err, "computing shared secret")
+		}
+
+		aead, err := newWrapAEAD(shared, ephemeral.PublicKey().Bytes(), r.key.Bytes())
+		if err != nil {
+			return nil, err
+		}
+
+		keyBlock = append(keyBlock, ephemeral.PublicKey().Bytes()...)
+		keyBlock = aead.Seal(keyBlock, make([]byte, aead.NonceSize()), fileKey, nil)
+	}
+
+	return keyBlock, nil
+}
@@ -0,0 +1,89 @@
+package cryptostream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityEncoding(t *testing.T) {
+	id, err := GenerateIdentity()
+	require.NoError(t, err)
+
+	pid, err := ParseIdentity(id.String())
+	require.NoError(t, err)
+	assert.True(t, id.key.Equal(pid.key))
+
+	rcpt, err := ParseRecipient(id.Recipient().String())
+	require.NoError(t, err)
+	assert.True(t, id.key.PublicKey().Equal(rcpt.key))
+
+	_, err = ParseRecipient(id.String())
+	assert.Error(t, err, "identity is no recipient")
+
+	_, err = ParseIdentity(identityPrefix + "AAAA")
+	assert.Error(t, err, "invalid key length")
+
+	ids, err := ParseIdentities(strings.NewReader(strings.Join([]string{
+		"# created: today",
+		"# public key: " + id.Recipient().String(),
+		id.String(),
+		"",
+	}, "\n")))
+	require.NoError(t, err)
+	assert.Len(t, ids, 1)
+
+	_, err = ParseIdentities(strings.NewReader("# nothing here\n"))
+	assert.Error(t, err, "no identities")
+}
+
+func TestRecipientStream(t *testing.T) {
+	var (
+		ids  = make([]Identity, 3)
+		err  error
+		rcpt []Recipient
+	)
+
+	for i := range ids {
+		ids[i], err = GenerateIdentity()
+		require.NoError(t, err)
+	}
+	rcpt = []Recipient{ids[0].Recipient(), ids[1].Recipient()}
+
+	rawData := make([]byte, 10*testChunkSize+6)
+	_, err = rand.Read(rawData)
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	w, err := newWriterForRecipients(buf, rcpt, testChunkSize)
+	require.NoError(t, err)
+	_, err = w.Write(rawData)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	testData := buf.Bytes()
+
+	for i, id := range ids[:2] {
+		r, err := NewReaderAtWithKeys(bytes.NewReader(testData), int64(len(testData)), Keys{Identities: []Identity{ids[2], id}})
+		require.NoError(t, err, "recipient %d", i)
+		assert.NoError(t, r.Verify())
+
+		vData, err := io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+		require.NoError(t, err)
+		assert.Equal(t, rawData, vData)
+	}
+
+	_, err = NewReaderAtWithKeys(bytes.NewReader(testData), int64(len(testData)), Keys{Identities: ids[2:]})
+	assert.Error(t, err, "not a recipient")
+
+	_, err = NewReaderAtWithKeys(bytes.NewReader(testData), int64(len(testData)), Keys{Passphrase: testPass})
+	assert.Error(t, err, "passphrase given for recipient stream")
+
+	_, err = newWriterForRecipients(new(bytes.Buffer), nil, testChunkSize)
+	assert.Error(t, err, "no recipients")
+}
@@ -9,9 +9,11 @@ import (
 )
 
 type (
-	// CryptoWriteCloser implements an io.Writer following the
-	// specification of this package onto a given io.Writer
-	CryptoWriteCloser struct {
+	// CryptoWriteCloserV1 implements an io.Writer following the v1
+	// specification of this package onto a given io.Writer. The v1
+	// format does not provide integrity protection, new streams should
+	// be written using the CryptoWriteCloserV2.
+	CryptoWriteCloserV1 struct {
 		aes *aesctrat.AesCtr
 		iv  []byte
 
@@ -19,13 +21,27 @@ type (
 		blocksWritten int
 		buf           []byte
 	}
+
+	// CryptoWriteCloser is the v1 writer under its former name
+	//
+	// Deprecated: Use CryptoWriteCloserV2 to write streams with
+	// integrity protection.
+	CryptoWriteCloser = CryptoWriteCloserV1
 )
 
-var _ io.WriteCloser = (*CryptoWriteCloser)(nil)
+var _ io.WriteCloser = (*CryptoWriteCloserV1)(nil)
 
-// NewWriter creates a new CryptoWriteCloser, gets a random salt
-// and writes the header to the underlying writer
+// NewWriter creates a new CryptoWriteCloser writing the v1 format
+//
+// Deprecated: Use NewWriterV2 or NewRecipientWriter to write streams
+// with integrity protection.
 func NewWriter(next io.Writer, pass []byte) (*CryptoWriteCloser, error) {
+	return NewWriterV1(next, pass)
+}
+
+// NewWriterV1 creates a new CryptoWriteCloserV1, gets a random salt
+// and writes the header to the underlying writer
+func NewWriterV1(next io.Writer, pass []byte) (*CryptoWriteCloserV1, error) {
 	// Put together our IV / Key from a salted pass
 	salt, err := getRandomSalt()
 	if err != nil {
@@ -38,7 +54,7 @@ func NewWriter(next io.Writer, pass []byte) (*CryptoWriteCloser, error) {
 	}
 
 	// Build our writer
-	cw := &CryptoWriteCloser{
+	cw := &CryptoWriteCloserV1{
 		aes: aesctrat.NewAesCtr(key),
 		iv:  iv,
 
@@ -62,7 +78,7 @@ func NewWriter(next io.Writer, pass []byte) (*CryptoWriteCloser, error) {
 // all writes are finished as the Write method might NOT have written
 // all data to the underlying writer. This method ensures the data is
 // fully and properly written
-func (c *CryptoWriteCloser) Close() error {
+func (c *CryptoWriteCloserV1) Close() error {
 	if len(c.buf) == 0 {
 		// Nice! No remains, no issue!
 		return nil
@@ -84,7 +100,7 @@ func (c *CryptoWriteCloser) Close() error {
 // Write implements the io.Writer interface. See Close for hints how
 // to properly write all data to the underlying writer. This method
 // contains a buffer which buffers up to 16 bytes.
-func (c *CryptoWriteCloser) Write(p []byte) (n int, err error) {
+func (c *CryptoWriteCloserV1) Write(p []byte) (n int, err error) {
 	data := append(c.buf, p...) //nolint:gocritic // This intentionally does NOT use the same slice
 
 	// Get fully available blocks to write
@@ -0,0 +1,149 @@
+package cryptostream
+
+import (
+	"crypto/cipher"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// CryptoWriteCloserV2 implements an io.Writer following the v2
+	// specification of this package onto a given io.Writer
+	CryptoWriteCloserV2 struct {
+		aead   cipher.AEAD
+		header []byte
+
+		next      io.Writer
+		chunkSize int
+		chunks    int64
+		buf       []byte
+		sealBuf   []byte
+		closed    bool
+	}
+)
+
+var _ io.WriteCloser = (*CryptoWriteCloserV2)(nil)
+
+// NewWriterV2 creates a new CryptoWriteCloserV2, gets a random salt
+// and writes the header to the underlying writer
+func NewWriterV2(next io.Writer, pass []byte) (*CryptoWriteCloserV2, error) {
+	return newPassphraseWriter(next, pass, defaultChunkSize)
+}
+
+// NewRecipientWriter creates a new CryptoWriteCloserV2 encrypting the
+// stream using a random key, which is wrapped for each of the given
+// recipients, and writes the header to the underlying writer. The
+// stream can only be decrypted by the Identity of a recipient.
+func NewRecipientWriter(next io.Writer, recipients []Recipient) (*CryptoWriteCloserV2, error) {
+	return newWriterForRecipients(next, recipients, defaultChunkSize)
+}
+
+func newPassphraseWriter(next io.Writer, pass []byte, chunkSize int) (*CryptoWriteCloserV2, error) {
+	salt, err := getRandomBytes(saltLengthV2)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting random salt")
+	}
+
+	key, err := deriveKey(pass, salt)
+	if err != nil {
+		return nil, errors.Wrap(err, "deriving key")
+	}
+
+	return newWriterV2(next, key, keyTypePassphrase, salt, chunkSize)
+}
+
+func newWriterForRecipients(next io.Writer, recipients []Recipient, chunkSize int) (*CryptoWriteCloserV2, error) {
+	key, err := getRandomBytes(cryptoKeyLen)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting random key")
+	}
+
+	keyBlock, err := wrapFileKey(key, recipients)
+	if err != nil {
+		return nil, errors.Wrap(err, "wrapping key for recipients")
+	}
+
+	return newWriterV2(next, key, keyTypeRecipients, keyBlock, chunkSize)
+}
+
+func newWriterV2(next io.Writer, key []byte, keyType byte, keyBlock []byte, chunkSize int) (*CryptoWriteCloserV2, error) {
+	aead, err := newChunkAEAD(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating AEAD")
+	}
+
+	cw := &CryptoWriteCloserV2{
+		aead:   aead,
+		header: buildHeaderV2(chunkSize, keyType, keyBlock),
+
+		next:      next,
+		chunkSize: chunkSize,
+		buf:       make([]byte, 0, chunkSize),
+		sealBuf:   make([]byte, 0, chunkSize+aead.Overhead()),
+	}
+
+	n, err := next.Write(cw.header)
+	if err != nil {
+		return nil, errors.Wrap(err, "writing header")
+	}
+	if n != len(cw.header) {
+		return nil, errors.Errorf("wrote only %d / %d header bytes", n, len(cw.header))
+	}
+
+	return cw, nil
+}
+
+// Close implements the io.Closer interface and MUST be called after
+// all writes are finished as the last chunk is only written on Close.
+// Without it the stream is detected as truncated when reading it.
+func (c *CryptoWriteCloserV2) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	return c.writeChunk(c.buf, nonceFinalFlag)
+}
+
+// Write implements the io.Writer interface. See Close for hints how
+// to properly write all data to the underlying writer. This method
+// buffers up to one chunk of data as the last chunk needs to be
+// written differently.
+func (c *CryptoWriteCloserV2) Write(p []byte) (n int, err error) {
+	if c.closed {
+		return 0, errors.New("write to closed writer")
+	}
+
+	for len(p) > 0 {
+		if len(c.buf) == c.chunkSize {
+			// There is more data so the buffered chunk is not the last one
+			if err = c.writeChunk(c.buf, 0); err != nil {
+				return n, err
+			}
+			c.buf = c.buf[:0]
+		}
+
+		copied := min(c.chunkSize-len(c.buf), len(p))
+		c.buf = append(c.buf, p[:copied]...)
+		p = p[copied:]
+		n += copied
+	}
+
+	return n, nil
+}
+
+func (c *CryptoWriteCloserV2) writeChunk(data []byte, nonceFlags byte) error {
+	c.sealBuf = c.aead.Seal(c.sealBuf[:0], chunkNonce(c.chunks, nonceFlags), data, c.header)
+
+	n, err := c.next.Write(c.sealBuf)
+	if err != nil {
+		return errors.Wrap(err, "writing encrypted chunk to underlying writer")
+	}
+	if n != len(c.sealBuf) {
+		return errors.Errorf("incomplete write to underlying writer %d/%d", n, len(c.sealBuf))
+	}
+
+	c.chunks++
+	return nil
+}
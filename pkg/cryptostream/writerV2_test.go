@@ -0,0 +1,72 @@
+package cryptostream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterV2(t *testing.T) {
+	var (
+		encpass   = []byte("password")
+		chunkSize = 64
+		hdrLen    = headerV2FixedLen + saltLengthV2
+		tagLen    = 16
+	)
+
+	t.Run("empty stream", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		w, err := newPassphraseWriter(buf, encpass, chunkSize)
+		require.NoError(t, err)
+
+		assert.Equal(t, hdrLen, buf.Len())
+		assert.Equal(t, headerV2, buf.Bytes()[0:len(headerV2)])
+
+		require.NoError(t, w.Close())
+		assert.Equal(t, hdrLen+tagLen, buf.Len(), "empty final chunk")
+	})
+
+	t.Run("exactly one chunk", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		w, err := newPassphraseWriter(buf, encpass, chunkSize)
+		require.NoError(t, err)
+
+		n, err := w.Write(make([]byte, chunkSize))
+		require.NoError(t, err)
+		assert.Equal(t, chunkSize, n)
+
+		assert.Equal(t, hdrLen, buf.Len(), "last chunk is written on close")
+
+		require.NoError(t, w.Close())
+		assert.Equal(t, hdrLen+chunkSize+tagLen, buf.Len())
+	})
+
+	t.Run("many incomplete writes", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		w, err := newPassphraseWriter(buf, encpass, chunkSize)
+		require.NoError(t, err)
+
+		data := make([]byte, 10*chunkSize+6)
+		_, err = rand.Read(data)
+		require.NoError(t, err)
+
+		var pos int
+		for _, i := range []int{15, 22, 1, 7, 127, 3, 84, 16, 289, 13, 23, 46} {
+			n, err := w.Write(data[pos : pos+i])
+			require.NoError(t, err)
+			assert.Equal(t, i, n)
+			pos += i
+		}
+
+		assert.Equal(t, hdrLen+10*(chunkSize+tagLen), buf.Len())
+
+		require.NoError(t, w.Close())
+		assert.Equal(t, hdrLen+10*(chunkSize+tagLen)+6+tagLen, buf.Len())
+
+		_, err = w.Write([]byte{0x0})
+		assert.Error(t, err, "write after close")
+	})
+}
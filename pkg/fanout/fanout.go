@@ -0,0 +1,192 @@
+// Package fanout implements an io.Writer distributing the written
+// data to multiple targets while isolating the targets from each
+// other: A slow target delays the others once its queue is full, a
+// target failing or not finishing a write within the stall timeout
+// is dropped without affecting the rest
+package fanout
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Writer distributes all data written to it to the targets added
+	// through AddTarget. Targets MUST be added before the first write.
+	Writer struct {
+		queueLength  int
+		stallTimeout time.Duration
+		targets      []*Target
+
+		closeOnce sync.Once
+	}
+
+	// Target represents one of the writers the data is distributed to
+	// and holds the error which caused the target to be dropped
+	Target struct {
+		next         io.Writer
+		stallTimeout time.Duration
+
+		queue    chan []byte
+		failed   chan struct{}
+		finished chan struct{}
+
+		err      error
+		failOnce sync.Once
+	}
+)
+
+var (
+	_ io.WriteCloser = (*Writer)(nil)
+
+	// ErrAllTargetsFailed signalizes all targets were dropped and no
+	// more data can be written to the Writer
+	ErrAllTargetsFailed = errors.New("all targets failed")
+
+	// ErrTargetStalled signalizes the target did not finish a write
+	// within the stall timeout and was dropped
+	ErrTargetStalled = errors.New("target stalled")
+)
+
+// NewWriter creates a new Writer allowing up to queueLength pending
+// writes per target before blocking the writer. Targets not finishing
+// a single write within the stallTimeout are dropped (zero disables
+// the timeout). The write of a dropped target is NOT interrupted, the
+// owner of the target needs to abort it (i.e. close the pipe).
+func NewWriter(queueLength int, stallTimeout time.Duration) *Writer {
+	return &Writer{queueLength: queueLength, stallTimeout: stallTimeout}
+}
+
+// AddTarget registers a new io.Writer to receive all data written
+// to the Writer and starts the routine feeding it
+func (w *Writer) AddTarget(next io.Writer) *Target {
+	t := &Target{
+		next:         next,
+		stallTimeout: w.stallTimeout,
+
+		queue:    make(chan []byte, w.queueLength),
+		failed:   make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	go t.run()
+
+	w.targets = append(w.targets, t)
+	return t
+}
+
+// Close implements the io.Closer interface and MUST be called after
+// all writes are finished: It waits for all targets to receive their
+// pending data or to be dropped. The underlying writers are NOT closed.
+//
+// Returns ErrAllTargetsFailed in case no target received all data.
+func (w *Writer) Close() error {
+	w.closeOnce.Do(func() {
+		for _, t := range w.targets {
+			close(t.queue)
+		}
+	})
+
+	var alive int
+	for _, t := range w.targets {
+		select {
+		case <-t.finished:
+		case <-t.failed:
+			// Target might still be stuck in a write, don't wait for it
+		}
+
+		if t.Err() == nil {
+			alive++
+		}
+	}
+
+	if alive == 0 {
+		return ErrAllTargetsFailed
+	}
+
+	return nil
+}
+
+// Write implements the io.Writer interface and queues a copy of the
+// data for every target not yet failed. It only fails when there is
+// no target left to write to.
+func (w *Writer) Write(p []byte) (n int, err error) {
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	var alive int
+	for _, t := range w.targets {
+		if t.hasFailed() {
+			continue
+		}
+
+		select {
+		case t.queue <- data:
+			alive++
+		case <-t.failed:
+			// Target failed while we were waiting for space in its queue
+		}
+	}
+
+	if alive == 0 {
+		return 0, ErrAllTargetsFailed
+	}
+
+	return len(p), nil
+}
+
+// Err returns the error which caused the target to be dropped or
+// nil in case the target did not fail (yet)
+func (t *Target) Err() error {
+	if !t.hasFailed() {
+		return nil
+	}
+
+	return t.err
+}
+
+func (t *Target) hasFailed() bool {
+	select {
+	case <-t.failed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *Target) fail(err error) {
+	t.failOnce.Do(func() {
+		t.err = err
+		close(t.failed)
+	})
+}
+
+func (t *Target) run() {
+	defer close(t.finished)
+
+	for data := range t.queue {
+		if err := t.write(data); err != nil {
+			t.fail(err)
+		}
+
+		if t.hasFailed() {
+			// Drain the queue so the Writer does not wait for us
+			for range t.queue { //revive:disable-line:empty-block // Intentionally discarding
+			}
+			return
+		}
+	}
+}
+
+func (t *Target) write(data []byte) error {
+	if t.stallTimeout > 0 {
+		stall := time.AfterFunc(t.stallTimeout, func() { t.fail(ErrTargetStalled) })
+		defer stall.Stop()
+	}
+
+	_, err := t.next.Write(data)
+	return errors.Wrap(err, "writing to target")
+}
@@ -0,0 +1,180 @@
+package fanout
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	blockingWriter struct {
+		release chan struct{}
+	}
+
+	failingWriter struct {
+		after   int
+		written int
+	}
+
+	slowWriter struct {
+		buf   bytes.Buffer
+		delay time.Duration
+	}
+)
+
+var errTestWriteFailed = errors.New("write failed")
+
+func (b blockingWriter) Write(p []byte) (int, error) {
+	<-b.release
+	return len(p), nil
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if f.written+len(p) > f.after {
+		return 0, errTestWriteFailed
+	}
+	f.written += len(p)
+	return len(p), nil
+}
+
+func (s *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.buf.Write(p) //nolint:wrapcheck // Test helper
+}
+
+func TestWriterDistributesData(t *testing.T) {
+	var (
+		bufA, bufB = new(bytes.Buffer), new(bytes.Buffer)
+		w          = NewWriter(1, 0)
+		ta         = w.AddTarget(bufA)
+		tb         = w.AddTarget(bufB)
+	)
+
+	data := make([]byte, 10*1024)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+
+	for i := 0; i < len(data); i += 1000 {
+		end := i + 1000
+		if end > len(data) {
+			end = len(data)
+		}
+
+		n, err := w.Write(data[i:end])
+		require.NoError(t, err)
+		assert.Equal(t, end-i, n)
+	}
+
+	require.NoError(t, w.Close())
+
+	assert.NoError(t, ta.Err())
+	assert.NoError(t, tb.Err())
+	assert.Equal(t, data, bufA.Bytes())
+	assert.Equal(t, data, bufB.Bytes())
+}
+
+func TestWriterIsolatesFailingTarget(t *testing.T) {
+	var (
+		buf = new(bytes.Buffer)
+		w   = NewWriter(1, 0)
+		tOK = w.AddTarget(buf)
+		tKO = w.AddTarget(&failingWriter{after: 100})
+	)
+
+	data := bytes.Repeat([]byte("0123456789"), 100)
+
+	for i := 0; i < len(data); i += 10 {
+		_, err := w.Write(data[i : i+10])
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+
+	assert.NoError(t, tOK.Err())
+	assert.ErrorIs(t, tKO.Err(), errTestWriteFailed)
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestWriterDropsStalledTarget(t *testing.T) {
+	var (
+		blocked = blockingWriter{release: make(chan struct{})}
+		buf     = new(bytes.Buffer)
+		w       = NewWriter(1, 50*time.Millisecond)
+		tOK     = w.AddTarget(buf)
+		tKO     = w.AddTarget(blocked)
+	)
+	defer close(blocked.release)
+
+	data := bytes.Repeat([]byte("0123456789"), 100)
+
+	// The blocked target fills its queue within the first writes and
+	// must not keep the data from reaching the other target
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < len(data); i += 10 {
+			_, err := w.Write(data[i : i+10])
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, w.Close())
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writer blocked by stalled target")
+	}
+
+	assert.NoError(t, tOK.Err())
+	assert.ErrorIs(t, tKO.Err(), ErrTargetStalled)
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestWriterReusedBufferDoesNotCorruptQueue(t *testing.T) {
+	var (
+		slow = &slowWriter{delay: time.Millisecond}
+		w    = NewWriter(16, 0)
+		tgt  = w.AddTarget(slow)
+
+		expect = new(bytes.Buffer)
+		chunk  = make([]byte, 16)
+	)
+
+	// Writers like io.Copy reuse their buffer: Queued data must not
+	// be modified by the next write
+	for i := 0; i < 32; i++ {
+		_, err := rand.Read(chunk)
+		require.NoError(t, err)
+		_, err = expect.Write(chunk)
+		require.NoError(t, err)
+
+		_, err = w.Write(chunk)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+	assert.NoError(t, tgt.Err())
+	assert.Equal(t, expect.Bytes(), slow.buf.Bytes())
+}
+
+func TestWriterAllTargetsFailed(t *testing.T) {
+	w := NewWriter(1, 0)
+	tgt := w.AddTarget(&failingWriter{})
+
+	// The first write is queued, the failure is detected asynchronously
+	// and reported on the next write
+	_, err := w.Write([]byte("data"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return tgt.Err() != nil }, time.Second, time.Millisecond)
+
+	_, err = w.Write([]byte("data"))
+	assert.ErrorIs(t, err, ErrAllTargetsFailed)
+	assert.ErrorIs(t, w.Close(), ErrAllTargetsFailed)
+	assert.ErrorIs(t, tgt.Err(), errTestWriteFailed)
+}
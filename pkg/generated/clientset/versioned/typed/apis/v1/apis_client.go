@@ -13,7 +13,9 @@ import (
 type BackupV1Interface interface {
 	RESTClient() rest.Interface
 	DatabaseBackupsGetter
+	DatabaseBackupRunsGetter
 	DatabaseBackupStorageClassesGetter
+	DatabaseRestoresGetter
 }
 
 // BackupV1Client is used to interact with features provided by the backup.nect.com group.
@@ -25,10 +27,18 @@ func (c *BackupV1Client) DatabaseBackups(namespace string) DatabaseBackupInterfa
 	return newDatabaseBackups(c, namespace)
 }
 
+func (c *BackupV1Client) DatabaseBackupRuns(namespace string) DatabaseBackupRunInterface {
+	return newDatabaseBackupRuns(c, namespace)
+}
+
 func (c *BackupV1Client) DatabaseBackupStorageClasses() DatabaseBackupStorageClassInterface {
 	return newDatabaseBackupStorageClasses(c)
 }
 
+func (c *BackupV1Client) DatabaseRestores(namespace string) DatabaseRestoreInterface {
+	return newDatabaseRestores(c, namespace)
+}
+
 // NewForConfig creates a new BackupV1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
@@ -16,10 +16,18 @@ func (c *FakeBackupV1) DatabaseBackups(namespace string) v1.DatabaseBackupInterf
 	return &FakeDatabaseBackups{c, namespace}
 }
 
+func (c *FakeBackupV1) DatabaseBackupRuns(namespace string) v1.DatabaseBackupRunInterface {
+	return &FakeDatabaseBackupRuns{c, namespace}
+}
+
 func (c *FakeBackupV1) DatabaseBackupStorageClasses() v1.DatabaseBackupStorageClassInterface {
 	return &FakeDatabaseBackupStorageClasses{c}
 }
 
+func (c *FakeBackupV1) DatabaseRestores(namespace string) v1.DatabaseRestoreInterface {
+	return &FakeDatabaseRestores{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeBackupV1) RESTClient() rest.Interface {
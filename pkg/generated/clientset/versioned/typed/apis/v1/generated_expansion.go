@@ -4,4 +4,8 @@ package v1
 
 type DatabaseBackupExpansion interface{}
 
+type DatabaseBackupRunExpansion interface{}
+
 type DatabaseBackupStorageClassExpansion interface{}
+
+type DatabaseRestoreExpansion interface{}
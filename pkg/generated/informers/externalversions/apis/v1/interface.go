@@ -10,8 +10,12 @@ import (
 type Interface interface {
 	// DatabaseBackups returns a DatabaseBackupInformer.
 	DatabaseBackups() DatabaseBackupInformer
+	// DatabaseBackupRuns returns a DatabaseBackupRunInformer.
+	DatabaseBackupRuns() DatabaseBackupRunInformer
 	// DatabaseBackupStorageClasses returns a DatabaseBackupStorageClassInformer.
 	DatabaseBackupStorageClasses() DatabaseBackupStorageClassInformer
+	// DatabaseRestores returns a DatabaseRestoreInformer.
+	DatabaseRestores() DatabaseRestoreInformer
 }
 
 type version struct {
@@ -30,7 +34,17 @@ func (v *version) DatabaseBackups() DatabaseBackupInformer {
 	return &databaseBackupInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// DatabaseBackupRuns returns a DatabaseBackupRunInformer.
+func (v *version) DatabaseBackupRuns() DatabaseBackupRunInformer {
+	return &databaseBackupRunInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // DatabaseBackupStorageClasses returns a DatabaseBackupStorageClassInformer.
 func (v *version) DatabaseBackupStorageClasses() DatabaseBackupStorageClassInformer {
 	return &databaseBackupStorageClassInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
+
+// DatabaseRestores returns a DatabaseRestoreInformer.
+func (v *version) DatabaseRestores() DatabaseRestoreInformer {
+	return &databaseRestoreInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
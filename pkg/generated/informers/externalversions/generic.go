@@ -39,8 +39,12 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=backup.nect.com, Version=v1
 	case v1.SchemeGroupVersion.WithResource("databasebackups"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Backup().V1().DatabaseBackups().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("databasebackupruns"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Backup().V1().DatabaseBackupRuns().Informer()}, nil
 	case v1.SchemeGroupVersion.WithResource("databasebackupstorageclasses"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Backup().V1().DatabaseBackupStorageClasses().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("databaserestores"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Backup().V1().DatabaseRestores().Informer()}, nil
 
 	}
 
@@ -10,6 +10,22 @@ type DatabaseBackupListerExpansion interface{}
 // DatabaseBackupNamespaceLister.
 type DatabaseBackupNamespaceListerExpansion interface{}
 
+// DatabaseBackupRunListerExpansion allows custom methods to be added to
+// DatabaseBackupRunLister.
+type DatabaseBackupRunListerExpansion interface{}
+
+// DatabaseBackupRunNamespaceListerExpansion allows custom methods to be added to
+// DatabaseBackupRunNamespaceLister.
+type DatabaseBackupRunNamespaceListerExpansion interface{}
+
 // DatabaseBackupStorageClassListerExpansion allows custom methods to be added to
 // DatabaseBackupStorageClassLister.
 type DatabaseBackupStorageClassListerExpansion interface{}
+
+// DatabaseRestoreListerExpansion allows custom methods to be added to
+// DatabaseRestoreLister.
+type DatabaseRestoreListerExpansion interface{}
+
+// DatabaseRestoreNamespaceListerExpansion allows custom methods to be added to
+// DatabaseRestoreNamespaceLister.
+type DatabaseRestoreNamespaceListerExpansion interface{}
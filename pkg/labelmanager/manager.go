@@ -14,7 +14,7 @@ type (
 	// Manager is a helper to organize and book-keep the entries based
 	// on a Grandfather-Father-Son principle
 	Manager struct {
-		retention RetentionConfig
+		policy RetentionPolicy
 
 		store *retentionStore
 	}
@@ -30,11 +30,11 @@ var (
 )
 
 // New creates a new Manager configured with the baseDir and the
-// RetentionConfig defining how long to keep backups
+// RetentionPolicy defining how long to keep backups
 //
 // if labelStorage is nil, an empty manager will be initialized
-// if retention is nil, the DefaultRetentionConfig will be used
-func New(labelStorage io.Reader, retention RetentionConfig) (*Manager, error) {
+// if the policy has no Durations, the DefaultRetentionConfig will be used
+func New(labelStorage io.Reader, policy RetentionPolicy) (*Manager, error) {
 	store := newRetentionStore()
 	if labelStorage != nil {
 		if err := store.Load(labelStorage); err != nil {
@@ -42,32 +42,43 @@ func New(labelStorage io.Reader, retention RetentionConfig) (*Manager, error) {
 		}
 	}
 
-	if retention == nil {
-		retention = DefaultRetentionConfig
+	if policy.Durations == nil {
+		policy.Durations = DefaultRetentionConfig
 	}
 
 	return &Manager{
-		retention: retention,
+		policy: policy,
 
 		store: store,
 	}, nil
 }
 
 // Add adds a new backup to the manager. When adding it is assigned
-// labels defined by the RetentionConfig in case they are not already
+// labels defined by the RetentionPolicy in case they are not already
 // assigned to any other backup. This ensures the generations are
-// kept for as long as the RetentionConfig defines
+// kept for as long as the RetentionPolicy defines
 //
 // Returns ErrNoLabelsAdded in case all possible labels were already
 // set. In this case the entry is not added to the Manager / store.
 func (m Manager) Add(entryName string) error {
+	return m.AddAt(entryName, time.Now())
+}
+
+// AddAt adds a backup created at the given time to the manager. See
+// Add for details about the label assignment. Adding an entry already
+// known (i.e. picked up by rebuilding the labels) is a no-op.
+func (m Manager) AddAt(entryName string, createdAt time.Time) error {
+	if m.store.IsEntryKnown(entryName) {
+		return nil
+	}
+
 	var addedLabels int
 
-	for format, retainFor := range m.retention {
+	for format, retainFor := range m.policy.formats() {
 		if err := m.store.AddEntry(entryName, retentionStoreEntry{
 			Format:          format,
 			InitialHoldTime: retainFor,
-			Name:            timefmt.Format(time.Now(), format),
+			Name:            timefmt.Format(createdAt, format),
 		}); !errors.Is(err, errDuplicateLabel) {
 			addedLabels++
 		}
@@ -81,9 +92,10 @@ func (m Manager) Add(entryName string) error {
 }
 
 // CleanRetentions iterates all labels present and removes labels
-// no longer covered by their retention duration
+// no longer covered by their retention duration unless they are kept
+// by the count rules or belong to the MinKeep most recent entries
 func (m Manager) CleanRetentions() {
-	m.store.CleanupLabels(m.retention)
+	m.store.CleanupLabels(m.policy)
 }
 
 // GetClosestOlderBackup retrieves the backup closest to the given
@@ -99,6 +111,12 @@ func (m Manager) GetClosestOlderBackup(pointInTime time.Time) (string, error) {
 	return backup, nil
 }
 
+// GetManifest returns the name of the manifest describing the entry
+// if one is referenced
+func (m Manager) GetManifest(entryName string) (string, bool) {
+	return m.store.EntryManifest(entryName)
+}
+
 // GetRetainedEntries lists all entries which match IsRetained
 func (m Manager) GetRetainedEntries() []string {
 	return m.store.ListRetainedEntries()
@@ -122,12 +140,28 @@ func (m Manager) IsRetained(entryName string) bool {
 	return m.store.IsEntryRetained(entryName)
 }
 
+// RetainedUntil returns the point in time the longest retention label
+// of the entry expires at. The zero time is returned for entries
+// without labels. Labels kept by count or by MinKeep are not taken
+// into account as their expiry depends on future backups.
+func (m Manager) RetainedUntil(entryName string) time.Time {
+	return m.store.EntryRetainedUntil(entryName, m.policy.Durations)
+}
+
 // Remove removes an entry from the Manager causing IsKnown and
 // IsRetained will return false afterwards
 func (m Manager) Remove(entryName string) {
 	m.store.Remove(entryName)
 }
 
+// SetManifest references the manifest describing the entry by its
+// name, it is removed together with the entry by Cleanup
+//
+// Returns ErrEntryNotFound in case the entry is not known
+func (m Manager) SetManifest(entryName, manifestName string) error {
+	return m.store.SetManifest(entryName, manifestName)
+}
+
 // Save stores the retention data to the given writer. You should
 // take care this is an atomic write by writing into temp location
 // and moving afterwards
@@ -0,0 +1,92 @@
+package labelmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetainedUntil(t *testing.T) {
+	m, err := New(nil, RetentionPolicy{Durations: RetentionConfig{
+		"%Y-%m-%d":          48 * time.Hour,
+		"%Y-%m-%dT%H-%M-%S": time.Hour,
+	}})
+	require.NoError(t, err)
+
+	assert.True(t, m.RetainedUntil("unknown").IsZero())
+
+	require.NoError(t, m.Add("backup1"))
+	until := m.RetainedUntil("backup1")
+
+	// The daily label starts at midnight and is the longest one
+	day, err := time.ParseInLocation("2006-01-02", time.Now().Format("2006-01-02"), time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, day.Add(48*time.Hour), until)
+
+	// Changing the config changes the retention of existing labels
+	m.policy.Durations["%Y-%m-%d"] = 72 * time.Hour
+	assert.Equal(t, day.Add(72*time.Hour), m.RetainedUntil("backup1"))
+}
+
+func TestCleanRetentionsKeepsByCount(t *testing.T) {
+	m, err := New(nil, RetentionPolicy{
+		Durations: RetentionConfig{"%Y-%m-%dT%H": time.Hour},
+		// Daily labels are only kept by count
+		Counts: RetentionCounts{"%Y-%m-%dT%H": 2, "%Y-%m-%d": 1},
+	})
+	require.NoError(t, err)
+
+	// No backups were created for a week
+	var entries []string
+	for day := 10; day > 7; day-- {
+		createdAt := time.Now().UTC().AddDate(0, 0, -day)
+		entries = append(entries, createdAt.Format(EntryNameFormat))
+		require.NoError(t, m.AddAt(entries[len(entries)-1], createdAt))
+	}
+
+	m.CleanRetentions()
+
+	assert.False(t, m.IsRetained(entries[0]))
+	assert.True(t, m.IsRetained(entries[1]))
+	assert.True(t, m.IsRetained(entries[2]))
+
+	// The daily label of the newest entry is kept, the others expired
+	assert.Len(t, m.store.EntryLabels(entries[1]), 1)
+	assert.Len(t, m.store.EntryLabels(entries[2]), 2)
+}
+
+func TestCleanRetentionsKeepsMinimum(t *testing.T) {
+	m, err := New(nil, RetentionPolicy{
+		Durations: RetentionConfig{"%Y-%m-%dT%H-%M-%S": time.Hour},
+		MinKeep:   2,
+	})
+	require.NoError(t, err)
+
+	var entries []string
+	for day := 10; day > 7; day-- {
+		createdAt := time.Now().UTC().AddDate(0, 0, -day)
+		entries = append(entries, createdAt.Format(EntryNameFormat))
+		require.NoError(t, m.AddAt(entries[len(entries)-1], createdAt))
+	}
+
+	m.CleanRetentions()
+
+	assert.Equal(t, []string{entries[0]}, m.GetUnretainedEntries())
+	assert.ElementsMatch(t, entries[1:], m.GetRetainedEntries())
+
+	// Once a new backup is added the oldest protected one is given up
+	require.NoError(t, m.Add(time.Now().UTC().Format(EntryNameFormat)))
+	m.CleanRetentions()
+
+	assert.False(t, m.IsRetained(entries[1]))
+	assert.True(t, m.IsRetained(entries[2]))
+
+	plans := m.Plan()
+	require.Len(t, plans, 4)
+	assert.False(t, plans[1].Retained)
+	assert.True(t, plans[2].Retained)
+	assert.True(t, plans[2].KeptByMinKeep)
+	assert.True(t, plans[2].Labels[0].Expired)
+}
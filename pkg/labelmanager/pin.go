@@ -0,0 +1,67 @@
+package labelmanager
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Pin protects an entry from being removed regardless of its labels
+	// (i.e. for a legal hold)
+	Pin struct {
+		Reason   string    `json:"reason,omitempty" yaml:"reason,omitempty"`
+		PinnedAt time.Time `json:"pinnedAt" yaml:"pinnedAt"`
+		// Until is the point in time the pin expires at, the zero time
+		// pins the entry until it is unpinned
+		Until time.Time `json:"until" yaml:"until,omitempty"`
+	}
+)
+
+var (
+	// ErrEntryNotFound signalizes the entry is not known to the Manager
+	ErrEntryNotFound = errors.New("entry not found")
+	// ErrEntryNotPinned signalizes the entry has no pin to remove
+	ErrEntryNotPinned = errors.New("entry is not pinned")
+)
+
+// IsActive checks whether the pin still protects the entry at the
+// given point in time
+func (p Pin) IsActive(at time.Time) bool {
+	return p.Until.IsZero() || p.Until.After(at)
+}
+
+// GetPin returns the pin of the entry if it has an active one
+func (m Manager) GetPin(entryName string) (Pin, bool) {
+	return m.store.EntryPin(entryName)
+}
+
+// GetPins returns the active pins of all entries
+func (m Manager) GetPins() map[string]Pin {
+	return m.store.ListPins()
+}
+
+// Pin protects the entry from being removed until the given point in
+// time (forever for the zero time) regardless of its labels. Pinning a
+// pinned entry replaces its pin.
+//
+// Returns ErrEntryNotFound in case the entry is not known
+func (m Manager) Pin(entryName, reason string, until time.Time) error {
+	if !until.IsZero() && !until.After(time.Now()) {
+		return errors.New("pin expiry must be in the future")
+	}
+
+	return m.store.SetPin(entryName, Pin{
+		Reason:   reason,
+		PinnedAt: time.Now().UTC(),
+		Until:    until,
+	})
+}
+
+// Unpin removes the pin of the entry, the entry is retained by its
+// labels afterwards
+//
+// Returns ErrEntryNotPinned in case the entry has no active pin
+func (m Manager) Unpin(entryName string) error {
+	return m.store.RemovePin(entryName)
+}
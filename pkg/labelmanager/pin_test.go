@@ -0,0 +1,86 @@
+package labelmanager
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPin(t *testing.T) {
+	m, err := New(nil, RetentionPolicy{Durations: RetentionConfig{"%Y-%m-%dT%H-%M-%S": time.Hour}})
+	require.NoError(t, err)
+
+	var (
+		oldEntry   = time.Now().UTC().AddDate(0, 0, -10)
+		pinned     = oldEntry.Format(EntryNameFormat)
+		notPinned  = oldEntry.Add(time.Second).Format(EntryNameFormat)
+		pinExpired = oldEntry.Add(2 * time.Second).Format(EntryNameFormat)
+	)
+
+	for i, entry := range []string{pinned, notPinned, pinExpired} {
+		require.NoError(t, m.AddAt(entry, oldEntry.Add(time.Duration(i)*time.Second)))
+	}
+
+	assert.ErrorIs(t, m.Pin("unknown", "", time.Time{}), ErrEntryNotFound)
+	assert.Error(t, m.Pin(pinned, "", time.Now().Add(-time.Hour)))
+	assert.ErrorIs(t, m.Unpin(pinned), ErrEntryNotPinned)
+
+	require.NoError(t, m.Pin(pinned, "audit", time.Time{}))
+	require.NoError(t, m.Pin(pinExpired, "", time.Now().Add(time.Hour)))
+	m.store.Pins[pinExpired] = Pin{Until: time.Now().Add(-time.Minute)}
+
+	m.CleanRetentions()
+
+	assert.True(t, m.IsRetained(pinned))
+	assert.False(t, m.IsRetained(notPinned))
+	assert.False(t, m.IsRetained(pinExpired))
+	assert.Equal(t, []string{pinned}, m.GetRetainedEntries())
+
+	pin, ok := m.GetPin(pinned)
+	require.True(t, ok)
+	assert.Equal(t, "audit", pin.Reason)
+	assert.Len(t, m.GetPins(), 1)
+
+	// Pinned entries stay available for point-in-time restores
+	backup, err := m.GetClosestOlderBackup(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, pinned, backup)
+
+	// Pins are persisted
+	buf := new(bytes.Buffer)
+	require.NoError(t, m.Save(buf))
+	loaded, err := New(buf, RetentionPolicy{})
+	require.NoError(t, err)
+	assert.True(t, loaded.IsRetained(pinned))
+
+	require.NoError(t, m.Unpin(pinned))
+	assert.False(t, m.IsRetained(pinned))
+}
+
+func TestReindexKeepsPins(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		entry  = time.Now().UTC().AddDate(-1, 0, 0).Format(EntryNameFormat)
+		store  = &memStore{entries: []string{entry}}
+		policy = RetentionPolicy{Durations: RetentionConfig{"%Y-%m-%d": time.Hour}}
+	)
+
+	_, err := Update(ctx, store, policy, func(m *Manager) error { return m.Pin(entry, "audit", time.Time{}) })
+	require.NoError(t, err)
+
+	m, err := Reindex(ctx, store, policy)
+	require.NoError(t, err)
+
+	m.CleanRetentions()
+	assert.True(t, m.IsRetained(entry))
+
+	plans := m.Plan()
+	require.Len(t, plans, 1)
+	require.NotNil(t, plans[0].Pin)
+	assert.Equal(t, "audit", plans[0].Pin.Reason)
+	assert.True(t, plans[0].Retained)
+}
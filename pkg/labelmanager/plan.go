@@ -0,0 +1,119 @@
+package labelmanager
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+type (
+	// EntryPlan explains why and how long an entry is retained
+	EntryPlan struct {
+		Entry  string      `json:"entry"`
+		Labels []LabelPlan `json:"labels"`
+
+		// Retained tells whether the entry survives a cleanup executed
+		// now. RetainedUntil is the point in time the last label of the
+		// entry expires: The first cleanup afterwards removes the entry
+		// unless it is still kept by count or by MinKeep.
+		Retained      bool      `json:"retained"`
+		RetainedUntil time.Time `json:"retainedUntil"`
+
+		// KeptByMinKeep tells the entry is one of the most recent ones
+		// kept regardless of the expiry of its labels
+		KeptByMinKeep bool `json:"keptByMinKeep,omitempty"`
+
+		// Pin is set for entries pinned manually (see Manager.Pin) which
+		// are retained regardless of their labels
+		Pin *Pin `json:"pin,omitempty"`
+	}
+
+	// LabelPlan describes a label held by an entry
+	LabelPlan struct {
+		Name      string    `json:"name"`
+		Format    string    `json:"format"`
+		Expired   bool      `json:"expired"`
+		ExpiresAt time.Time `json:"expiresAt"`
+
+		// KeptByCount tells the label is one of the most recent ones of
+		// its format kept by the count rules regardless of its expiry
+		KeptByCount bool `json:"keptByCount,omitempty"`
+	}
+)
+
+// Plan loads the labels from the store and explains the retention of
+// all entries under the given RetentionPolicy (see Manager.Plan). If
+// relabel is set the labels are re-evaluated before (see
+// Manager.Relabel). The store is not modified.
+func Plan(ctx context.Context, store Store, policy RetentionPolicy, relabel bool) ([]EntryPlan, error) {
+	m, err := Load(ctx, store, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	if relabel {
+		if _, err = m.Relabel(); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.Plan(), nil
+}
+
+// Plan explains for every known entry which labels it holds, when they
+// expire and until when the entry is retained without modifying the
+// Manager
+func (m Manager) Plan() []EntryPlan {
+	var (
+		keptLabels, keptEntries = m.store.Protected(m.policy)
+		now                     = time.Now()
+	)
+
+	plans := make([]EntryPlan, 0)
+	for _, entry := range m.store.ListEntries() {
+		plan := EntryPlan{
+			Entry:         entry,
+			Labels:        make([]LabelPlan, 0),
+			KeptByMinKeep: keptEntries[entry],
+		}
+
+		if pin, ok := m.store.EntryPin(entry); ok {
+			plan.Pin = &pin
+			plan.Retained = true
+		}
+
+		for _, label := range m.store.EntryLabels(entry) {
+			expiry, err := label.expiresAt(m.policy.Durations)
+			if err != nil {
+				// We are checking entries on adding them, so this should not happen,
+				// If it happens the label is dropped on next cleanup.
+				expiry = time.Time{}
+			}
+
+			lp := LabelPlan{
+				Name:        label.Name,
+				Format:      label.Format,
+				Expired:     !expiry.After(now),
+				ExpiresAt:   expiry,
+				KeptByCount: keptLabels[label.Name],
+			}
+
+			if !lp.Expired || lp.KeptByCount || plan.KeptByMinKeep {
+				plan.Retained = true
+			}
+
+			if !lp.Expired && expiry.After(plan.RetainedUntil) {
+				plan.RetainedUntil = expiry
+			}
+
+			plan.Labels = append(plan.Labels, lp)
+		}
+
+		sort.Slice(plan.Labels, func(i, j int) bool { return plan.Labels[i].ExpiresAt.Before(plan.Labels[j].ExpiresAt) })
+		plans = append(plans, plan)
+	}
+
+	sort.Slice(plans, func(i, j int) bool { return plans[i].Entry < plans[j].Entry })
+
+	return plans
+}
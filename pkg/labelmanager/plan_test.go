@@ -0,0 +1,55 @@
+package labelmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan(t *testing.T) {
+	const century = 100 * 365 * 24 * time.Hour
+
+	var (
+		ctx     = context.Background()
+		monthly = RetentionPolicy{Durations: RetentionConfig{"%Y-%m": century}}
+		store   = &memStore{entries: []string{
+			"2024-01-05T10-00-00",
+			"2024-01-06T10-00-00",
+		}}
+	)
+
+	_, err := Reindex(ctx, store, monthly)
+	require.NoError(t, err)
+	writes := store.writes
+
+	plans, err := Plan(ctx, store, monthly, false)
+	require.NoError(t, err)
+	require.Len(t, plans, 2)
+
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(century)
+	assert.Equal(t, EntryPlan{
+		Entry:         "2024-01-05T10-00-00",
+		Labels:        []LabelPlan{{Name: "2024-01", Format: "%Y-%m", ExpiresAt: first}},
+		Retained:      true,
+		RetainedUntil: first,
+	}, plans[0])
+	assert.Equal(t, EntryPlan{Entry: "2024-01-06T10-00-00", Labels: []LabelPlan{}}, plans[1])
+
+	// Shortening the retention expires the label
+	plans, err = Plan(ctx, store, RetentionPolicy{Durations: RetentionConfig{"%Y-%m": time.Hour}}, false)
+	require.NoError(t, err)
+	assert.False(t, plans[0].Retained)
+	assert.True(t, plans[0].RetainedUntil.IsZero())
+	assert.True(t, plans[0].Labels[0].Expired)
+
+	// Relabeling takes new formats into account
+	plans, err = Plan(ctx, store, RetentionPolicy{Durations: RetentionConfig{"%Y-%m": century, "%Y-%m-%d": century}}, true)
+	require.NoError(t, err)
+	assert.Len(t, plans[0].Labels, 2)
+	assert.True(t, plans[1].Retained)
+
+	assert.Equal(t, writes, store.writes, "plan must not modify the store")
+}
@@ -0,0 +1,122 @@
+package labelmanager
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/NectGmbH/db-backup-controller/pkg/manifest"
+)
+
+// EntryNameFormat is the time layout (UTC) backups are named with. It
+// is used to recover the creation time of the backups when rebuilding
+// the labels.
+const EntryNameFormat = "2006-01-02T15-04-05"
+
+// Reindex rebuilds the labels from the entries present in the store
+// and replaces the stored labels with the result. Every entry gets the
+// labels the RetentionPolicy would have assigned when the backups were
+// added in order of their creation. Entries not getting any label are
+// kept unretained to be removed on the next cleanup. Entries not named
+// in EntryNameFormat are ignored. Pins of entries still present are
+// kept and manifests stored next to the entries are referenced.
+func Reindex(ctx context.Context, store Store, policy RetentionPolicy) (*Manager, error) {
+	return Update(ctx, store, policy, func(m *Manager) error {
+		rebuilt, err := reindex(ctx, store, policy)
+		if err != nil {
+			return err
+		}
+
+		for entry, pin := range m.store.ListPins() {
+			if err = rebuilt.store.SetPin(entry, pin); err != nil && !errors.Is(err, ErrEntryNotFound) {
+				return errors.Wrapf(err, "keeping pin of %q", entry)
+			}
+		}
+
+		m.store = rebuilt.store
+		return nil
+	})
+}
+
+func reindex(ctx context.Context, store Store, policy RetentionPolicy) (*Manager, error) {
+	names, err := store.ListEntries(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing entries")
+	}
+
+	m, _, err := rebuild(names, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	present := make(map[string]bool, len(names))
+	for _, name := range names {
+		present[name] = true
+	}
+
+	for _, entry := range m.store.ListEntries() {
+		if !present[manifest.Name(entry)] {
+			continue
+		}
+
+		if err = m.SetManifest(entry, manifest.Name(entry)); err != nil {
+			return nil, errors.Wrapf(err, "referencing manifest of %q", entry)
+		}
+	}
+
+	return m, nil
+}
+
+// rebuild creates a Manager assigning the labels to the entries named
+// in EntryNameFormat as if they were added in order of their creation.
+// Names not in EntryNameFormat are skipped and returned.
+func rebuild(names []string, policy RetentionPolicy) (*Manager, []string, error) {
+	type entry struct {
+		name      string
+		createdAt time.Time
+	}
+
+	var (
+		entries []entry
+		skipped []string
+	)
+	for _, name := range names {
+		createdAt, err := time.Parse(EntryNameFormat, name)
+		if err != nil {
+			// Not a backup (i.e. the labels themselves)
+			skipped = append(skipped, name)
+			continue
+		}
+
+		entries = append(entries, entry{name, createdAt})
+	}
+
+	// The first backup of a period gets its label so we need to add
+	// them in the order they were created
+	sort.Slice(entries, func(i, j int) bool { return entries[i].createdAt.Before(entries[j].createdAt) })
+
+	m, err := New(nil, policy)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "initializing label manager")
+	}
+
+	for _, e := range entries {
+		err = m.AddAt(e.name, e.createdAt)
+		switch {
+		case err == nil:
+			// Entry got its labels
+
+		case errors.Is(err, ErrNoLabelsAdded):
+			// All labels were taken by older backups, nothing retains
+			// this one
+			m.store.AddUnlabeledEntry(e.name)
+
+		default:
+			return nil, nil, errors.Wrapf(err, "adding entry %q", e.name)
+		}
+	}
+
+	return m, skipped, nil
+}
@@ -0,0 +1,72 @@
+package labelmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReindex(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		now       = time.Now().UTC()
+		retention = RetentionPolicy{Durations: RetentionConfig{
+			"%Y-%m-%d":          72 * time.Hour,
+			"%Y-%m-%dT%H-%M-%S": time.Hour,
+		}}
+		today    = now.Add(-time.Minute).Format(EntryNameFormat)
+		todayDup = now.Add(-2 * time.Minute).Format(EntryNameFormat)
+		lastYear = now.AddDate(-1, 0, 0).Format(EntryNameFormat)
+		store    = &memStore{entries: []string{today, ".labels", lastYear, todayDup, "foobar"}}
+	)
+
+	if now.Add(-2*time.Minute).Day() != now.Day() {
+		t.Skip("test is not stable around midnight")
+	}
+
+	// Missing labels are rebuilt from the store contents
+	m, err := Load(ctx, store, retention)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{today, lastYear, todayDup}, m.GetRetainedEntries())
+	assert.Zero(t, store.writes)
+
+	// The oldest backup of the day must hold the daily label
+	assert.Equal(t, now.Truncate(24*time.Hour).Add(72*time.Hour), m.RetainedUntil(todayDup))
+
+	m.CleanRetentions()
+	assert.ElementsMatch(t, []string{today, todayDup}, m.GetRetainedEntries())
+	assert.Equal(t, []string{lastYear}, m.GetUnretainedEntries())
+
+	// Reindexing replaces the stored labels
+	_, err = Update(ctx, store, retention, func(m *Manager) error { return m.Add("unknown") })
+	require.NoError(t, err)
+
+	m, err = Reindex(ctx, store, retention)
+	require.NoError(t, err)
+	assert.False(t, m.IsKnown("unknown"))
+
+	m, err = Load(ctx, store, retention)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{today, lastYear, todayDup}, m.GetRetainedEntries())
+}
+
+func TestReindexUnlabeled(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		first  = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+		second = first.Add(time.Hour)
+		store  = &memStore{entries: []string{second.Format(EntryNameFormat), first.Format(EntryNameFormat)}}
+	)
+
+	m, err := Reindex(ctx, store, RetentionPolicy{Durations: RetentionConfig{"%Y": 100 * 365 * 24 * time.Hour}})
+	require.NoError(t, err)
+
+	// Only the first backup of the year is retained, the other one is
+	// known to be removed by the next cleanup
+	assert.Equal(t, []string{first.Format(EntryNameFormat)}, m.GetRetainedEntries())
+	assert.Equal(t, []string{second.Format(EntryNameFormat)}, m.GetUnretainedEntries())
+	assert.True(t, m.IsKnown(second.Format(EntryNameFormat)))
+}
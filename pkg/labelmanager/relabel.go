@@ -0,0 +1,149 @@
+package labelmanager
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// LabelChange describes how the labels of an entry change when
+	// re-evaluating them under the current RetentionPolicy
+	LabelChange struct {
+		Entry string `json:"entry"`
+
+		AddedLabels   []string `json:"addedLabels,omitempty"`
+		RemovedLabels []string `json:"removedLabels,omitempty"`
+
+		// RetainedUntil is the point in time the entry is retained
+		// until before and after the change (zero time for entries
+		// no longer retained)
+		PreviousRetainedUntil time.Time `json:"previousRetainedUntil"`
+		RetainedUntil         time.Time `json:"retainedUntil"`
+	}
+)
+
+// Relabel loads the labels from the store and re-evaluates them (see
+// Manager.Relabel). If apply is false the store is not modified and
+// the changes are only returned as preview.
+func Relabel(ctx context.Context, store Store, policy RetentionPolicy, apply bool) (changes []LabelChange, err error) {
+	if !apply {
+		m, err := Load(ctx, store, policy)
+		if err != nil {
+			return nil, err
+		}
+
+		return m.PlanRelabel()
+	}
+
+	_, err = Update(ctx, store, policy, func(m *Manager) (err error) {
+		changes, err = m.Relabel()
+		return err
+	})
+
+	return changes, err
+}
+
+// PlanRelabel calculates the changes Relabel would apply without
+// modifying the Manager
+func (m Manager) PlanRelabel() ([]LabelChange, error) {
+	changes, _, err := m.relabel()
+	return changes, err
+}
+
+// Relabel re-evaluates the labels of all known entries from their
+// creation time (see EntryNameFormat) as if they were added under the
+// current RetentionPolicy: Labels for new formats are assigned to the
+// first backup of the respective period and labels for formats no
+// longer configured are removed. Entries not named in EntryNameFormat
+// keep their labels. The applied changes are returned.
+func (m Manager) Relabel() ([]LabelChange, error) {
+	changes, rebuilt, err := m.relabel()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range changes {
+		m.store.SetEntryLabels(c.Entry, rebuilt.store.EntryLabels(c.Entry))
+	}
+
+	return changes, nil
+}
+
+func (m Manager) relabel() ([]LabelChange, *Manager, error) {
+	rebuilt, skipped, err := rebuild(m.store.ListEntries(), m.policy)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "rebuilding labels")
+	}
+
+	// Expired labels are not worth to be assigned
+	rebuilt.CleanRetentions()
+
+	keptLabels, _ := m.store.Protected(m.policy)
+
+	isSkipped := make(map[string]bool, len(skipped))
+	for _, entry := range skipped {
+		isSkipped[entry] = true
+	}
+
+	var changes []LabelChange
+	for _, entry := range m.store.ListEntries() {
+		if isSkipped[entry] {
+			continue
+		}
+
+		change := LabelChange{
+			Entry:                 entry,
+			PreviousRetainedUntil: m.RetainedUntil(entry),
+			RetainedUntil:         rebuilt.RetainedUntil(entry),
+		}
+
+		var (
+			current = labelNames(m.store.EntryLabels(entry), nil, nil)
+			target  = labelNames(rebuilt.store.EntryLabels(entry), nil, nil)
+		)
+
+		for name := range target {
+			if !current[name] {
+				change.AddedLabels = append(change.AddedLabels, name)
+			}
+		}
+
+		for name := range labelNames(m.store.EntryLabels(entry), m.policy.Durations, keptLabels) {
+			if !target[name] {
+				change.RemovedLabels = append(change.RemovedLabels, name)
+			}
+		}
+
+		if change.AddedLabels == nil && change.RemovedLabels == nil {
+			continue
+		}
+
+		sort.Strings(change.AddedLabels)
+		sort.Strings(change.RemovedLabels)
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Entry < changes[j].Entry })
+
+	return changes, rebuilt, nil
+}
+
+// labelNames returns the set of names of the given labels. If a
+// retention is given expired labels are excluded unless they are kept.
+func labelNames(labels []retentionStoreEntry, retention RetentionConfig, kept map[string]bool) map[string]bool {
+	names := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		if retention != nil && !kept[label.Name] {
+			if expiry, err := label.expiresAt(retention); err != nil || expiry.Before(time.Now()) {
+				continue
+			}
+		}
+
+		names[label.Name] = true
+	}
+
+	return names
+}
@@ -0,0 +1,75 @@
+package labelmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelabel(t *testing.T) {
+	const century = 100 * 365 * 24 * time.Hour
+
+	var (
+		ctx     = context.Background()
+		monthly = RetentionPolicy{Durations: RetentionConfig{"%Y-%m": century}}
+		store   = &memStore{entries: []string{
+			"2024-01-05T10-00-00",
+			"2024-03-01T10-00-00",
+			"2025-02-01T10-00-00",
+			"custom",
+		}}
+	)
+
+	_, err := Reindex(ctx, store, monthly)
+	require.NoError(t, err)
+
+	// Entries not named by time must keep their labels
+	_, err = Update(ctx, store, monthly, func(m *Manager) error { return m.Add("custom") })
+	require.NoError(t, err)
+
+	// Adding a yearly tier assigns the labels to the first backup of
+	// the respective year
+	yearly := RetentionPolicy{Durations: RetentionConfig{"%Y-%m": century, "%Y": century}}
+
+	changes, err := Relabel(ctx, store, yearly, false)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "2024-01-05T10-00-00", changes[0].Entry)
+	assert.Equal(t, []string{"2024"}, changes[0].AddedLabels)
+	assert.Empty(t, changes[0].RemovedLabels)
+	assert.Equal(t, "2025-02-01T10-00-00", changes[1].Entry)
+	assert.Equal(t, []string{"2025"}, changes[1].AddedLabels)
+
+	// The preview must not modify the store
+	writes := store.writes
+	changes, err = Relabel(ctx, store, yearly, false)
+	require.NoError(t, err)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, writes, store.writes)
+
+	changes, err = Relabel(ctx, store, yearly, true)
+	require.NoError(t, err)
+	assert.Len(t, changes, 2)
+
+	changes, err = Relabel(ctx, store, yearly, false)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// Dropping the monthly tier removes the labels and leaves the
+	// second backup of 2024 without labels
+	changes, err = Relabel(ctx, store, RetentionPolicy{Durations: RetentionConfig{"%Y": century}}, true)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	assert.Equal(t, "2024-03-01T10-00-00", changes[1].Entry)
+	assert.Equal(t, []string{"2024-03"}, changes[1].RemovedLabels)
+	assert.False(t, changes[1].PreviousRetainedUntil.IsZero())
+	assert.True(t, changes[1].RetainedUntil.IsZero())
+
+	m, err := Load(ctx, store, RetentionPolicy{Durations: RetentionConfig{"%Y": century}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024-03-01T10-00-00"}, m.GetUnretainedEntries())
+	assert.True(t, m.IsRetained("custom"))
+}
@@ -1,6 +1,11 @@
 package labelmanager
 
-import "time"
+import (
+	"time"
+
+	"github.com/itchyny/timefmt-go"
+	"github.com/pkg/errors"
+)
 
 type (
 	// RetentionConfig specifies strftime formats and their
@@ -8,6 +13,26 @@ type (
 	// labels for entries and therefore define how long the
 	// entry is retained on disk
 	RetentionConfig map[string]time.Duration
+
+	// RetentionCounts specifies strftime formats and the number of
+	// most recent labels of the respective format to keep regardless
+	// of their retention period (i.e. "%Y-%m-%dT%H": 48 keeps the last
+	// 48 hourly backups even if no backup was created for days)
+	RetentionCounts map[string]int
+
+	// RetentionPolicy combines the rules deciding how long entries are
+	// retained
+	RetentionPolicy struct {
+		// Durations defines the labels assigned and their retention
+		// period, if nil the DefaultRetentionConfig is used
+		Durations RetentionConfig
+		// Counts defines labels to keep by count. Formats not present in
+		// Durations are assigned too but only retained by their count.
+		Counts RetentionCounts
+		// MinKeep is the number of most recent entries never to be
+		// removed regardless of the expiry of their labels
+		MinKeep int
+	}
 )
 
 const (
@@ -20,10 +45,45 @@ const (
 // DefaultRetentionConfig defines a two-year retention schema with
 // 24 hourly, 7 daily, 4 weekly and 12 monthly backups. Other
 // backups are held one hour.
+//
+// The weekly label uses the ISO year (%G) matching the ISO week (%V):
+// Labels using %Y together with %V cannot be parsed back and are never
+// stored, so no weekly backups would be retained.
 var DefaultRetentionConfig = map[string]time.Duration{
 	"%Y-%m":             durTwelveMonths, // Created once per month, first backup of the month
-	"%Y-w%V":            durOneMonth,     // Created once per week, first backup of the week
+	"%G-w%V":            durOneMonth,     // Created once per week, first backup of the week
 	"%Y-%m-%d":          durOneWeek,      // Created once per day, first backup of the day
 	"%Y-%m-%dT%H":       durOneDay,       // Created once per hour, first backup of the hour
 	"%Y-%m-%dT%H-%M-%S": time.Hour,       // Created once per second, should hold all backups
 }
+
+// formats returns the retention periods of all formats to assign
+// labels for: Formats only present in Counts get no retention period.
+func (p RetentionPolicy) formats() RetentionConfig {
+	formats := make(RetentionConfig, len(p.Durations)+len(p.Counts))
+	for format := range p.Counts {
+		formats[format] = 0
+	}
+
+	for format, retainFor := range p.Durations {
+		formats[format] = retainFor
+	}
+
+	return formats
+}
+
+// ValidateFormat checks whether labels of the given strftime format
+// can be generated and parsed back into the time they were created
+// for: Labels failing to parse are never stored.
+func ValidateFormat(format string) error {
+	label := timefmt.Format(time.Now(), format)
+	if label == format {
+		return errors.New("format contains no time directives")
+	}
+
+	if _, err := timefmt.Parse(label, format); err != nil {
+		return errors.Wrap(err, "parsing generated label")
+	}
+
+	return nil
+}
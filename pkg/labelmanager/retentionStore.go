@@ -3,6 +3,7 @@ package labelmanager
 import (
 	"io"
 	"math"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,6 +15,10 @@ import (
 type (
 	retentionStore struct {
 		Entries map[string][]retentionStoreEntry `yaml:"entries"`
+		Pins    map[string]Pin                   `yaml:"pins,omitempty"`
+		// Manifests references the object describing the entry by its
+		// name (see manifest package)
+		Manifests map[string]string `yaml:"manifests,omitempty"`
 
 		labels map[string]string
 		lock   sync.RWMutex
@@ -31,6 +36,9 @@ var errDuplicateLabel = errors.New("label already exists")
 func newRetentionStore() *retentionStore {
 	return &retentionStore{
 		Entries: make(map[string][]retentionStoreEntry),
+		Pins:    make(map[string]Pin),
+
+		Manifests: make(map[string]string),
 
 		labels: make(map[string]string),
 	}
@@ -58,32 +66,50 @@ func (r *retentionStore) AddEntry(entry string, label retentionStoreEntry) error
 	return nil
 }
 
+// AddUnlabeledEntry adds the entry without any labels so it is known
+// but not retained and therefore removed on the next cleanup
+func (r *retentionStore) AddUnlabeledEntry(entry string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.Entries[entry] == nil {
+		r.Entries[entry] = []retentionStoreEntry{}
+	}
+}
+
 // CleanupLabels removes timed out labels but does NOT delete the
 // entry. This has to be done using the Remove function in
 // order to give cleanup tasks the chance to see the is now
-// no longer retained but was previously known.
-func (r *retentionStore) CleanupLabels(retention RetentionConfig) {
+// no longer retained but was previously known. Labels protected
+// by the policy (see Protected) are kept even when timed out.
+// Expired pins are removed.
+func (r *retentionStore) CleanupLabels(policy RetentionPolicy) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
+	for entry, pin := range r.Pins {
+		if !pin.IsActive(time.Now()) {
+			delete(r.Pins, entry)
+		}
+	}
+
+	keptLabels, keptEntries := r.protected(policy)
+
 	for entry, labels := range r.Entries {
+		if keptEntries[entry] {
+			continue
+		}
+
 		var retained []retentionStoreEntry
 		for _, label := range labels {
-			labelTime, err := timefmt.Parse(label.Name, label.Format)
+			expiry, err := label.expiresAt(policy.Durations)
 			if err != nil {
 				// We are checking entries on adding them, so this should not happen,
 				// If it happens we treat the entry as invalid and drop it.
 				continue
 			}
 
-			retainFor := label.InitialHoldTime
-			if retention[label.Format] > 0 {
-				// There is an entry in the current config which might
-				// overwrite the initial hold time
-				retainFor = retention[label.Format]
-			}
-
-			if labelTime.Add(retainFor).Before(time.Now()) {
+			if expiry.Before(time.Now()) && !keptLabels[label.Name] {
 				// That one expired, drop it.
 				continue
 			}
@@ -110,6 +136,7 @@ func (r *retentionStore) FindRetainedBackupForPointInTime(pointInTime time.Time)
 	defer r.lock.RUnlock()
 
 	for entry, labels := range r.Entries {
+		times := make([]time.Time, 0, len(labels))
 		for _, label := range labels {
 			labelTime, err := timefmt.Parse(label.Name, label.Format)
 			if err != nil {
@@ -118,6 +145,17 @@ func (r *retentionStore) FindRetainedBackupForPointInTime(pointInTime time.Time)
 				continue
 			}
 
+			times = append(times, labelTime)
+		}
+
+		if len(labels) == 0 && r.isPinned(entry) {
+			// Pinned entries without labels can still be restored
+			if createdAt := entryCreatedAt(entry, nil); !createdAt.IsZero() {
+				times = append(times, createdAt)
+			}
+		}
+
+		for _, labelTime := range times {
 			if labelTime.After(pointInTime) {
 				// We were asked for a backup older than pointInTime, this is not it.
 				continue
@@ -138,6 +176,54 @@ func (r *retentionStore) FindRetainedBackupForPointInTime(pointInTime time.Time)
 	return closest
 }
 
+// EntryRetainedUntil returns the latest expiry of the labels of the
+// given entry or the zero time if it has no labels
+func (r *retentionStore) EntryRetainedUntil(entry string, retention RetentionConfig) (until time.Time) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, label := range r.Entries[entry] {
+		expiry, err := label.expiresAt(retention)
+		if err != nil {
+			// We are checking entries on adding them, so this should not happen,
+			// If it happens we treat the label as invalid and skip it.
+			continue
+		}
+
+		if expiry.After(until) {
+			until = expiry
+		}
+	}
+
+	return until
+}
+
+// EntryManifest returns the name of the manifest of the given entry
+func (r *retentionStore) EntryManifest(entry string) (string, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	name, ok := r.Manifests[entry]
+	return name, ok
+}
+
+// EntryPin returns the pin of the given entry if it is active
+func (r *retentionStore) EntryPin(entry string) (Pin, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	pin, ok := r.Pins[entry]
+	return pin, ok && pin.IsActive(time.Now())
+}
+
+// EntryLabels returns a copy of the labels of the given entry
+func (r *retentionStore) EntryLabels(entry string) []retentionStoreEntry {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return append([]retentionStoreEntry(nil), r.Entries[entry]...)
+}
+
 func (r *retentionStore) IsEntryKnown(entry string) bool {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
@@ -149,7 +235,33 @@ func (r *retentionStore) IsEntryRetained(entry string) bool {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
 
-	return len(r.Entries[entry]) > 0
+	return len(r.Entries[entry]) > 0 || r.isPinned(entry)
+}
+
+func (r *retentionStore) ListEntries() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	out := make([]string, 0, len(r.Entries))
+	for entry := range r.Entries {
+		out = append(out, entry)
+	}
+
+	return out
+}
+
+func (r *retentionStore) ListPins() map[string]Pin {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	out := make(map[string]Pin)
+	for entry, pin := range r.Pins {
+		if pin.IsActive(time.Now()) {
+			out[entry] = pin
+		}
+	}
+
+	return out
 }
 
 func (r *retentionStore) ListRetainedEntries() []string {
@@ -191,11 +303,30 @@ func (r *retentionStore) Load(source io.Reader) error {
 		return errors.Wrap(err, "reading store file")
 	}
 
+	if r.Pins == nil {
+		// Stored before pins were introduced
+		r.Pins = make(map[string]Pin)
+	}
+
+	if r.Manifests == nil {
+		// Stored before manifests were introduced
+		r.Manifests = make(map[string]string)
+	}
+
 	r.rebuildLabels()
 
 	return nil
 }
 
+// Protected returns the names of the labels kept by the count rules
+// of the policy and the entries kept by its MinKeep rule
+func (r *retentionStore) Protected(policy RetentionPolicy) (labels, entries map[string]bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.protected(policy)
+}
+
 // Remove deletes the entry from the database and rebuilds the labels
 // list
 func (r *retentionStore) Remove(entry string) {
@@ -203,6 +334,68 @@ func (r *retentionStore) Remove(entry string) {
 	defer r.lock.Unlock()
 
 	delete(r.Entries, entry)
+	delete(r.Pins, entry)
+	delete(r.Manifests, entry)
+	r.rebuildLabels()
+}
+
+// RemovePin removes the pin of the given entry
+//
+// Returns ErrEntryNotPinned in case the entry has no active pin
+func (r *retentionStore) RemovePin(entry string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.isPinned(entry) {
+		return ErrEntryNotPinned
+	}
+
+	delete(r.Pins, entry)
+	return nil
+}
+
+// SetManifest references the manifest of the given entry
+//
+// Returns ErrEntryNotFound in case the entry is not known
+func (r *retentionStore) SetManifest(entry, name string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.Entries[entry] == nil {
+		return ErrEntryNotFound
+	}
+
+	r.Manifests[entry] = name
+	return nil
+}
+
+// SetPin pins the given entry replacing an existing pin
+//
+// Returns ErrEntryNotFound in case the entry is not known
+func (r *retentionStore) SetPin(entry string, pin Pin) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.Entries[entry] == nil {
+		return ErrEntryNotFound
+	}
+
+	r.Pins[entry] = pin
+	return nil
+}
+
+// SetEntryLabels replaces the labels of the given entry and rebuilds
+// the labels list
+func (r *retentionStore) SetEntryLabels(entry string, labels []retentionStoreEntry) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if labels == nil {
+		// Keep the entry known to have it removed on next cleanup
+		labels = []retentionStoreEntry{}
+	}
+
+	r.Entries[entry] = labels
 	r.rebuildLabels()
 }
 
@@ -218,12 +411,112 @@ func (r *retentionStore) Save(dest io.Writer) error {
 	)
 }
 
+// isPinned checks whether the entry has an active pin. It MUST NOT
+// be used without previously acquiring a lock!
+func (r *retentionStore) isPinned(entry string) bool {
+	pin, ok := r.Pins[entry]
+	return ok && pin.IsActive(time.Now())
+}
+
+// protected implements Protected. It MUST NOT be used without
+// previously acquiring a lock!
+func (r *retentionStore) protected(policy RetentionPolicy) (labels, entries map[string]bool) {
+	type item struct {
+		name string
+		at   time.Time
+	}
+
+	newest := func(items []item, n int, into map[string]bool) {
+		sort.Slice(items, func(i, j int) bool { return items[i].at.After(items[j].at) })
+		for i := 0; i < n && i < len(items); i++ {
+			into[items[i].name] = true
+		}
+	}
+
+	labels = make(map[string]bool)
+	for format, count := range policy.Counts {
+		var items []item
+		for _, entryLabels := range r.Entries {
+			for _, label := range entryLabels {
+				if label.Format != format {
+					continue
+				}
+
+				labelTime, err := timefmt.Parse(label.Name, label.Format)
+				if err != nil {
+					// We are checking entries on adding them, so this should not happen,
+					// If it happens we treat the label as invalid and skip it.
+					continue
+				}
+
+				items = append(items, item{label.Name, labelTime})
+			}
+		}
+
+		newest(items, count, labels)
+	}
+
+	entries = make(map[string]bool)
+	if policy.MinKeep > 0 {
+		var items []item
+		for entry, entryLabels := range r.Entries {
+			if len(entryLabels) == 0 {
+				// Entries without labels were already given up (i.e. they
+				// were not the most recent ones when losing their labels)
+				continue
+			}
+
+			items = append(items, item{entry, entryCreatedAt(entry, entryLabels)})
+		}
+
+		newest(items, policy.MinKeep, entries)
+	}
+
+	return labels, entries
+}
+
 // rebuildLabels updates the label list for the entries currently
 // known. It MUST NOT be used without previously acquiring a write-lock!
 func (r *retentionStore) rebuildLabels() {
+	r.labels = make(map[string]string)
 	for entry, labels := range r.Entries {
 		for _, label := range labels {
 			r.labels[label.Name] = entry
 		}
 	}
 }
+
+// expiresAt calculates when the label expires taking into account
+// the current config might overwrite the initial hold time
+func (r retentionStoreEntry) expiresAt(retention RetentionConfig) (time.Time, error) {
+	labelTime, err := timefmt.Parse(r.Name, r.Format)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "parsing label time")
+	}
+
+	retainFor := r.InitialHoldTime
+	if retention[r.Format] > 0 {
+		// There is an entry in the current config which might
+		// overwrite the initial hold time
+		retainFor = retention[r.Format]
+	}
+
+	return labelTime.Add(retainFor), nil
+}
+
+// entryCreatedAt determines the creation time of the entry from its
+// name (see EntryNameFormat) and falls back to the most recent time
+// of its labels for entries named differently
+func entryCreatedAt(entry string, labels []retentionStoreEntry) (createdAt time.Time) {
+	if t, err := time.Parse(EntryNameFormat, entry); err == nil {
+		return t
+	}
+
+	for _, label := range labels {
+		if t, err := timefmt.Parse(label.Name, label.Format); err == nil && t.After(createdAt) {
+			createdAt = t
+		}
+	}
+
+	return createdAt
+}
@@ -0,0 +1,24 @@
+package labelmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFormat(t *testing.T) {
+	for format := range DefaultRetentionConfig {
+		assert.NoError(t, ValidateFormat(format), format)
+	}
+
+	assert.NoError(t, ValidateFormat("daily-%Y-%m-%d"))
+
+	// ISO week needs the ISO year to be parsed
+	assert.Error(t, ValidateFormat("%Y-w%V"))
+	// Unknown directive
+	assert.Error(t, ValidateFormat("%Y-%Q"))
+	// Stray percent sign
+	assert.Error(t, ValidateFormat("%Y-%"))
+	// Constant label
+	assert.Error(t, ValidateFormat("daily"))
+}
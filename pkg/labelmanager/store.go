@@ -0,0 +1,191 @@
+package labelmanager
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxUpdateAttempts = 10
+	updateBackoff     = 100 * time.Millisecond
+)
+
+type (
+	// Store describes a storage the labels are persisted in which is
+	// able to detect concurrent modifications
+	Store interface {
+		// ReadLabels returns the stored labels and a version identifying
+		// the returned content. If no labels are stored yet nil content
+		// and an empty version are returned.
+		ReadLabels(ctx context.Context) (content io.ReadCloser, version string, err error)
+		// WriteLabels stores the content if the stored labels still have
+		// the given version (or do not exist for an empty version) and
+		// returns ErrVersionConflict otherwise
+		WriteLabels(ctx context.Context, content io.Reader, version string) error
+		// ListEntries returns the names of all objects stored next to
+		// the labels, used to rebuild the labels through Reindex
+		ListEntries(ctx context.Context) ([]string, error)
+	}
+)
+
+// ErrVersionConflict signalizes the labels in the store were modified
+// since they were read
+var ErrVersionConflict = errors.New("labels were modified concurrently")
+
+// Load reads the labels from the store and creates a Manager from them.
+// If no labels are stored yet they are rebuilt from the entries in the
+// store (see Reindex).
+func Load(ctx context.Context, store Store, policy RetentionPolicy) (*Manager, error) {
+	m, _, err := load(ctx, store, policy)
+	return m, err
+}
+
+// Update reads the labels from the store, applies the given function
+// and writes the result back. If the labels were modified in the
+// meantime the update is retried on freshly read labels, so fn might
+// be called multiple times and must not have side-effects outside of
+// the given Manager. If fn returns an error nothing is written.
+func Update(ctx context.Context, store Store, policy RetentionPolicy, fn func(*Manager) error) (*Manager, error) {
+	for attempt := 1; ; attempt++ {
+		m, version, err := load(ctx, store, policy)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = fn(m); err != nil {
+			return nil, err
+		}
+
+		buf := new(bytes.Buffer)
+		if err = m.Save(buf); err != nil {
+			return nil, errors.Wrap(err, "serializing labels")
+		}
+
+		err = store.WriteLabels(ctx, buf, version)
+		switch {
+		case err == nil:
+			return m, nil
+
+		case !errors.Is(err, ErrVersionConflict):
+			return nil, errors.Wrap(err, "writing labels")
+
+		case attempt == maxUpdateAttempts:
+			return nil, errors.Wrapf(err, "giving up after %d attempts", attempt)
+		}
+
+		// Someone else was faster, wait a moment to not collide again
+		// and retry with their changes
+		wait := time.Duration(attempt)*updateBackoff + time.Duration(rand.Int63n(int64(updateBackoff))) //#nosec:G404 // Jitter does not need crypto
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "waiting for retry")
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Cleanup removes expired labels from the store and deletes the
+// backups (and their manifests) which are no longer retained using
+// the remove function. It reports whether the backup is gone or needs
+// to be kept for now (i.e. because of a storage lock). Retained
+// backups reported as missing by the exists function are removed from
+// the labels.
+//
+// Labels of deleted backups which were retained again in the meantime
+// (i.e. pinned) are kept, callers should not modify the labels while
+// the cleanup is running to not lose the backup.
+func Cleanup(
+	ctx context.Context,
+	store Store,
+	policy RetentionPolicy,
+	remove func(ctx context.Context, entry string) (removed bool, err error),
+	exists func(ctx context.Context, entry string) (bool, error),
+) error {
+	m, err := Load(ctx, store, policy)
+	if err != nil {
+		return err
+	}
+
+	// Let the label manager clean itself up
+	m.CleanRetentions()
+
+	// Now we get all entries which should no longer exist and make
+	// sure they don't. This must not happen inside the update as that
+	// might be retried.
+	var removed, missing []string
+	for _, entry := range m.GetUnretainedEntries() {
+		ok, err := remove(ctx, entry)
+		if err != nil {
+			return errors.Wrap(err, "deleting expired backup")
+		}
+
+		if !ok {
+			continue
+		}
+
+		if manifest, ok := m.GetManifest(entry); ok {
+			if _, err = remove(ctx, manifest); err != nil {
+				return errors.Wrap(err, "deleting manifest of expired backup")
+			}
+		}
+
+		removed = append(removed, entry)
+	}
+
+	for _, entry := range m.GetRetainedEntries() {
+		found, err := exists(ctx, entry)
+		if err != nil {
+			return errors.Wrap(err, "checking backup existence")
+		}
+
+		if !found {
+			// Well, that backup is for sure gone...
+			missing = append(missing, entry)
+		}
+	}
+
+	// And finally we store the state back merging it with changes
+	// made in the meantime
+	_, err = Update(ctx, store, policy, func(m *Manager) error {
+		m.CleanRetentions()
+		for _, entry := range removed {
+			if m.IsRetained(entry) {
+				// Retained since we loaded the labels, keep the labels
+				// so the change is not silently dropped: The exists
+				// check of the next cleanup removes them.
+				continue
+			}
+			m.Remove(entry)
+		}
+
+		for _, entry := range missing {
+			m.Remove(entry)
+		}
+		return nil
+	})
+
+	return errors.Wrap(err, "storing labels")
+}
+
+func load(ctx context.Context, store Store, policy RetentionPolicy) (*Manager, string, error) {
+	content, version, err := store.ReadLabels(ctx)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "reading labels")
+	}
+
+	if content == nil {
+		// There are no labels (anymore), make sure backups already
+		// present in the store do not become invisible
+		m, err := reindex(ctx, store, policy)
+		return m, version, err
+	}
+	defer content.Close() //nolint:errcheck // This might leak FDs but this is a library and should not log
+
+	m, err := New(content, policy)
+	return m, version, errors.Wrap(err, "initializing label manager")
+}
@@ -0,0 +1,170 @@
+package labelmanager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memStore struct {
+	content []byte
+	entries []string
+	lock    sync.Mutex
+	version int
+	writes  int
+}
+
+func (m *memStore) ListEntries(context.Context) ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.entries, nil
+}
+
+func (m *memStore) ReadLabels(context.Context) (io.ReadCloser, string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	// Give other writers the chance to interleave
+	time.Sleep(time.Millisecond)
+
+	if m.content == nil {
+		return nil, "", nil
+	}
+
+	return io.NopCloser(bytes.NewReader(m.content)), strconv.Itoa(m.version), nil
+}
+
+func (m *memStore) WriteLabels(_ context.Context, content io.Reader, version string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	current := ""
+	if m.content != nil {
+		current = strconv.Itoa(m.version)
+	}
+
+	if version != current {
+		return ErrVersionConflict
+	}
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	m.content = data
+	m.version++
+	m.writes++
+	return nil
+}
+
+func TestUpdateConcurrentWriters(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		store   = &memStore{}
+		writers = 8
+		wg      sync.WaitGroup
+	)
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			_, err := Update(ctx, store, RetentionPolicy{}, func(m *Manager) error {
+				return m.store.AddEntry(fmt.Sprintf("backup%d", i), retentionStoreEntry{
+					Format:          "%Y",
+					InitialHoldTime: 100 * 365 * 24 * time.Hour,
+					Name:            strconv.Itoa(2000 + i),
+				})
+			})
+			assert.NoError(t, err)
+		}(i)
+	}
+
+	wg.Wait()
+
+	m, err := Load(ctx, store, RetentionPolicy{})
+	require.NoError(t, err)
+
+	// No writer must have overwritten the entry of another one
+	assert.Len(t, m.GetRetainedEntries(), writers)
+	assert.Equal(t, writers, store.writes)
+}
+
+func TestUpdateConflict(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = &memStore{}
+	)
+
+	_, err := Update(ctx, store, RetentionPolicy{}, func(m *Manager) error { return m.Add("backup1") })
+	require.NoError(t, err)
+
+	// Writing with a stale version must fail
+	content, version, err := store.ReadLabels(ctx)
+	require.NoError(t, err)
+	require.NoError(t, content.Close())
+
+	_, err = Update(ctx, store, RetentionPolicy{}, func(m *Manager) error {
+		m.Remove("backup1")
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, store.WriteLabels(ctx, bytes.NewReader(nil), version), ErrVersionConflict)
+
+	// Errors in the update function must prevent the write
+	writes := store.writes
+	_, err = Update(ctx, store, RetentionPolicy{}, func(*Manager) error { return errors.New("nope") })
+	assert.Error(t, err)
+	assert.Equal(t, writes, store.writes)
+}
+
+func TestCleanupKeepsEntriesPinnedMeanwhile(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		old    = time.Now().UTC().AddDate(0, 0, -10)
+		pinned = old.Format(EntryNameFormat)
+		other  = old.Add(time.Second).Format(EntryNameFormat)
+		policy = RetentionPolicy{Durations: RetentionConfig{"%Y-%m-%dT%H-%M-%S": time.Hour}}
+		store  = &memStore{}
+	)
+
+	_, err := Update(ctx, store, policy, func(m *Manager) error {
+		if err := m.AddAt(pinned, old); err != nil {
+			return err
+		}
+		return m.AddAt(other, old.Add(time.Second))
+	})
+	require.NoError(t, err)
+
+	remove := func(ctx context.Context, entry string) (bool, error) {
+		if entry == pinned {
+			// Pin lands while the cleanup is deleting the backups
+			_, err := Update(ctx, store, policy, func(m *Manager) error { return m.Pin(pinned, "audit", time.Time{}) })
+			require.NoError(t, err)
+		}
+		return true, nil
+	}
+	exists := func(context.Context, string) (bool, error) { return true, nil }
+
+	require.NoError(t, Cleanup(ctx, store, policy, remove, exists))
+
+	m, err := Load(ctx, store, policy)
+	require.NoError(t, err)
+
+	_, ok := m.GetPin(pinned)
+	assert.True(t, ok, "pin must not be dropped")
+	assert.False(t, m.IsKnown(other))
+}
@@ -0,0 +1,124 @@
+// Package manifest describes the metadata stored next to each backup
+// to inspect it without downloading it
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Suffix is appended to the name of the backup to name its manifest
+const Suffix = ".manifest.json"
+
+const (
+	// EncryptionModePassphrase marks backups encrypted with the
+	// passphrase of the location
+	EncryptionModePassphrase = "passphrase"
+	// EncryptionModeRecipients marks backups encrypted to the
+	// recipients listed in the manifest
+	EncryptionModeRecipients = "recipients"
+)
+
+type (
+	// Manifest describes a stored backup. Backups stored without a
+	// manifest (i.e. created by older versions) are described by their
+	// name only.
+	Manifest struct {
+		Backup string `json:"backup"`
+
+		DatabaseType    string `json:"databaseType,omitempty"`
+		DatabaseVersion string `json:"databaseVersion,omitempty"`
+
+		StartedAt  time.Time `json:"startedAt"`
+		FinishedAt time.Time `json:"finishedAt"`
+
+		// Size and SHA256 describe the object as stored (after the
+		// compression and encryption)
+		Size   int64  `json:"size,omitempty"`
+		SHA256 string `json:"sha256,omitempty"`
+
+		Compression *Compression `json:"compression,omitempty"`
+		Encryption  *Encryption  `json:"encryption,omitempty"`
+	}
+
+	// Compression describes how the backup was compressed
+	Compression struct {
+		Codec string `json:"codec"`
+		Level int    `json:"level,omitempty"`
+	}
+
+	// Encryption describes how the backup was encrypted
+	Encryption struct {
+		Mode       string   `json:"mode"`
+		Recipients []string `json:"recipients,omitempty"`
+	}
+
+	// FetchFunc retrieves the object with the given name from the
+	// storage
+	FetchFunc func(ctx context.Context, name string) (io.ReadCloser, error)
+)
+
+// Name returns the name the manifest of the given backup is stored as
+func Name(backup string) string {
+	return backup + Suffix
+}
+
+// Collect fetches the manifests of the given backups. Backups without
+// a manifest reference (manifestOf returning false) are described by
+// their name only. The result is ordered by the backup names.
+func Collect(ctx context.Context, backups []string, manifestOf func(string) (string, bool), fetch FetchFunc) ([]Manifest, error) {
+	manifests := make([]Manifest, 0, len(backups))
+
+	for _, backup := range backups {
+		name, ok := manifestOf(backup)
+		if !ok {
+			manifests = append(manifests, Manifest{Backup: backup})
+			continue
+		}
+
+		m, err := fetchManifest(ctx, name, fetch)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetching manifest of %q", backup)
+		}
+
+		manifests = append(manifests, m)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].Backup < manifests[j].Backup })
+
+	return manifests, nil
+}
+
+// Read decodes a manifest from the given reader
+func Read(r io.Reader) (m Manifest, err error) {
+	if err = json.NewDecoder(r).Decode(&m); err != nil {
+		return m, errors.Wrap(err, "decoding manifest")
+	}
+
+	return m, nil
+}
+
+// Duration returns how long creating and uploading the backup took
+func (m Manifest) Duration() time.Duration {
+	return m.FinishedAt.Sub(m.StartedAt)
+}
+
+// Write encodes the manifest into the given writer
+func (m Manifest) Write(w io.Writer) error {
+	return errors.Wrap(json.NewEncoder(w).Encode(m), "encoding manifest")
+}
+
+func fetchManifest(ctx context.Context, name string, fetch FetchFunc) (Manifest, error) {
+	r, err := fetch(ctx, name)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer r.Close() //nolint:errcheck // This might leak FDs but this is a library and should not log
+
+	return Read(r)
+}
@@ -0,0 +1,41 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	stored := Manifest{
+		Backup:     "b",
+		StartedAt:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		FinishedAt: time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC),
+		Size:       42,
+		Encryption: &Encryption{Mode: EncryptionModePassphrase},
+	}
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, stored.Write(buf))
+
+	var (
+		manifestOf = func(backup string) (string, bool) { return Name(backup), backup == "b" }
+		fetch      = func(_ context.Context, name string) (io.ReadCloser, error) {
+			assert.Equal(t, "b.manifest.json", name)
+			return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+		}
+	)
+
+	manifests, err := Collect(context.Background(), []string{"b", "a"}, manifestOf, fetch)
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+
+	assert.Equal(t, Manifest{Backup: "a"}, manifests[0])
+	assert.Equal(t, stored, manifests[1])
+	assert.Equal(t, time.Minute, manifests[1].Duration())
+}
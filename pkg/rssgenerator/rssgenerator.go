@@ -36,6 +36,10 @@ type (
 		STS     *appsv1.StatefulSet
 
 		Hash string `hash:"-"`
+
+		// storageClass is filled by the secret generator to be used in
+		// later generators without fetching it again
+		storageClass *v1.DatabaseBackupStorageClassSpec
 	}
 
 	generator struct {
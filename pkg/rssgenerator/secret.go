@@ -2,15 +2,26 @@ package rssgenerator
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 
 	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
 )
 
+const (
+	// SecretKeyAPIToken is the key of the generated secret containing
+	// the token the controller authenticates with at the runner API
+	SecretKeyAPIToken = "apiToken"
+
+	apiTokenLength = 32
+)
+
 func generateSecret(o Opts, res *Result) (err error) {
 	sc, err := o.ControllerClient.BackupV1().
 		DatabaseBackupStorageClasses().
@@ -50,7 +61,38 @@ func generateSecret(o Opts, res *Result) (err error) {
 		return errors.Wrap(err, "marshalling DatabaseBackupStorageClassSpec")
 	}
 
+	if secret.Data[SecretKeyAPIToken], err = apiToken(o); err != nil {
+		return errors.Wrap(err, "getting API token")
+	}
+
 	res.Secret = secret
+	res.storageClass = &sc.Spec
 
 	return nil
 }
+
+// apiToken returns the token of the existing runner secret or a new
+// one if there is none: Changing it would change the hash and restart
+// the runner on every update.
+func apiToken(o Opts) ([]byte, error) {
+	existing, err := o.K8sClient.CoreV1().Secrets(o.TargetNamespace).Get(context.TODO(), o.ResourceName, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		if token := existing.Data[SecretKeyAPIToken]; len(token) > 0 {
+			return token, nil
+		}
+
+	case k8sErrors.IsNotFound(err):
+		// Runner is created, needs a new token
+
+	default:
+		return nil, errors.Wrap(err, "fetching existing secret")
+	}
+
+	token := make([]byte, apiTokenLength)
+	if _, err = rand.Read(token); err != nil {
+		return nil, errors.Wrap(err, "generating token")
+	}
+
+	return []byte(hex.EncodeToString(token)), nil
+}
@@ -0,0 +1,37 @@
+package rssgenerator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAPIToken(t *testing.T) {
+	o := Opts{
+		K8sClient:       fake.NewSimpleClientset(),
+		ResourceName:    "runner",
+		TargetNamespace: "backups",
+	}
+
+	token, err := apiToken(o)
+	require.NoError(t, err)
+	assert.Len(t, token, 2*apiTokenLength)
+
+	other, err := apiToken(o)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other, "new runners must get distinct tokens")
+
+	// Existing token is kept to keep the hash stable
+	o.K8sClient = fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "runner", Namespace: "backups"},
+		Data:       map[string][]byte{SecretKeyAPIToken: token},
+	})
+
+	kept, err := apiToken(o)
+	require.NoError(t, err)
+	assert.Equal(t, token, kept)
+}
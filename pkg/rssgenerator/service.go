@@ -6,7 +6,10 @@ import (
 )
 
 const (
-	servicePort       = 3000
+	// ServicePort is the port the API of the runner is reachable at
+	// through the generated service
+	ServicePort = 3000
+
 	serviceNameMaxLen = 63
 )
 
@@ -22,7 +25,7 @@ func generateService(o Opts, res *Result) (err error) {
 				{
 					Name:     "api",
 					Protocol: corev1.ProtocolTCP,
-					Port:     servicePort,
+					Port:     ServicePort,
 				},
 			},
 			Selector: objectMetaLabelsFromDatabaseBackup(o.Backup),
@@ -2,6 +2,7 @@ package rssgenerator
 
 import (
 	"fmt"
+	"path"
 	"strconv"
 
 	"github.com/mitchellh/hashstructure/v2"
@@ -11,6 +12,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
 	"github.com/NectGmbH/db-backup-controller/pkg/backupengine"
 	"github.com/NectGmbH/db-backup-controller/pkg/backupengine/opts"
 )
@@ -74,6 +76,14 @@ func generateSTS(o Opts, res *Result) (err error) {
 		},
 	})
 
+	if err = addStorageVolumes(&res.STS.Spec.Template.Spec, res.storageClass.BackupLocations); err != nil {
+		return errors.Wrap(err, "adding storage volumes")
+	}
+
+	if err = applyServiceAccount(&res.STS.Spec.Template.Spec, res.storageClass.BackupLocations); err != nil {
+		return errors.Wrap(err, "applying service account")
+	}
+
 	for i := range res.STS.Spec.Template.Spec.Containers {
 		res.STS.Spec.Template.Spec.Containers[i].Env = append(
 			res.STS.Spec.Template.Spec.Containers[i].Env,
@@ -84,3 +94,81 @@ func generateSTS(o Opts, res *Result) (err error) {
 
 	return nil
 }
+
+// addStorageVolumes mounts the PVCs referenced by filesystem storage
+// locations into all containers of the pod at their storage path
+func addStorageVolumes(podSpec *corev1.PodSpec, locations []v1.DatabaseBackupStorageLocation) error {
+	var (
+		claimVolumes = map[string]string{}
+		mountClaims  = map[string]string{}
+	)
+
+	for _, loc := range locations {
+		if loc.StorageType != "filesystem" || loc.StorageVolumeClaimName == "" {
+			continue
+		}
+
+		if !path.IsAbs(loc.StoragePath) {
+			return errors.Errorf("storage path %q for claim %q is not absolute", loc.StoragePath, loc.StorageVolumeClaimName)
+		}
+
+		mountPath := path.Clean(loc.StoragePath)
+		if claim, ok := mountClaims[mountPath]; ok {
+			if claim != loc.StorageVolumeClaimName {
+				return errors.Errorf("storage path %q is used for claims %q and %q", mountPath, claim, loc.StorageVolumeClaimName)
+			}
+			// Already mounted
+			continue
+		}
+		mountClaims[mountPath] = loc.StorageVolumeClaimName
+
+		// The same claim might be mounted to multiple paths, the volume
+		// must only exist once in the pod
+		volName, ok := claimVolumes[loc.StorageVolumeClaimName]
+		if !ok {
+			volName = fmt.Sprintf("storage-%d", len(claimVolumes))
+			claimVolumes[loc.StorageVolumeClaimName] = volName
+
+			podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+				Name: volName,
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: loc.StorageVolumeClaimName,
+					},
+				},
+			})
+		}
+
+		for i := range podSpec.Containers {
+			podSpec.Containers[i].VolumeMounts = append(
+				podSpec.Containers[i].VolumeMounts,
+				corev1.VolumeMount{Name: volName, MountPath: mountPath},
+			)
+		}
+	}
+
+	return nil
+}
+
+// applyServiceAccount sets the ServiceAccount requested by the storage
+// locations (i.e. to use workload identity) on the pod
+func applyServiceAccount(podSpec *corev1.PodSpec, locations []v1.DatabaseBackupStorageLocation) error {
+	var serviceAccount string
+
+	for _, loc := range locations {
+		if loc.StorageServiceAccountName == "" {
+			continue
+		}
+
+		if serviceAccount != "" && serviceAccount != loc.StorageServiceAccountName {
+			return errors.Errorf("storage locations request service accounts %q and %q", serviceAccount, loc.StorageServiceAccountName)
+		}
+		serviceAccount = loc.StorageServiceAccountName
+	}
+
+	if serviceAccount != "" {
+		podSpec.ServiceAccountName = serviceAccount
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package rssgenerator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+)
+
+func TestAddStorageVolumes(t *testing.T) {
+	podSpec := corev1.PodSpec{Containers: []corev1.Container{{Name: "backup"}}}
+
+	require.NoError(t, addStorageVolumes(&podSpec, []v1.DatabaseBackupStorageLocation{
+		{StorageType: "s3", StorageBucket: "backups"},
+		{StorageType: "filesystem", StoragePath: "/backups/local"},
+		{StorageType: "filesystem", StoragePath: "/backups/a", StorageVolumeClaimName: "claim-a"},
+		{StorageType: "filesystem", StoragePath: "/backups/a/", StorageVolumeClaimName: "claim-a"},
+		{StorageType: "filesystem", StoragePath: "/backups/b", StorageVolumeClaimName: "claim-b"},
+		{StorageType: "filesystem", StoragePath: "/backups/c", StorageVolumeClaimName: "claim-a"},
+	}))
+
+	assert.Equal(t, []corev1.Volume{
+		{Name: "storage-0", VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "claim-a"},
+		}},
+		{Name: "storage-1", VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "claim-b"},
+		}},
+	}, podSpec.Volumes)
+
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: "storage-0", MountPath: "/backups/a"},
+		{Name: "storage-1", MountPath: "/backups/b"},
+		{Name: "storage-0", MountPath: "/backups/c"},
+	}, podSpec.Containers[0].VolumeMounts)
+
+	// Conflicting claims for the same path
+	assert.Error(t, addStorageVolumes(&corev1.PodSpec{}, []v1.DatabaseBackupStorageLocation{
+		{StorageType: "filesystem", StoragePath: "/backups", StorageVolumeClaimName: "claim-a"},
+		{StorageType: "filesystem", StoragePath: "/backups", StorageVolumeClaimName: "claim-b"},
+	}))
+
+	// Relative mount path
+	assert.Error(t, addStorageVolumes(&corev1.PodSpec{}, []v1.DatabaseBackupStorageLocation{
+		{StorageType: "filesystem", StoragePath: "backups", StorageVolumeClaimName: "claim-a"},
+	}))
+}
+
+func TestApplyServiceAccount(t *testing.T) {
+	podSpec := corev1.PodSpec{ServiceAccountName: "db-backup-runner"}
+
+	require.NoError(t, applyServiceAccount(&podSpec, []v1.DatabaseBackupStorageLocation{
+		{StorageType: "s3"},
+	}))
+	assert.Equal(t, "db-backup-runner", podSpec.ServiceAccountName)
+
+	require.NoError(t, applyServiceAccount(&podSpec, []v1.DatabaseBackupStorageLocation{
+		{StorageType: "s3", StorageServiceAccountName: "irsa"},
+		{StorageType: "s3"},
+		{StorageType: "s3", StorageServiceAccountName: "irsa"},
+	}))
+	assert.Equal(t, "irsa", podSpec.ServiceAccountName)
+
+	assert.Error(t, applyServiceAccount(&podSpec, []v1.DatabaseBackupStorageLocation{
+		{StorageType: "s3", StorageServiceAccountName: "irsa"},
+		{StorageType: "gcs", StorageServiceAccountName: "workload-identity"},
+	}))
+}
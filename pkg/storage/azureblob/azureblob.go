@@ -0,0 +1,250 @@
+// Package azureblob provides a storage.Manager for Azure Blob Storage
+package azureblob
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/pkg/errors"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+	"github.com/NectGmbH/db-backup-controller/pkg/storage/helper"
+)
+
+const (
+	labelmanagerStorageFileName = ".labels"
+
+	// Block blobs consist of up to 50,000 blocks, with 8MiB blocks
+	// this allows for backups of up to ~390GiB with unknown size
+	uploadBlockSize = 8 * 1024 * 1024
+
+	azureMaxIdleConns        = 10
+	azureIdleConnTimeout     = 30 * time.Second
+	azureTLSHandshakeTimeout = 30 * time.Second
+)
+
+type (
+	// Storage implements the storage.Manager interface to provide
+	// backup storage access in Azure Blob Storage
+	Storage struct {
+		helper.LabeledStorage
+
+		client *azblob.Client
+
+		config          *v1.DatabaseBackupSpec
+		storageLocation *v1.DatabaseBackupStorageLocation
+
+		storagePath string
+	}
+)
+
+var _ helper.Backend = Storage{}
+
+// New creates a new Azure Blob Storage authenticating with the SAS
+// token given in the storage location or the shared key (account
+// name and account key) if no SAS token is given
+func New(_ context.Context, storageLocation *v1.DatabaseBackupStorageLocation, cfg *v1.DatabaseBackup) (*Storage, error) {
+	client, err := newClient(storageLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	stor := &Storage{
+		client: client,
+
+		config:          &cfg.Spec,
+		storageLocation: storageLocation,
+
+		storagePath: strings.Join([]string{cfg.Namespace, cfg.Name}, "-"),
+	}
+	stor.LabeledStorage = helper.LabeledStorage{Backend: stor, Config: stor.config, StoragePath: stor.storagePath}
+
+	return stor, nil
+}
+
+// DownloadAsReader fetches the given backup (must exist) and
+// returns an io.ReadCloser for it
+func (s Storage) DownloadAsReader(ctx context.Context, name string) (helper.ReaderAtCloser, int64, error) { //nolint:ireturn,lll // Interface is expecting this
+	sourceName := s.blobName(name)
+
+	props, err := s.blob(sourceName).GetProperties(ctx, nil)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "getting stored blob properties")
+	}
+
+	if props.ContentLength == nil || props.ETag == nil {
+		return nil, 0, errors.New("blob properties are missing size or etag")
+	}
+
+	// Pin the ETag so all ranged reads see the same content even if
+	// the blob is overwritten in the meantime
+	return &blobReader{
+		ctx:       ctx,
+		client:    s.client,
+		container: s.storageLocation.StorageBucket,
+		name:      sourceName,
+		etag:      *props.ETag,
+		size:      *props.ContentLength,
+	}, *props.ContentLength, nil
+}
+
+// ListEntries implements the labelmanager.Store interface listing
+// the blobs inside the storage path
+func (s Storage) ListEntries(ctx context.Context) ([]string, error) {
+	var (
+		entries []string
+		prefix  = s.storagePath + "/"
+		pager   = s.client.NewListBlobsFlatPager(s.storageLocation.StorageBucket, &azblob.ListBlobsFlatOptions{Prefix: &prefix})
+	)
+
+	for pager.More() {
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "listing blobs")
+		}
+
+		for _, item := range resp.Segment.BlobItems {
+			if item.Name == nil || strings.Contains(strings.TrimPrefix(*item.Name, prefix), "/") {
+				// Not directly inside the storage path
+				continue
+			}
+
+			entries = append(entries, path.Base(*item.Name))
+		}
+	}
+
+	return entries, nil
+}
+
+// ReadLabels implements the labelmanager.Store interface using the
+// ETag of the label blob as version
+func (s Storage) ReadLabels(ctx context.Context) (io.ReadCloser, string, error) {
+	resp, err := s.client.DownloadStream(ctx, s.storageLocation.StorageBucket, path.Join(s.storagePath, labelmanagerStorageFileName), nil)
+	switch {
+	case err == nil:
+		if resp.ETag == nil {
+			resp.Body.Close() //nolint:errcheck,gosec // Body is not used, error is not relevant
+			return nil, "", errors.New("label storage blob is missing etag")
+		}
+		return resp.Body, string(*resp.ETag), nil
+
+	case bloberror.HasCode(err, bloberror.BlobNotFound):
+		// No labels stored yet
+		return nil, "", nil
+
+	default:
+		return nil, "", errors.Wrap(err, "fetching label storage blob")
+	}
+}
+
+// WriteLabels implements the labelmanager.Store interface using
+// conditional writes on the ETag of the label blob
+func (s Storage) WriteLabels(ctx context.Context, content io.Reader, version string) error {
+	cond := &blob.ModifiedAccessConditions{IfNoneMatch: to.Ptr(azcore.ETagAny)}
+	if version != "" {
+		cond = &blob.ModifiedAccessConditions{IfMatch: to.Ptr(azcore.ETag(version))}
+	}
+
+	_, err := s.client.UploadStream(ctx, s.storageLocation.StorageBucket, path.Join(s.storagePath, labelmanagerStorageFileName), content,
+		&azblob.UploadStreamOptions{AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: cond}})
+	if bloberror.HasCode(err, bloberror.ConditionNotMet, bloberror.BlobAlreadyExists) {
+		return labelmanager.ErrVersionConflict
+	}
+
+	return errors.Wrap(err, "uploading label storage blob")
+}
+
+// EntryExists checks whether the blob for the given entry exists
+func (s Storage) EntryExists(ctx context.Context, name string) (bool, error) {
+	_, err := s.blob(s.blobName(name)).GetProperties(ctx, nil)
+	switch {
+	case err == nil:
+		return true, nil
+
+	case bloberror.HasCode(err, bloberror.BlobNotFound):
+		return false, nil
+
+	default:
+		return false, errors.Wrap(err, "fetching blob properties for backup")
+	}
+}
+
+func (s Storage) blob(name string) *blob.Client {
+	return s.client.ServiceClient().NewContainerClient(s.storageLocation.StorageBucket).NewBlobClient(name)
+}
+
+// blobName returns the name of the blob storing the given entry
+func (s Storage) blobName(name string) string {
+	if s.config.UseSingleBackupTarget {
+		return s.storagePath
+	}
+
+	return path.Join(s.storagePath, name)
+}
+
+// RemoveEntry deletes the blob for the given entry
+func (s Storage) RemoveEntry(ctx context.Context, name string) (bool, error) {
+	_, err := s.client.DeleteBlob(ctx, s.storageLocation.StorageBucket, s.blobName(name), nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return false, errors.Wrap(err, "deleting blob")
+	}
+
+	return true, nil
+}
+
+// UploadEntry stores the data as the blob for the given entry
+func (s Storage) UploadEntry(ctx context.Context, name string, data io.Reader, _ int64) error {
+	// UploadStream stages blocks and commits them after the stream
+	// ended, so an aborted upload does not create a partial blob and
+	// the size of the data does not need to be known
+	_, err := s.client.UploadStream(ctx, s.storageLocation.StorageBucket, s.blobName(name), data, &azblob.UploadStreamOptions{
+		BlockSize: uploadBlockSize,
+	})
+	return errors.Wrap(err, "uploading blob")
+}
+
+func newClient(storageLocation *v1.DatabaseBackupStorageLocation) (*azblob.Client, error) {
+	serviceURL := storageLocation.StorageEndpoint
+	if serviceURL == "" {
+		serviceURL = fmt.Sprintf("https://%s.blob.core.windows.net/", storageLocation.StorageAccessKeyID.Value)
+	}
+
+	opts := &azblob.ClientOptions{}
+	if storageLocation.StorageInsecureSkipVerify {
+		opts.ClientOptions = policy.ClientOptions{
+			Transport: &http.Client{Transport: &http.Transport{
+				MaxIdleConns:    azureMaxIdleConns,
+				IdleConnTimeout: azureIdleConnTimeout,
+				//#nosec:G402 // That's exactly the intention of this code path
+				TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+				TLSHandshakeTimeout: azureTLSHandshakeTimeout,
+			}},
+		}
+	}
+
+	if sas := strings.TrimPrefix(storageLocation.StorageSASToken.Value, "?"); sas != "" {
+		client, err := azblob.NewClientWithNoCredential(strings.Join([]string{serviceURL, sas}, "?"), opts)
+		return client, errors.Wrap(err, "creating Azure Blob client")
+	}
+
+	cred, err := azblob.NewSharedKeyCredential(storageLocation.StorageAccessKeyID.Value, storageLocation.StorageSecretAccessKey.Value)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating shared key credential")
+	}
+
+	client, err := azblob.NewClientWithSharedKeyCredential(serviceURL, cred, opts)
+	return client, errors.Wrap(err, "creating Azure Blob client")
+}
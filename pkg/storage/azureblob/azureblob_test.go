@@ -0,0 +1,305 @@
+package azureblob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+)
+
+const (
+	// Well-known development credentials of Azurite
+	azuriteAccount = "devstoreaccount1"
+	azuriteKey     = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+	testContainer = "backups"
+)
+
+type (
+	// fakeBlobService implements the parts of the Blob service API used
+	// by the Storage: Single-request uploads, properties and (ranged)
+	// downloads, all honoring the If-Match / If-None-Match conditions
+	fakeBlobService struct {
+		blobs map[string]fakeBlob
+		lock  sync.Mutex
+		seq   int
+	}
+
+	fakeBlob struct {
+		content []byte
+		etag    string
+	}
+)
+
+func newFakeBlobService(t *testing.T) string {
+	f := &fakeBlobService{blobs: map[string]fakeBlob{}}
+
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	return srv.URL + "/" + azuriteAccount
+}
+
+func (f *fakeBlobService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	name := strings.TrimPrefix(r.URL.Path, "/"+azuriteAccount+"/")
+	b, exists := f.blobs[name]
+
+	switch {
+	case r.Method == http.MethodPut && r.Header.Get("If-None-Match") == "*" && exists:
+		f.fail(w, http.StatusConflict, "BlobAlreadyExists")
+		return
+
+	case r.Header.Get("If-Match") != "" && (!exists || r.Header.Get("If-Match") != b.etag):
+		f.fail(w, http.StatusPreconditionFailed, "ConditionNotMet")
+		return
+
+	case r.Method != http.MethodPut && !exists:
+		f.fail(w, http.StatusNotFound, "BlobNotFound")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			f.fail(w, http.StatusBadRequest, "InvalidInput")
+			return
+		}
+
+		f.seq++
+		f.blobs[name] = fakeBlob{content: content, etag: fmt.Sprintf(`"0x%d"`, f.seq)}
+		w.Header().Set("ETag", f.blobs[name].etag)
+		w.WriteHeader(http.StatusCreated)
+
+	case http.MethodHead:
+		w.Header().Set("Content-Length", strconv.Itoa(len(b.content)))
+		w.Header().Set("ETag", b.etag)
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodGet:
+		var (
+			content = b.content
+			status  = http.StatusOK
+			start   int
+			end     = len(b.content) - 1
+		)
+
+		if rng := r.Header.Get("x-ms-range"); rng != "" {
+			if _, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); err != nil {
+				f.fail(w, http.StatusBadRequest, "InvalidRange")
+				return
+			}
+			end = min(end, len(b.content)-1)
+			content, status = b.content[start:end+1], http.StatusPartialContent
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(b.content)))
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Header().Set("ETag", b.etag)
+		w.WriteHeader(status)
+		_, _ = w.Write(content)
+
+	default:
+		f.fail(w, http.StatusMethodNotAllowed, "UnsupportedHttpVerb")
+	}
+}
+
+func (*fakeBlobService) fail(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.WriteHeader(status)
+}
+
+func TestNewClient(t *testing.T) {
+	client, err := newClient(&v1.DatabaseBackupStorageLocation{
+		StorageAccessKeyID:     v1.Secret{Value: azuriteAccount},
+		StorageSecretAccessKey: v1.Secret{Value: azuriteKey},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://devstoreaccount1.blob.core.windows.net/", client.URL())
+
+	client, err = newClient(&v1.DatabaseBackupStorageLocation{
+		StorageEndpoint: "http://127.0.0.1:10000/devstoreaccount1",
+		StorageSASToken: v1.Secret{Value: "?sv=2022-11-02&sig=abc"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:10000/devstoreaccount1?sv=2022-11-02&sig=abc", client.URL())
+
+	_, err = newClient(&v1.DatabaseBackupStorageLocation{
+		StorageAccessKeyID:     v1.Secret{Value: azuriteAccount},
+		StorageSecretAccessKey: v1.Secret{Value: "not base64"},
+	})
+	assert.Error(t, err)
+}
+
+func TestStorageFake(t *testing.T) {
+	var (
+		ctx = context.Background()
+		cfg = &v1.DatabaseBackup{}
+	)
+	cfg.Name, cfg.Namespace = "db", "test"
+
+	stor, err := New(ctx, &v1.DatabaseBackupStorageLocation{
+		StorageType:     "azureblob",
+		StorageEndpoint: newFakeBlobService(t),
+		StorageSASToken: v1.Secret{Value: "sv=2022-11-02&sig=abc"},
+		StorageBucket:   testContainer,
+	}, cfg)
+	require.NoError(t, err)
+
+	// Labels must only be created if there are none yet
+	require.NoError(t, stor.WriteLabels(ctx, strings.NewReader("{}"), ""))
+	assert.ErrorIs(t, stor.WriteLabels(ctx, strings.NewReader("{}"), ""), labelmanager.ErrVersionConflict)
+
+	labels, version, err := stor.ReadLabels(ctx)
+	require.NoError(t, err)
+	require.NoError(t, labels.Close())
+
+	// Labels must only be replaced if they were not changed meanwhile
+	assert.ErrorIs(t, stor.WriteLabels(ctx, strings.NewReader("{}"), `"0x0"`), labelmanager.ErrVersionConflict)
+	require.NoError(t, stor.WriteLabels(ctx, strings.NewReader("{}"), version))
+	assert.ErrorIs(t, stor.WriteLabels(ctx, strings.NewReader("{}"), version), labelmanager.ErrVersionConflict)
+
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	require.NoError(t, stor.UploadEntry(ctx, "backup1", bytes.NewReader(data), int64(len(data))))
+
+	exists, err := stor.EntryExists(ctx, "backup1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = stor.EntryExists(ctx, "backup2")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	r, size, err := stor.DownloadAsReader(ctx, "backup1")
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, r.Close()) })
+	assert.Equal(t, int64(len(data)), size)
+
+	// Ranged read
+	buf := make([]byte, 15)
+	n, err := r.ReadAt(buf, 1234)
+	require.NoError(t, err)
+	assert.Equal(t, 15, n)
+	assert.Equal(t, data[1234:1249], buf)
+
+	// Ranged read across the end
+	n, err = r.ReadAt(buf, size-5)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, data[size-5:], buf[:n])
+
+	// Ranged read beyond the end
+	n, err = r.ReadAt(buf, size)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Zero(t, n)
+
+	// Sequential read
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+
+	// Ranged reads must not mix content of an overwritten blob
+	require.NoError(t, stor.UploadEntry(ctx, "backup1", bytes.NewReader(data), int64(len(data))))
+	_, err = r.ReadAt(buf, 0)
+	assert.Error(t, err)
+}
+
+// TestStorage runs against Azurite when AZURITE_BLOB_ENDPOINT is set
+// (i.e. "http://127.0.0.1:10000/devstoreaccount1")
+func TestStorage(t *testing.T) {
+	endpoint := os.Getenv("AZURITE_BLOB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("AZURITE_BLOB_ENDPOINT not set")
+	}
+
+	var (
+		ctx = context.Background()
+		loc = &v1.DatabaseBackupStorageLocation{
+			StorageType:            "azureblob",
+			StorageEndpoint:        endpoint,
+			StorageAccessKeyID:     v1.Secret{Value: azuriteAccount},
+			StorageSecretAccessKey: v1.Secret{Value: azuriteKey},
+			StorageBucket:          testContainer,
+		}
+		cfg = &v1.DatabaseBackup{}
+	)
+
+	cfg.Name, cfg.Namespace = "db", "test-"+time.Now().Format("20060102150405")
+
+	client, err := newClient(loc)
+	require.NoError(t, err)
+	if _, err = client.CreateContainer(ctx, testContainer, nil); err != nil {
+		require.True(t, bloberror.HasCode(err, bloberror.ContainerAlreadyExists), "creating container: %s", err)
+	}
+
+	stor, err := New(ctx, loc, cfg)
+	require.NoError(t, err)
+
+	backups, err := stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	require.NoError(t, stor.UploadFromReader(ctx, "backup1", bytes.NewReader(data), -1))
+
+	// Labels must have been persisted to the container
+	stor, err = New(ctx, loc, cfg)
+	require.NoError(t, err)
+
+	backups, err = stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backup1"}, backups)
+
+	r, size, err := stor.DownloadPITBackupAsReader(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, r.Close()) })
+	assert.Equal(t, int64(len(data)), size)
+
+	// Ranged read
+	buf := make([]byte, 15)
+	n, err := r.ReadAt(buf, 1234)
+	require.NoError(t, err)
+	assert.Equal(t, 15, n)
+	assert.Equal(t, data[1234:1249], buf)
+
+	// Ranged read across the end
+	n, err = r.ReadAt(buf, size-5)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, 5, n)
+
+	// Sequential read
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+
+	// Label writes based on an outdated state must be rejected
+	assert.ErrorIs(t, stor.WriteLabels(ctx, strings.NewReader("{}"), ""), labelmanager.ErrVersionConflict)
+	assert.ErrorIs(t, stor.WriteLabels(ctx, strings.NewReader("{}"), `"0x0"`), labelmanager.ErrVersionConflict)
+
+	// Blob removed behind our back is dropped from the labels
+	_, err = client.DeleteBlob(ctx, testContainer, stor.storagePath+"/backup1", nil)
+	require.NoError(t, err)
+	require.NoError(t, stor.CleanupBackups(ctx))
+
+	backups, err = stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
@@ -0,0 +1,87 @@
+package azureblob
+
+import (
+	"context"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/pkg/errors"
+
+	"github.com/NectGmbH/db-backup-controller/pkg/storage/helper"
+)
+
+type (
+	// blobReader provides sequential and ranged reads on a blob:
+	// Sequential reads use one streaming download, ReadAt issues a
+	// ranged download for each call
+	blobReader struct {
+		ctx       context.Context //nolint:containedctx // Reader needs the context for every request
+		client    *azblob.Client
+		container string
+		name      string
+		etag      azcore.ETag
+		size      int64
+
+		stream io.ReadCloser
+	}
+)
+
+var _ helper.ReaderAtCloser = (*blobReader)(nil)
+
+// Close implements the io.Closer interface
+func (b *blobReader) Close() error {
+	if b.stream == nil {
+		return nil
+	}
+
+	return errors.Wrap(b.stream.Close(), "closing blob stream")
+}
+
+// Read implements the io.Reader interface
+func (b *blobReader) Read(p []byte) (n int, err error) {
+	if b.stream == nil {
+		if b.stream, err = b.download(blob.HTTPRange{}); err != nil {
+			return 0, errors.Wrap(err, "opening blob stream")
+		}
+	}
+
+	return b.stream.Read(p) //nolint:wrapcheck // Must return unwrapped io.EOF
+}
+
+// ReadAt implements the io.ReaderAt interface
+func (b *blobReader) ReadAt(p []byte, off int64) (n int, err error) {
+	if off >= b.size {
+		// Azure rejects ranges starting beyond the end of the blob
+		return 0, io.EOF
+	}
+
+	r, err := b.download(blob.HTTPRange{Offset: off, Count: int64(len(p))})
+	if err != nil {
+		return 0, errors.Wrap(err, "opening ranged blob reader")
+	}
+	defer r.Close() //nolint:errcheck // Reader is only used for this single read
+
+	n, err = io.ReadFull(r, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		// Read beyond the end of the blob
+		return n, io.EOF
+	}
+
+	return n, errors.Wrap(err, "reading blob range")
+}
+
+func (b *blobReader) download(rng blob.HTTPRange) (io.ReadCloser, error) {
+	resp, err := b.client.DownloadStream(b.ctx, b.container, b.name, &azblob.DownloadStreamOptions{
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: &b.etag},
+		},
+		Range: rng,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "downloading blob")
+	}
+
+	return resp.Body, nil
+}
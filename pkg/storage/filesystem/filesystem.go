@@ -0,0 +1,315 @@
+// Package filesystem provides a storage.Manager for a local
+// directory (i.e. a mounted PVC or NFS share)
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+	"github.com/NectGmbH/db-backup-controller/pkg/storage/helper"
+)
+
+const (
+	dirPermission               = 0o750
+	filePermission              = 0o640
+	labelmanagerStorageFileName = ".labels"
+	labelmanagerLockFileName    = ".labels.lock"
+)
+
+type (
+	// Storage implements the storage.Manager interface to provide
+	// backup storage access in a local directory
+	Storage struct {
+		helper.LabeledStorage
+
+		config          *v1.DatabaseBackupSpec
+		storageLocation *v1.DatabaseBackupStorageLocation
+
+		storagePath string
+	}
+)
+
+var _ helper.Backend = Storage{}
+
+// New creates a new Filesystem Storage inside the StoragePath given
+// in the storage location. The directory must exist.
+func New(_ context.Context, storageLocation *v1.DatabaseBackupStorageLocation, cfg *v1.DatabaseBackup) (*Storage, error) {
+	if storageLocation.StoragePath == "" {
+		return nil, errors.New("storage path is not set")
+	}
+
+	if stat, err := os.Stat(storageLocation.StoragePath); err != nil {
+		return nil, errors.Wrap(err, "getting storage path stat")
+	} else if !stat.IsDir() {
+		return nil, errors.New("storage path is not a directory")
+	}
+
+	stor := &Storage{
+		config:          &cfg.Spec,
+		storageLocation: storageLocation,
+
+		storagePath: strings.Join([]string{cfg.Namespace, cfg.Name}, "-"),
+	}
+	stor.LabeledStorage = helper.LabeledStorage{Backend: stor, Config: stor.config, StoragePath: stor.storagePath}
+
+	return stor, nil
+}
+
+// DownloadAsReader fetches the given backup (must exist) and
+// returns an io.ReadCloser for it
+func (s Storage) DownloadAsReader(_ context.Context, name string) (helper.ReaderAtCloser, int64, error) { //nolint:ireturn,lll // Interface is expecting this
+	filePath, err := s.filePath(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	f, err := os.Open(filePath) //#nosec:G304 // Path is checked to be inside the storage path
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "opening stored file")
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		_ = f.Close() // File is not returned, error is not relevant
+		return nil, 0, errors.Wrap(err, "getting stored file stat")
+	}
+
+	return f, stat.Size(), nil
+}
+
+// ListEntries implements the labelmanager.Store interface listing
+// the files inside the storage path
+func (s Storage) ListEntries(context.Context) ([]string, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(s.storageLocation.StoragePath, s.storagePath))
+	switch {
+	case err == nil:
+		// Directory exists, list it
+
+	case errors.Is(err, fs.ErrNotExist):
+		// Nothing has been stored yet
+		return nil, nil
+
+	default:
+		return nil, errors.Wrap(err, "reading backup directory")
+	}
+
+	var entries []string
+	for _, entry := range dirEntries {
+		if entry.Type().IsRegular() {
+			entries = append(entries, entry.Name())
+		}
+	}
+
+	return entries, nil
+}
+
+// ReadLabels implements the labelmanager.Store interface using the
+// hash of the label file content as version
+func (s Storage) ReadLabels(context.Context) (io.ReadCloser, string, error) {
+	content, err := s.readLabels()
+	if err != nil || content == nil {
+		return nil, "", err
+	}
+
+	return io.NopCloser(bytes.NewReader(content)), contentVersion(content), nil
+}
+
+// WriteLabels implements the labelmanager.Store interface. As the
+// filesystem has no conditional writes the label file is locked while
+// checking the version and replacing the content.
+func (s Storage) WriteLabels(_ context.Context, content io.Reader, version string) error {
+	unlock, err := s.lockLabels()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := s.readLabels()
+	if err != nil {
+		return err
+	}
+
+	if current != nil && version != contentVersion(current) || current == nil && version != "" {
+		return labelmanager.ErrVersionConflict
+	}
+
+	return errors.Wrap(
+		s.writeFile(labelmanagerStorageFileName, content),
+		"storing label file",
+	)
+}
+
+// EntryExists checks whether the file for the given entry exists
+func (s Storage) EntryExists(_ context.Context, name string) (bool, error) {
+	filePath, err := s.filePath(name)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(filePath)
+	switch {
+	case err == nil:
+		return true, nil
+
+	case errors.Is(err, fs.ErrNotExist):
+		return false, nil
+
+	default:
+		return false, errors.Wrap(err, "fetching file stats for backup")
+	}
+}
+
+// UploadEntry stores the data as the file for the given entry
+func (s Storage) UploadEntry(_ context.Context, name string, data io.Reader, _ int64) error {
+	return s.writeFile(name, data)
+}
+
+// filePath returns the path of the given backup inside the storage
+// path and ensures the name does not escape the backup directory
+func (s Storage) filePath(name string) (string, error) {
+	if s.config.UseSingleBackupTarget {
+		return filepath.Join(s.storageLocation.StoragePath, s.storagePath), nil
+	}
+
+	if !filepath.IsLocal(name) || strings.ContainsRune(name, filepath.Separator) {
+		return "", errors.Errorf("invalid backup name %q", name)
+	}
+
+	return filepath.Join(s.storageLocation.StoragePath, s.storagePath, name), nil
+}
+
+// lockLabels takes an exclusive lock on the lock file next to the
+// label file and returns the function to release it again
+func (s Storage) lockLabels() (func(), error) {
+	lockPath, err := s.filePath(labelmanagerLockFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(lockPath), dirPermission); err != nil {
+		return nil, errors.Wrap(err, "creating backup directory")
+	}
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, filePermission) //#nosec:G304 // Path is checked to be inside the storage path
+	if err != nil {
+		return nil, errors.Wrap(err, "opening label lock file")
+	}
+
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		_ = f.Close() // Lock failed, error is not relevant
+		return nil, errors.Wrap(err, "locking label lock file")
+	}
+
+	// Closing the file releases the lock
+	return func() { _ = f.Close() }, nil
+}
+
+// readLabels reads the content of the label file or returns nil if
+// there is no label file yet
+func (s Storage) readLabels() ([]byte, error) {
+	labelPath, err := s.filePath(labelmanagerStorageFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(labelPath) //#nosec:G304 // Path is checked to be inside the storage path
+	switch {
+	case err == nil:
+		return content, nil
+
+	case errors.Is(err, fs.ErrNotExist):
+		// No labels stored yet
+		return nil, nil
+
+	default:
+		return nil, errors.Wrap(err, "reading label storage file")
+	}
+}
+
+// RemoveEntry deletes the file for the given entry
+func (s Storage) RemoveEntry(_ context.Context, name string) (bool, error) {
+	filePath, err := s.filePath(name)
+	if err != nil {
+		return false, err
+	}
+
+	if err = os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, errors.Wrap(err, "deleting file")
+	}
+
+	return true, nil
+}
+
+// writeFile writes the data into a temporary file next to the target
+// and renames it afterwards so the target never contains partial data
+func (s Storage) writeFile(name string, data io.Reader) error {
+	targetPath, err := s.filePath(name)
+	if err != nil {
+		return err
+	}
+
+	targetDir := filepath.Dir(targetPath)
+	if err = os.MkdirAll(targetDir, dirPermission); err != nil {
+		return errors.Wrap(err, "creating backup directory")
+	}
+
+	tmp, err := os.CreateTemp(targetDir, "."+filepath.Base(targetPath)+".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "creating temporary file")
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // File is gone after successful rename
+
+	if err = writeAndSync(tmp, data); err != nil {
+		_ = tmp.Close() // Write already failed, error is not relevant
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "closing temporary file")
+	}
+
+	if err = os.Rename(tmp.Name(), targetPath); err != nil {
+		return errors.Wrap(err, "moving temporary file into place")
+	}
+
+	return syncDir(targetDir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir) //#nosec:G304 // Directory is the one we just wrote to
+	if err != nil {
+		return errors.Wrap(err, "opening directory")
+	}
+	defer d.Close() //nolint:errcheck // Directory was only opened for sync
+
+	return errors.Wrap(d.Sync(), "syncing directory")
+}
+
+func writeAndSync(f *os.File, data io.Reader) error {
+	if err := f.Chmod(filePermission); err != nil {
+		return errors.Wrap(err, "setting file permissions")
+	}
+
+	if _, err := io.Copy(f, data); err != nil {
+		return errors.Wrap(err, "writing file")
+	}
+
+	return errors.Wrap(f.Sync(), "syncing file")
+}
+
+func contentVersion(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
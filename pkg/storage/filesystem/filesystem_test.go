@@ -0,0 +1,253 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+	"github.com/NectGmbH/db-backup-controller/pkg/manifest"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("broken") }
+
+func TestStorage(t *testing.T) {
+	var (
+		ctx = context.Background()
+		loc = &v1.DatabaseBackupStorageLocation{StorageType: "filesystem", StoragePath: t.TempDir()}
+		cfg = &v1.DatabaseBackup{}
+	)
+	cfg.Name, cfg.Namespace = "db", "test"
+
+	stor, err := New(ctx, loc, cfg)
+	require.NoError(t, err)
+
+	backups, err := stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	require.NoError(t, stor.UploadFromReader(ctx, "backup1", bytes.NewReader(data), -1))
+
+	// Failed uploads must neither leave files nor labels behind
+	require.Error(t, stor.UploadFromReader(ctx, "backup2", failingReader{}, -1))
+	entries, err := os.ReadDir(filepath.Join(loc.StoragePath, "test-db"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 3) // backup1, .labels, .labels.lock
+
+	// Labels must have been persisted to the directory
+	stor, err = New(ctx, loc, cfg)
+	require.NoError(t, err)
+
+	backups, err = stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backup1"}, backups)
+
+	name, err := stor.FindPITBackup(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "backup1", name)
+
+	_, err = stor.FindPITBackup(ctx, time.Now().AddDate(-30, 0, 0))
+	assert.ErrorIs(t, err, labelmanager.ErrNoBackupFound)
+
+	r, size, err := stor.DownloadPITBackupAsReader(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, r.Close()) })
+	assert.Equal(t, int64(len(data)), size)
+
+	// Ranged read
+	buf := make([]byte, 15)
+	n, err := r.ReadAt(buf, 1234)
+	require.NoError(t, err)
+	assert.Equal(t, 15, n)
+	assert.Equal(t, data[1234:1249], buf)
+
+	// Sequential read
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+
+	// Names must not escape the backup directory
+	_, _, err = stor.DownloadAsReader(ctx, "../test-db/backup1")
+	assert.Error(t, err)
+
+	// File removed behind our back is dropped from the labels
+	require.NoError(t, os.Remove(filepath.Join(loc.StoragePath, "test-db", "backup1")))
+	require.NoError(t, stor.CleanupBackups(ctx))
+
+	backups, err = stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
+
+func TestSingleBackupTarget(t *testing.T) {
+	var (
+		ctx = context.Background()
+		loc = &v1.DatabaseBackupStorageLocation{StorageType: "filesystem", StoragePath: t.TempDir()}
+		cfg = &v1.DatabaseBackup{Spec: v1.DatabaseBackupSpec{UseSingleBackupTarget: true}}
+	)
+	cfg.Name, cfg.Namespace = "db", "test"
+
+	stor, err := New(ctx, loc, cfg)
+	require.NoError(t, err)
+
+	backups, err := stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	require.NoError(t, stor.UploadFromReader(ctx, "backup1", bytes.NewReader([]byte("first")), -1))
+	require.NoError(t, stor.UploadFromReader(ctx, "backup2", bytes.NewReader([]byte("second")), -1))
+
+	backups, err = stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test-db"}, backups)
+
+	target := filepath.Join(t.TempDir(), "restore")
+	require.NoError(t, stor.DownloadPITBackupToFile(ctx, time.Now(), target))
+
+	content, err := os.ReadFile(target) //#nosec:G304 // Test file
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), content)
+}
+
+func TestNewInvalidPath(t *testing.T) {
+	cfg := &v1.DatabaseBackup{}
+
+	_, err := New(context.Background(), &v1.DatabaseBackupStorageLocation{}, cfg)
+	assert.Error(t, err)
+
+	_, err = New(context.Background(), &v1.DatabaseBackupStorageLocation{StoragePath: filepath.Join(t.TempDir(), "missing")}, cfg)
+	assert.Error(t, err)
+}
+
+func TestWriteLabelsConflict(t *testing.T) {
+	var (
+		ctx = context.Background()
+		loc = &v1.DatabaseBackupStorageLocation{StorageType: "filesystem", StoragePath: t.TempDir()}
+		cfg = &v1.DatabaseBackup{}
+	)
+	cfg.Name, cfg.Namespace = "db", "test"
+
+	stor, err := New(ctx, loc, cfg)
+	require.NoError(t, err)
+
+	content, version, err := stor.ReadLabels(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, content)
+	assert.Empty(t, version)
+
+	require.NoError(t, stor.WriteLabels(ctx, strings.NewReader("{}"), ""))
+	assert.ErrorIs(t, stor.WriteLabels(ctx, strings.NewReader("{}"), ""), labelmanager.ErrVersionConflict)
+
+	content, version, err = stor.ReadLabels(ctx)
+	require.NoError(t, err)
+	require.NoError(t, content.Close())
+
+	require.NoError(t, stor.WriteLabels(ctx, strings.NewReader(`{"a":{}}`), version))
+	assert.ErrorIs(t, stor.WriteLabels(ctx, strings.NewReader("{}"), version), labelmanager.ErrVersionConflict)
+}
+
+func TestReindex(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		loc    = &v1.DatabaseBackupStorageLocation{StorageType: "filesystem", StoragePath: t.TempDir()}
+		cfg    = &v1.DatabaseBackup{}
+		backup = time.Now().UTC().Add(-time.Minute).Format(labelmanager.EntryNameFormat)
+	)
+	cfg.Name, cfg.Namespace = "db", "test"
+
+	stor, err := New(ctx, loc, cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, stor.UploadFromReader(ctx, backup, strings.NewReader("data"), -1))
+	require.NoError(t, os.Mkdir(filepath.Join(loc.StoragePath, "test-db", "subdir"), 0o750))
+
+	entries, err := stor.ListEntries(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{backup, ".labels", ".labels.lock"}, entries)
+
+	// Backups must be found again after losing the labels
+	require.NoError(t, os.Remove(filepath.Join(loc.StoragePath, "test-db", ".labels")))
+
+	backups, err := stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{backup}, backups)
+
+	require.NoError(t, stor.Reindex(ctx))
+	_, err = os.Stat(filepath.Join(loc.StoragePath, "test-db", ".labels"))
+	assert.NoError(t, err)
+}
+
+func TestPin(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		loc    = &v1.DatabaseBackupStorageLocation{StorageType: "filesystem", StoragePath: t.TempDir()}
+		cfg    = &v1.DatabaseBackup{}
+		backup = time.Now().UTC().Format(labelmanager.EntryNameFormat)
+	)
+	cfg.Name, cfg.Namespace = "db", "test"
+	// Labels expire right after being assigned
+	cfg.Spec.RetentionConfig = labelmanager.RetentionConfig{"%Y-%m-%dT%H-%M-%S": time.Nanosecond}
+
+	stor, err := New(ctx, loc, cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, stor.UploadFromReader(ctx, backup, strings.NewReader("data"), -1))
+
+	assert.ErrorIs(t, stor.Pin(ctx, "unknown", "", time.Time{}), labelmanager.ErrEntryNotFound)
+	require.NoError(t, stor.Pin(ctx, backup, "audit", time.Time{}))
+
+	// The backup is expired but must survive the cleanup
+	require.NoError(t, stor.CleanupBackups(ctx))
+	_, err = os.Stat(filepath.Join(loc.StoragePath, "test-db", backup))
+	require.NoError(t, err)
+
+	require.NoError(t, stor.Unpin(ctx, backup))
+	require.NoError(t, stor.CleanupBackups(ctx))
+	_, err = os.Stat(filepath.Join(loc.StoragePath, "test-db", backup))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestManifest(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		loc    = &v1.DatabaseBackupStorageLocation{StorageType: "filesystem", StoragePath: t.TempDir()}
+		cfg    = &v1.DatabaseBackup{}
+		backup = time.Now().UTC().Format(labelmanager.EntryNameFormat)
+	)
+	cfg.Name, cfg.Namespace = "db", "test"
+
+	stor, err := New(ctx, loc, cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, stor.UploadFromReader(ctx, backup, strings.NewReader("data"), -1))
+
+	backups, err := stor.ListBackups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []manifest.Manifest{{Backup: backup}}, backups)
+
+	require.NoError(t, stor.WriteManifest(ctx, manifest.Manifest{Backup: backup, Size: 4}))
+
+	backups, err = stor.ListBackups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []manifest.Manifest{{Backup: backup, Size: 4}}, backups)
+
+	// The manifest is found again after losing the labels
+	require.NoError(t, stor.Reindex(ctx))
+
+	backups, err = stor.ListBackups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []manifest.Manifest{{Backup: backup, Size: 4}}, backups)
+}
@@ -0,0 +1,218 @@
+// Package gcs provides a storage.Manager for Google Cloud Storage
+package gcs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+	"github.com/NectGmbH/db-backup-controller/pkg/storage/helper"
+)
+
+const labelmanagerStorageFileName = ".labels"
+
+type (
+	// Storage implements the storage.Manager interface to provide
+	// backup storage access in Google Cloud Storage
+	Storage struct {
+		helper.LabeledStorage
+
+		client *storage.Client
+
+		config          *v1.DatabaseBackupSpec
+		storageLocation *v1.DatabaseBackupStorageLocation
+
+		storagePath string
+	}
+)
+
+var _ helper.Backend = Storage{}
+
+// New creates a new GCS Storage authenticating with the service
+// account JSON given in the storage location or the application
+// default credentials if none is given
+func New(ctx context.Context, storageLocation *v1.DatabaseBackupStorageLocation, cfg *v1.DatabaseBackup) (*Storage, error) {
+	var opts []option.ClientOption
+
+	if storageLocation.StorageServiceAccountJSON.Value != "" {
+		opts = append(opts, option.WithCredentialsJSON([]byte(storageLocation.StorageServiceAccountJSON.Value)))
+	}
+
+	if storageLocation.StorageEndpoint != "" {
+		opts = append(opts, option.WithEndpoint(storageLocation.StorageEndpoint))
+	}
+
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating GCS client")
+	}
+
+	return newWithClient(client, storageLocation, cfg), nil
+}
+
+func newWithClient(client *storage.Client, storageLocation *v1.DatabaseBackupStorageLocation, cfg *v1.DatabaseBackup) *Storage {
+	s := &Storage{
+		client: client,
+
+		config:          &cfg.Spec,
+		storageLocation: storageLocation,
+
+		storagePath: strings.Join([]string{cfg.Namespace, cfg.Name}, "-"),
+	}
+	s.LabeledStorage = helper.LabeledStorage{Backend: s, Config: s.config, StoragePath: s.storagePath}
+
+	return s
+}
+
+// DownloadAsReader fetches the given backup (must exist) and
+// returns an io.ReadCloser for it
+func (s Storage) DownloadAsReader(ctx context.Context, name string) (helper.ReaderAtCloser, int64, error) { //nolint:ireturn,lll // Interface is expecting this
+	obj := s.object(s.objectName(name))
+
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "getting stored object attributes")
+	}
+
+	// Pin the generation so all ranged reads see the same content
+	// even if the object is overwritten in the meantime
+	return &objectReader{ctx: ctx, obj: obj.Generation(attrs.Generation)}, attrs.Size, nil
+}
+
+// ListEntries implements the labelmanager.Store interface listing
+// the objects inside the storage path
+func (s Storage) ListEntries(ctx context.Context) ([]string, error) {
+	var (
+		entries []string
+		it      = s.client.Bucket(s.storageLocation.StorageBucket).Objects(ctx, &storage.Query{
+			Delimiter: "/",
+			Prefix:    s.storagePath + "/",
+		})
+	)
+
+	for {
+		attrs, err := it.Next()
+		switch {
+		case errors.Is(err, iterator.Done):
+			return entries, nil
+
+		case err != nil:
+			return nil, errors.Wrap(err, "listing objects")
+
+		case attrs.Name == "":
+			// Synthetic entry for a sub-directory
+			continue
+		}
+
+		entries = append(entries, path.Base(attrs.Name))
+	}
+}
+
+// ReadLabels implements the labelmanager.Store interface using the
+// generation of the label object as version
+func (s Storage) ReadLabels(ctx context.Context) (io.ReadCloser, string, error) {
+	r, err := s.object(path.Join(s.storagePath, labelmanagerStorageFileName)).NewReader(ctx)
+	switch {
+	case err == nil:
+		return r, strconv.FormatInt(r.Attrs.Generation, 10), nil
+
+	case errors.Is(err, storage.ErrObjectNotExist):
+		// No labels stored yet
+		return nil, "", nil
+
+	default:
+		return nil, "", errors.Wrap(err, "fetching label storage object")
+	}
+}
+
+// WriteLabels implements the labelmanager.Store interface using
+// generation preconditions on the label object
+func (s Storage) WriteLabels(ctx context.Context, content io.Reader, version string) error {
+	cond := storage.Conditions{DoesNotExist: true}
+	if version != "" {
+		gen, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "parsing label object generation")
+		}
+		cond = storage.Conditions{GenerationMatch: gen}
+	}
+
+	err := s.writeObject(ctx, s.object(path.Join(s.storagePath, labelmanagerStorageFileName)).If(cond), content)
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+		return labelmanager.ErrVersionConflict
+	}
+
+	return err
+}
+
+// EntryExists checks whether the object for the given entry exists
+func (s Storage) EntryExists(ctx context.Context, name string) (bool, error) {
+	_, err := s.object(s.objectName(name)).Attrs(ctx)
+	switch {
+	case err == nil:
+		return true, nil
+
+	case errors.Is(err, storage.ErrObjectNotExist):
+		return false, nil
+
+	default:
+		return false, errors.Wrap(err, "fetching object stats for backup")
+	}
+}
+
+// RemoveEntry deletes the object for the given entry
+func (s Storage) RemoveEntry(ctx context.Context, name string) (bool, error) {
+	err := s.object(s.objectName(name)).Delete(ctx)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return false, errors.Wrap(err, "deleting object")
+	}
+
+	return true, nil
+}
+
+// UploadEntry stores the data as the object for the given entry
+func (s Storage) UploadEntry(ctx context.Context, name string, data io.Reader, _ int64) error {
+	return s.writeObject(ctx, s.object(s.objectName(name)), data)
+}
+
+func (s Storage) object(name string) *storage.ObjectHandle {
+	return s.client.Bucket(s.storageLocation.StorageBucket).Object(name)
+}
+
+// objectName returns the name of the object storing the given entry
+func (s Storage) objectName(name string) string {
+	if s.config.UseSingleBackupTarget {
+		return s.storagePath
+	}
+
+	return path.Join(s.storagePath, name)
+}
+
+func (Storage) writeObject(ctx context.Context, obj *storage.ObjectHandle, data io.Reader) error {
+	// Cancelling the context is the only way to abort an upload so the
+	// object is not created from partial data
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := obj.NewWriter(ctx)
+	if _, err := io.Copy(w, data); err != nil {
+		cancel()
+		_ = w.Close() // The upload was cancelled, this will yield the context error
+		return errors.Wrap(err, "uploading object")
+	}
+
+	return errors.Wrap(w.Close(), "finishing object upload")
+}
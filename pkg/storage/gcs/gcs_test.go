@@ -0,0 +1,167 @@
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsouza/fake-gcs-server/fakestorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+)
+
+const testBucket = "backups"
+
+func TestStorage(t *testing.T) {
+	var (
+		ctx = context.Background()
+		srv = fakestorage.NewServer(nil)
+		loc = &v1.DatabaseBackupStorageLocation{StorageType: "gcs", StorageBucket: testBucket}
+		cfg = &v1.DatabaseBackup{}
+	)
+	t.Cleanup(srv.Stop)
+
+	srv.CreateBucketWithOpts(fakestorage.CreateBucketOpts{Name: testBucket})
+	cfg.Name, cfg.Namespace = "db", "test"
+
+	stor := newWithClient(srv.Client(), loc, cfg)
+
+	backups, err := stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	require.NoError(t, stor.UploadFromReader(ctx, "backup1", bytes.NewReader(data), -1))
+
+	// Labels must have been persisted to the bucket
+	stor = newWithClient(srv.Client(), loc, cfg)
+
+	backups, err = stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backup1"}, backups)
+
+	r, size, err := stor.DownloadPITBackupAsReader(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, r.Close()) })
+	assert.Equal(t, int64(len(data)), size)
+
+	// Ranged read
+	buf := make([]byte, 15)
+	n, err := r.ReadAt(buf, 1234)
+	require.NoError(t, err)
+	assert.Equal(t, 15, n)
+	assert.Equal(t, data[1234:1249], buf)
+
+	// Ranged read beyond the end
+	n, err = r.ReadAt(buf, size-5)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, 5, n)
+
+	// Sequential read
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+
+	// Backups removed behind our back are dropped from the labels
+	require.NoError(t, srv.Client().Bucket(testBucket).Object("test-db/backup1").Delete(ctx))
+	require.NoError(t, stor.CleanupBackups(ctx))
+
+	backups, err = stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
+
+func TestWriteLabelsConflict(t *testing.T) {
+	var (
+		ctx = context.Background()
+		srv = fakestorage.NewServer(nil)
+		loc = &v1.DatabaseBackupStorageLocation{StorageType: "gcs", StorageBucket: testBucket}
+		cfg = &v1.DatabaseBackup{}
+	)
+	t.Cleanup(srv.Stop)
+
+	srv.CreateBucketWithOpts(fakestorage.CreateBucketOpts{Name: testBucket})
+	cfg.Name, cfg.Namespace = "db", "test"
+
+	stor := newWithClient(srv.Client(), loc, cfg)
+
+	content, version, err := stor.ReadLabels(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, content)
+	assert.Empty(t, version)
+
+	require.NoError(t, stor.WriteLabels(ctx, strings.NewReader("{}"), ""))
+	assert.ErrorIs(t, stor.WriteLabels(ctx, strings.NewReader("{}"), ""), labelmanager.ErrVersionConflict)
+
+	content, version, err = stor.ReadLabels(ctx)
+	require.NoError(t, err)
+	require.NoError(t, content.Close())
+
+	require.NoError(t, stor.WriteLabels(ctx, strings.NewReader("{}"), version))
+	assert.ErrorIs(t, stor.WriteLabels(ctx, strings.NewReader("{}"), version), labelmanager.ErrVersionConflict)
+}
+
+func TestReindex(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		srv    = fakestorage.NewServer(nil)
+		loc    = &v1.DatabaseBackupStorageLocation{StorageType: "gcs", StorageBucket: testBucket}
+		cfg    = &v1.DatabaseBackup{}
+		backup = time.Now().UTC().Add(-time.Minute).Format(labelmanager.EntryNameFormat)
+	)
+	t.Cleanup(srv.Stop)
+
+	srv.CreateBucketWithOpts(fakestorage.CreateBucketOpts{Name: testBucket})
+	cfg.Name, cfg.Namespace = "db", "test"
+
+	stor := newWithClient(srv.Client(), loc, cfg)
+
+	require.NoError(t, stor.UploadFromReader(ctx, backup, strings.NewReader("data"), -1))
+	require.NoError(t, stor.writeObject(ctx, stor.object("test-db/nested/object"), strings.NewReader("data")))
+
+	entries, err := stor.ListEntries(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{backup, ".labels"}, entries)
+
+	// Backups must be found again after losing the labels
+	require.NoError(t, stor.object("test-db/.labels").Delete(ctx))
+
+	backups, err := stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{backup}, backups)
+
+	require.NoError(t, stor.Reindex(ctx))
+	_, err = stor.object("test-db/.labels").Attrs(ctx)
+	assert.NoError(t, err)
+}
+
+func TestSingleBackupTarget(t *testing.T) {
+	var (
+		ctx = context.Background()
+		srv = fakestorage.NewServer(nil)
+		loc = &v1.DatabaseBackupStorageLocation{StorageType: "gcs", StorageBucket: testBucket}
+		cfg = &v1.DatabaseBackup{Spec: v1.DatabaseBackupSpec{UseSingleBackupTarget: true}}
+	)
+	t.Cleanup(srv.Stop)
+
+	srv.CreateBucketWithOpts(fakestorage.CreateBucketOpts{Name: testBucket})
+	cfg.Name, cfg.Namespace = "db", "test"
+
+	stor := newWithClient(srv.Client(), loc, cfg)
+
+	backups, err := stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	require.NoError(t, stor.UploadFromReader(ctx, "ignored", bytes.NewReader([]byte("backup")), -1))
+
+	backups, err = stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test-db"}, backups)
+}
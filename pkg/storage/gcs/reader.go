@@ -0,0 +1,62 @@
+package gcs
+
+import (
+	"context"
+	"io"
+
+	"cloud.google.com/go/storage"
+	"github.com/pkg/errors"
+
+	"github.com/NectGmbH/db-backup-controller/pkg/storage/helper"
+)
+
+type (
+	// objectReader provides sequential and ranged reads on an object:
+	// Sequential reads use one streaming download, ReadAt issues a
+	// ranged download for each call
+	objectReader struct {
+		ctx context.Context //nolint:containedctx // Reader needs the context for every request
+		obj *storage.ObjectHandle
+
+		stream *storage.Reader
+	}
+)
+
+var _ helper.ReaderAtCloser = (*objectReader)(nil)
+
+// Close implements the io.Closer interface
+func (o *objectReader) Close() error {
+	if o.stream == nil {
+		return nil
+	}
+
+	return errors.Wrap(o.stream.Close(), "closing object stream")
+}
+
+// Read implements the io.Reader interface
+func (o *objectReader) Read(p []byte) (n int, err error) {
+	if o.stream == nil {
+		if o.stream, err = o.obj.NewReader(o.ctx); err != nil {
+			return 0, errors.Wrap(err, "opening object stream")
+		}
+	}
+
+	return o.stream.Read(p) //nolint:wrapcheck // Must return unwrapped io.EOF
+}
+
+// ReadAt implements the io.ReaderAt interface
+func (o *objectReader) ReadAt(p []byte, off int64) (n int, err error) {
+	r, err := o.obj.NewRangeReader(o.ctx, off, int64(len(p)))
+	if err != nil {
+		return 0, errors.Wrap(err, "opening ranged object reader")
+	}
+	defer r.Close() //nolint:errcheck // Reader is only used for this single read
+
+	n, err = io.ReadFull(r, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		// Read beyond the end of the object
+		return n, io.EOF
+	}
+
+	return n, errors.Wrap(err, "reading object range")
+}
@@ -1,7 +1,10 @@
 // Package helper contains simple utilities to help with storage management
 package helper
 
-import "io"
+import (
+	"errors"
+	"io"
+)
 
 type (
 	// ReaderAtCloser combines ReaderAt and ReadCloser interfaces
@@ -10,3 +13,7 @@ type (
 		io.ReaderAt
 	}
 )
+
+// ErrSingleBackupTarget signalizes the operation requires the label
+// management which is not used for single backup targets
+var ErrSingleBackupTarget = errors.New("not supported for single backup target")
@@ -0,0 +1,295 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+	"github.com/NectGmbH/db-backup-controller/pkg/manifest"
+)
+
+type (
+	// Backend describes the primitive operations of a storage the
+	// LabeledStorage builds the label management upon. Entries are
+	// addressed by their name inside the storage path, when using a
+	// single backup target all names refer to the single target.
+	Backend interface {
+		labelmanager.Store
+
+		// DownloadAsReader fetches the given entry (must exist) and
+		// returns an io.ReadCloser for it
+		DownloadAsReader(ctx context.Context, name string) (ReaderAtCloser, int64, error)
+		// EntryExists checks whether the given entry exists
+		EntryExists(ctx context.Context, name string) (bool, error)
+		// RemoveEntry deletes the given entry and reports false if it
+		// needs to be kept for now (i.e. because of a storage lock)
+		RemoveEntry(ctx context.Context, name string) (removed bool, err error)
+		// UploadEntry stores the data as the given entry without
+		// modifying the labels
+		UploadEntry(ctx context.Context, name string, data io.Reader, size int64) error
+	}
+
+	// LabeledStorage implements the parts of the storage.Manager shared
+	// by all storages on top of their Backend: The retention labels,
+	// pins and manifests of the backups
+	LabeledStorage struct {
+		Backend Backend
+		Config  *v1.DatabaseBackupSpec
+		// StoragePath is the name of the single backup target or the
+		// directory the entries are stored in
+		StoragePath string
+	}
+)
+
+// CleanupBackups takes care of removing expired backups from the
+// remote storage
+func (l LabeledStorage) CleanupBackups(ctx context.Context) (err error) {
+	if l.Config.UseSingleBackupTarget {
+		// We don't clean that up.
+		return nil
+	}
+
+	return errors.Wrap(
+		labelmanager.Cleanup(ctx, l.Backend, l.Config.RetentionPolicy(), l.Backend.RemoveEntry, l.Backend.EntryExists),
+		"cleaning up backups",
+	)
+}
+
+// DownloadToFile fetches the given backup (must exist) and saves
+// it to the given targetPath
+func (l LabeledStorage) DownloadToFile(ctx context.Context, name, targetPath string) error {
+	f, err := os.Create(targetPath) //#nosec:G304 // This library is intended to write to passed file
+	if err != nil {
+		return errors.Wrap(err, "creating target file")
+	}
+	defer f.Close() //nolint:errcheck // This might leak FDs but this is a library and should not log
+
+	obj, _, err := l.Backend.DownloadAsReader(ctx, name)
+	if err != nil {
+		return errors.Wrap(err, "getting reader for backup")
+	}
+	defer obj.Close() //nolint:errcheck // This might leak FDs but this is a library and should not log
+
+	_, err = io.Copy(f, obj)
+	return errors.Wrap(err, "copying backup to target file")
+}
+
+// DownloadPITBackupAsReader takes a Point-in-Time, fetches the
+// closest older backup to that point-in-time and returns an
+// io.ReadCloser for it
+func (l LabeledStorage) DownloadPITBackupAsReader(ctx context.Context, pit time.Time) (ReaderAtCloser, int64, error) { //nolint:ireturn,lll // Interface is expecting this
+	backup, err := l.FindPITBackup(ctx, pit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return l.Backend.DownloadAsReader(ctx, backup)
+}
+
+// DownloadPITBackupToFile takes a Point-in-Time and downloads the
+// closest older backup to that point-in-time to the given path
+func (l LabeledStorage) DownloadPITBackupToFile(ctx context.Context, pit time.Time, targetPath string) error {
+	backup, err := l.FindPITBackup(ctx, pit)
+	if err != nil {
+		return err
+	}
+
+	return l.DownloadToFile(ctx, backup, targetPath)
+}
+
+// FindPITBackup returns the name of the closest older backup to the
+// given point-in-time (empty when using a single backup target)
+func (l LabeledStorage) FindPITBackup(ctx context.Context, pit time.Time) (string, error) {
+	if l.Config.UseSingleBackupTarget {
+		// There is no point in time, there is only the single target
+		return "", nil
+	}
+
+	labels, err := labelmanager.Load(ctx, l.Backend, l.Config.RetentionPolicy())
+	if err != nil {
+		return "", errors.Wrap(err, "loading labels")
+	}
+
+	backup, err := labels.GetClosestOlderBackup(pit)
+	return backup, errors.Wrap(err, "finding backup for given time")
+}
+
+// ListAvailableBackups fetches a list of backups stored on the
+// remote storage and returns the names suitable for DownloadToFile
+func (l LabeledStorage) ListAvailableBackups(ctx context.Context) ([]string, error) {
+	if l.Config.UseSingleBackupTarget {
+		// Special case: There can be only one - or none - lets see what's the case
+		found, err := l.Backend.EntryExists(ctx, "")
+		if err != nil || !found {
+			return nil, errors.Wrap(err, "checking backup existence")
+		}
+		return []string{l.StoragePath}, nil
+	}
+
+	// Not a single target backup, lets ask the label manager
+	labels, err := labelmanager.Load(ctx, l.Backend, l.Config.RetentionPolicy())
+	if err != nil {
+		return nil, errors.Wrap(err, "loading labels")
+	}
+
+	return labels.GetRetainedEntries(), nil
+}
+
+// ListBackups fetches the manifests of the backups stored on the
+// remote storage. Backups stored without manifest are described by
+// their name only.
+func (l LabeledStorage) ListBackups(ctx context.Context) ([]manifest.Manifest, error) {
+	if l.Config.UseSingleBackupTarget {
+		names, err := l.ListAvailableBackups(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return manifest.Collect(ctx, names, func(string) (string, bool) { return "", false }, nil)
+	}
+
+	labels, err := labelmanager.Load(ctx, l.Backend, l.Config.RetentionPolicy())
+	if err != nil {
+		return nil, errors.Wrap(err, "loading labels")
+	}
+
+	manifests, err := manifest.Collect(ctx, labels.GetRetainedEntries(), labels.GetManifest,
+		func(ctx context.Context, name string) (io.ReadCloser, error) {
+			r, _, err := l.Backend.DownloadAsReader(ctx, name)
+			return r, err
+		})
+	return manifests, errors.Wrap(err, "collecting manifests")
+}
+
+// Pin protects the given backup from being removed by the cleanup
+// until the given point in time (forever for the zero time)
+func (l LabeledStorage) Pin(ctx context.Context, backup, reason string, until time.Time) error {
+	if l.Config.UseSingleBackupTarget {
+		return ErrSingleBackupTarget
+	}
+
+	_, err := labelmanager.Update(ctx, l.Backend, l.Config.RetentionPolicy(), func(m *labelmanager.Manager) error {
+		return m.Pin(backup, reason, until)
+	})
+	return errors.Wrap(err, "pinning backup")
+}
+
+// PlanRetention explains the retention of the backups under the given
+// RetentionPolicy (the configured one if nil) without modifying them.
+// If relabel is set the labels are re-evaluated before.
+func (l LabeledStorage) PlanRetention(ctx context.Context, policy *labelmanager.RetentionPolicy, relabel bool) ([]labelmanager.EntryPlan, error) {
+	if l.Config.UseSingleBackupTarget {
+		// Not using label manager
+		return nil, nil
+	}
+
+	if policy == nil {
+		configured := l.Config.RetentionPolicy()
+		policy = &configured
+	}
+
+	plans, err := labelmanager.Plan(ctx, l.Backend, *policy, relabel)
+	return plans, errors.Wrap(err, "planning retention")
+}
+
+// Reindex rebuilds the retention labels from the backups present in
+// the remote storage
+func (l LabeledStorage) Reindex(ctx context.Context) error {
+	if l.Config.UseSingleBackupTarget {
+		// Not using label manager
+		return nil
+	}
+
+	_, err := labelmanager.Reindex(ctx, l.Backend, l.Config.RetentionPolicy())
+	return errors.Wrap(err, "reindexing labels")
+}
+
+// Relabel re-evaluates the retention labels of the backups under the
+// current RetentionPolicy. Without apply only the changes are returned.
+func (l LabeledStorage) Relabel(ctx context.Context, apply bool) ([]labelmanager.LabelChange, error) {
+	if l.Config.UseSingleBackupTarget {
+		// Not using label manager
+		return nil, nil
+	}
+
+	changes, err := labelmanager.Relabel(ctx, l.Backend, l.Config.RetentionPolicy(), apply)
+	return changes, errors.Wrap(err, "relabeling backups")
+}
+
+// Unpin removes the pin of the given backup
+func (l LabeledStorage) Unpin(ctx context.Context, backup string) error {
+	if l.Config.UseSingleBackupTarget {
+		return ErrSingleBackupTarget
+	}
+
+	_, err := labelmanager.Update(ctx, l.Backend, l.Config.RetentionPolicy(), func(m *labelmanager.Manager) error {
+		return m.Unpin(backup)
+	})
+	return errors.Wrap(err, "unpinning backup")
+}
+
+// UploadFromFile takes a local file and uploads the contents under
+// the filename the file on the filesystem has
+func (l LabeledStorage) UploadFromFile(ctx context.Context, filePath string) error {
+	f, err := os.Open(filePath) //#nosec:G304 // This library is intended to work with the given file
+	if err != nil {
+		return errors.Wrap(err, "opening source file")
+	}
+	defer f.Close() //nolint:errcheck // This might leak FDs but this is a library and should not log
+
+	stat, err := f.Stat()
+	if err != nil {
+		return errors.Wrap(err, "getting file-stat")
+	}
+
+	return l.UploadFromReader(ctx, path.Base(filePath), f, stat.Size())
+}
+
+// UploadFromReader takes a name and a reader to upload a backup
+// to the remote storage. The name is used as storage name and
+// later available in ListAvailableBackups and DownloadToFile
+func (l LabeledStorage) UploadFromReader(ctx context.Context, name string, data io.Reader, size int64) (err error) {
+	if err = l.Backend.UploadEntry(ctx, name, data, size); err != nil {
+		return err
+	}
+
+	if l.Config.UseSingleBackupTarget {
+		// Not using label manager
+		return nil
+	}
+
+	// Add the new file to the label manager
+	_, err = labelmanager.Update(ctx, l.Backend, l.Config.RetentionPolicy(), func(m *labelmanager.Manager) error {
+		return m.Add(name)
+	})
+	return errors.Wrap(err, "adding to label manager")
+}
+
+// WriteManifest stores the manifest next to its backup and references
+// it in the labels of the backup
+func (l LabeledStorage) WriteManifest(ctx context.Context, m manifest.Manifest) error {
+	if l.Config.UseSingleBackupTarget {
+		return ErrSingleBackupTarget
+	}
+
+	buf := new(bytes.Buffer)
+	if err := m.Write(buf); err != nil {
+		return errors.Wrap(err, "encoding manifest")
+	}
+
+	if err := l.Backend.UploadEntry(ctx, manifest.Name(m.Backup), buf, int64(buf.Len())); err != nil {
+		return errors.Wrap(err, "uploading manifest")
+	}
+
+	_, err := labelmanager.Update(ctx, l.Backend, l.Config.RetentionPolicy(), func(labels *labelmanager.Manager) error {
+		return labels.SetManifest(m.Backup, manifest.Name(m.Backup))
+	})
+	return errors.Wrap(err, "referencing manifest")
+}
@@ -0,0 +1,209 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+	"github.com/NectGmbH/db-backup-controller/pkg/manifest"
+)
+
+type (
+	memBackend struct {
+		entries map[string][]byte
+		labels  []byte
+		lock    sync.Mutex
+		version int
+	}
+
+	memReader struct{ *bytes.Reader }
+)
+
+var _ Backend = &memBackend{}
+
+func newMemBackend() *memBackend { return &memBackend{entries: map[string][]byte{}} }
+
+func (memReader) Close() error { return nil }
+
+func (m *memBackend) DownloadAsReader(_ context.Context, name string) (ReaderAtCloser, int64, error) { //nolint:ireturn // Interface is expecting this
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	data, ok := m.entries[name]
+	if !ok {
+		return nil, 0, errors.New("not found")
+	}
+
+	return memReader{bytes.NewReader(data)}, int64(len(data)), nil
+}
+
+func (m *memBackend) EntryExists(_ context.Context, name string) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, ok := m.entries[name]
+	return ok, nil
+}
+
+func (m *memBackend) ListEntries(context.Context) ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var names []string
+	for name := range m.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (m *memBackend) ReadLabels(context.Context) (io.ReadCloser, string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.labels == nil {
+		return nil, "", nil
+	}
+
+	return io.NopCloser(bytes.NewReader(m.labels)), strconv.Itoa(m.version), nil
+}
+
+func (m *memBackend) RemoveEntry(_ context.Context, name string) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.entries, name)
+	return true, nil
+}
+
+func (m *memBackend) UploadEntry(_ context.Context, name string, data io.Reader, _ int64) error {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.entries[name] = content
+	return nil
+}
+
+func (m *memBackend) WriteLabels(_ context.Context, content io.Reader, version string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	current := ""
+	if m.labels != nil {
+		current = strconv.Itoa(m.version)
+	}
+
+	if version != current {
+		return labelmanager.ErrVersionConflict
+	}
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	m.labels = data
+	m.version++
+	return nil
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("broken") }
+
+func TestLabeledStorage(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		backend = newMemBackend()
+		cfg     = &v1.DatabaseBackupSpec{RetentionConfig: labelmanager.RetentionConfig{"%Y-%m-%dT%H": time.Hour}}
+		stor    = LabeledStorage{Backend: backend, Config: cfg, StoragePath: "test-db"}
+	)
+
+	// Two backups whose labels already expired
+	for i, name := range []string{"backup1", "backup2"} {
+		require.NoError(t, backend.UploadEntry(ctx, name, bytes.NewReader([]byte(name)), -1))
+
+		createdAt := time.Now().Add(-time.Duration(i+2) * time.Hour)
+		_, err := labelmanager.Update(ctx, backend, cfg.RetentionPolicy(), func(m *labelmanager.Manager) error {
+			return m.AddAt(name, createdAt)
+		})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, stor.UploadFromReader(ctx, "backup3", bytes.NewReader([]byte("backup3")), -1))
+	require.NoError(t, stor.WriteManifest(ctx, manifest.Manifest{Backup: "backup3"}))
+
+	// Failed uploads must not be added to the labels
+	require.Error(t, stor.UploadFromReader(ctx, "backup4", failingReader{}, -1))
+
+	plans, err := stor.PlanRetention(ctx, nil, false)
+	require.NoError(t, err)
+	assert.Len(t, plans, 3)
+
+	// Only the pinned expired backup must survive the cleanup
+	require.NoError(t, stor.Pin(ctx, "backup1", "test", time.Time{}))
+	require.NoError(t, stor.CleanupBackups(ctx))
+
+	exists, err := backend.EntryExists(ctx, "backup2")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	backups, err := stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"backup1", "backup3"}, backups)
+
+	name, err := stor.FindPITBackup(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "backup3", name)
+
+	manifests, err := stor.ListBackups(ctx)
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+
+	require.NoError(t, stor.Unpin(ctx, "backup1"))
+	require.NoError(t, stor.CleanupBackups(ctx))
+
+	backups, err = stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backup3"}, backups)
+}
+
+func TestLabeledStorageSingleTarget(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		backend = newMemBackend()
+		stor    = LabeledStorage{Backend: backend, Config: &v1.DatabaseBackupSpec{UseSingleBackupTarget: true}, StoragePath: "test-db"}
+	)
+
+	backups, err := stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	require.NoError(t, stor.UploadFromReader(ctx, "", bytes.NewReader([]byte("one")), 3))
+	assert.Nil(t, backend.labels, "single target must not use labels")
+
+	backups, err = stor.ListAvailableBackups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test-db"}, backups)
+
+	assert.ErrorIs(t, stor.Pin(ctx, "test-db", "test", time.Time{}), ErrSingleBackupTarget)
+	assert.ErrorIs(t, stor.WriteManifest(ctx, manifest.Manifest{Backup: "test-db"}), ErrSingleBackupTarget)
+	assert.NoError(t, stor.CleanupBackups(ctx))
+}
@@ -8,6 +8,11 @@ import (
 	"time"
 
 	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+	"github.com/NectGmbH/db-backup-controller/pkg/manifest"
+	"github.com/NectGmbH/db-backup-controller/pkg/storage/azureblob"
+	"github.com/NectGmbH/db-backup-controller/pkg/storage/filesystem"
+	"github.com/NectGmbH/db-backup-controller/pkg/storage/gcs"
 	"github.com/NectGmbH/db-backup-controller/pkg/storage/helper"
 	"github.com/NectGmbH/db-backup-controller/pkg/storage/s3"
 )
@@ -32,9 +37,38 @@ type (
 		// DownloadPITBackupToFile takes a Point-in-Time and downloads the
 		// closest older backup to that point-in-time to the given path
 		DownloadPITBackupToFile(ctx context.Context, pit time.Time, targetPath string) error
+		// FindPITBackup returns the name of the closest older backup to
+		// the given point-in-time (empty when using a single backup
+		// target)
+		FindPITBackup(ctx context.Context, pit time.Time) (string, error)
+		// ListBackups fetches the manifests of the backups stored on the
+		// remote storage. Backups stored without manifest are described
+		// by their name only.
+		ListBackups(ctx context.Context) ([]manifest.Manifest, error)
 		// ListAvailableBackups fetches a list of backups stored on the
 		// remote storage and returns the names suitable for DownloadToFile
 		ListAvailableBackups(ctx context.Context) ([]string, error)
+		// Pin protects the given backup from being removed by the
+		// cleanup until the given point in time (forever for the zero
+		// time)
+		Pin(ctx context.Context, backup, reason string, until time.Time) error
+		// PlanRetention explains the retention of the backups under the
+		// given RetentionPolicy (the configured one if nil) without
+		// modifying them. If relabel is set the labels are re-evaluated
+		// before.
+		PlanRetention(ctx context.Context, policy *labelmanager.RetentionPolicy, relabel bool) ([]labelmanager.EntryPlan, error)
+		// Relabel re-evaluates the retention labels of the backups under
+		// the current RetentionPolicy. Without apply only the changes are
+		// returned.
+		Relabel(ctx context.Context, apply bool) ([]labelmanager.LabelChange, error)
+		// Reindex rebuilds the retention labels from the backups present
+		// in the remote storage
+		Reindex(ctx context.Context) error
+		// Unpin removes the pin of the given backup
+		Unpin(ctx context.Context, backup string) error
+		// WriteManifest stores the manifest next to its backup and
+		// references it in the labels of the backup
+		WriteManifest(ctx context.Context, m manifest.Manifest) error
 		// UploadFromFile takes a local file and uploads the contents under
 		// the filename the file on the filesystem has
 		UploadFromFile(ctx context.Context, filePath string) error
@@ -52,6 +86,15 @@ var ErrStorageEngineUnknown = errors.New("specified storage engine is unknown")
 // New creates a new preconfigured instance of the desired storage engine
 func New(ctx context.Context, storageLocation *v1.DatabaseBackupStorageLocation, cfg *v1.DatabaseBackup) (Manager, error) { //nolint:ireturn,lll // This is a registry
 	switch storageLocation.StorageType {
+	case "azureblob":
+		return azureblob.New(ctx, storageLocation, cfg) //nolint:wrapcheck // It's fine to return the error unwrapped as this is only a registry
+
+	case "filesystem":
+		return filesystem.New(ctx, storageLocation, cfg) //nolint:wrapcheck // It's fine to return the error unwrapped as this is only a registry
+
+	case "gcs":
+		return gcs.New(ctx, storageLocation, cfg) //nolint:wrapcheck // It's fine to return the error unwrapped as this is only a registry
+
 	case "s3":
 		return s3.New(ctx, storageLocation, cfg) //nolint:wrapcheck // It's fine to return the error unwrapped as this is only a registry
 
@@ -0,0 +1,128 @@
+package s3
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/pkg/errors"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+)
+
+const (
+	// CredentialsSourceChain tries static credentials (if set), the
+	// environment and IAM in that order
+	CredentialsSourceChain = "chain"
+	// CredentialsSourceEnv reads AWS_* or MINIO_* environment variables
+	CredentialsSourceEnv = "env"
+	// CredentialsSourceIAM uses the AWS IAM mechanisms: Web identity
+	// tokens (IRSA), EKS pod identity / ECS container credentials and
+	// the EC2 instance metadata service
+	CredentialsSourceIAM = "iam"
+	// CredentialsSourceStatic uses StorageAccessKeyID and
+	// StorageSecretAccessKey
+	CredentialsSourceStatic = "static"
+
+	assumeRoleSessionName = "db-backup-runner"
+)
+
+type (
+	// assumeRoleProvider assumes a role using the credentials of the
+	// base provider, which might be temporary credentials themselves
+	assumeRoleProvider struct {
+		credentials.Expiry
+
+		base *credentials.Credentials
+		role credentials.STSAssumeRole
+	}
+)
+
+var _ credentials.Provider = (*assumeRoleProvider)(nil)
+
+// Retrieve implements the credentials.Provider interface
+func (a *assumeRoleProvider) Retrieve() (credentials.Value, error) {
+	base, err := a.base.Get()
+	if err != nil {
+		return credentials.Value{}, errors.Wrap(err, "getting base credentials")
+	}
+
+	a.role.Options.AccessKey = base.AccessKeyID
+	a.role.Options.SecretKey = base.SecretAccessKey
+	a.role.Options.SessionToken = base.SessionToken
+
+	v, err := a.role.Retrieve()
+	if err != nil {
+		return credentials.Value{}, errors.Wrap(err, "assuming role")
+	}
+
+	a.SetExpiration(v.Expiration, credentials.DefaultExpiryWindow)
+	return v, nil
+}
+
+func newCredentials(storageLocation *v1.DatabaseBackupStorageLocation) (*credentials.Credentials, error) {
+	var creds *credentials.Credentials
+
+	switch storageLocation.StorageCredentialsSource {
+	case "", CredentialsSourceStatic:
+		creds = credentials.NewStaticV4(storageLocation.StorageAccessKeyID.Value, storageLocation.StorageSecretAccessKey.Value, "")
+
+	case CredentialsSourceChain:
+		var providers []credentials.Provider
+		if storageLocation.StorageAccessKeyID.Value != "" {
+			providers = append(providers, &credentials.Static{Value: credentials.Value{
+				AccessKeyID:     storageLocation.StorageAccessKeyID.Value,
+				SecretAccessKey: storageLocation.StorageSecretAccessKey.Value,
+				SignerType:      credentials.SignatureV4,
+			}})
+		}
+
+		creds = credentials.NewChainCredentials(append(providers,
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.IAM{Client: &http.Client{Transport: http.DefaultTransport}},
+		))
+
+	case CredentialsSourceEnv:
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+		})
+
+	case CredentialsSourceIAM:
+		creds = credentials.NewIAM("")
+
+	default:
+		return nil, errors.Errorf("unknown credentials source %q", storageLocation.StorageCredentialsSource)
+	}
+
+	if storageLocation.StorageAssumeRoleARN == "" {
+		return creds, nil
+	}
+
+	return credentials.New(&assumeRoleProvider{
+		base: creds,
+		role: credentials.STSAssumeRole{
+			Client:      &http.Client{Transport: http.DefaultTransport},
+			STSEndpoint: stsEndpoint(storageLocation),
+			Options: credentials.STSAssumeRoleOptions{
+				Location:        storageLocation.StorageLocation,
+				RoleARN:         storageLocation.StorageAssumeRoleARN,
+				RoleSessionName: assumeRoleSessionName,
+			},
+		},
+	}), nil
+}
+
+func stsEndpoint(storageLocation *v1.DatabaseBackupStorageLocation) string {
+	switch {
+	case storageLocation.StorageSTSEndpoint != "":
+		return storageLocation.StorageSTSEndpoint
+
+	case storageLocation.StorageLocation != "":
+		return fmt.Sprintf("https://sts.%s.amazonaws.com", storageLocation.StorageLocation)
+
+	default:
+		return "https://sts.amazonaws.com"
+	}
+}
@@ -0,0 +1,92 @@
+package s3
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+)
+
+func TestNewCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "env-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+
+	for source, expectKey := range map[string]string{
+		"":                      "static-key",
+		CredentialsSourceStatic: "static-key",
+		CredentialsSourceChain:  "static-key",
+		CredentialsSourceEnv:    "env-key",
+	} {
+		creds, err := newCredentials(&v1.DatabaseBackupStorageLocation{
+			StorageAccessKeyID:       v1.Secret{Value: "static-key"},
+			StorageSecretAccessKey:   v1.Secret{Value: "static-secret"},
+			StorageCredentialsSource: source,
+		})
+		require.NoError(t, err, source)
+
+		v, err := creds.Get()
+		require.NoError(t, err, source)
+		assert.Equal(t, expectKey, v.AccessKeyID, source)
+	}
+
+	// Chain without static credentials falls through to env
+	creds, err := newCredentials(&v1.DatabaseBackupStorageLocation{StorageCredentialsSource: CredentialsSourceChain})
+	require.NoError(t, err)
+
+	v, err := creds.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "env-key", v.AccessKeyID)
+
+	_, err = newCredentials(&v1.DatabaseBackupStorageLocation{StorageCredentialsSource: "magic"})
+	assert.Error(t, err)
+}
+
+func TestNewCredentialsAssumeRole(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "env-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	t.Setenv("AWS_SESSION_TOKEN", "env-token")
+
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "AssumeRole", r.Form.Get("Action"))
+		assert.Equal(t, "arn:aws:iam::123456789012:role/backup", r.Form.Get("RoleArn"))
+		assert.Equal(t, "env-token", r.Header.Get("X-Amz-Security-Token"))
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=env-key/")
+
+		_, err := fmt.Fprintf(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult><Credentials>`+
+			`<AccessKeyId>role-key</AccessKeyId><SecretAccessKey>role-secret</SecretAccessKey>`+
+			`<SessionToken>role-token</SessionToken><Expiration>%s</Expiration>`+
+			`</Credentials></AssumeRoleResult></AssumeRoleResponse>`, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(sts.Close)
+
+	creds, err := newCredentials(&v1.DatabaseBackupStorageLocation{
+		StorageCredentialsSource: CredentialsSourceEnv,
+		StorageAssumeRoleARN:     "arn:aws:iam::123456789012:role/backup",
+		StorageSTSEndpoint:       sts.URL,
+	})
+	require.NoError(t, err)
+
+	v, err := creds.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "role-key", v.AccessKeyID)
+	assert.Equal(t, "role-secret", v.SecretAccessKey)
+	assert.Equal(t, "role-token", v.SessionToken)
+	assert.False(t, creds.IsExpired())
+}
+
+func TestSTSEndpoint(t *testing.T) {
+	assert.Equal(t, "https://sts.amazonaws.com", stsEndpoint(&v1.DatabaseBackupStorageLocation{}))
+	assert.Equal(t, "https://sts.eu-west-1.amazonaws.com", stsEndpoint(&v1.DatabaseBackupStorageLocation{StorageLocation: "eu-west-1"}))
+	assert.Equal(t, "http://minio:9000", stsEndpoint(&v1.DatabaseBackupStorageLocation{
+		StorageLocation:    "eu-west-1",
+		StorageSTSEndpoint: "http://minio:9000",
+	}))
+}
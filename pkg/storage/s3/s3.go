@@ -7,21 +7,21 @@ import (
 	"crypto/tls"
 	"io"
 	"net/http"
-	"os"
 	"path"
 	"strings"
 	"time"
 
 	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/pkg/errors"
 
 	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
 	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+	"github.com/NectGmbH/db-backup-controller/pkg/manifest"
 	"github.com/NectGmbH/db-backup-controller/pkg/storage/helper"
 )
 
 const (
+	errCodeNoSuchKey            = "NoSuchKey"
 	labelmanagerStorageFileName = ".labels"
 	minioMaxIdleConns           = 10
 	minioIdleConnTimeout        = 30 * time.Second
@@ -32,21 +32,32 @@ type (
 	// Storage implements the storage.Manager interface to provide
 	// backup storage access in S3 compatible (S3, GCS, MinIO) storages
 	Storage struct {
+		helper.LabeledStorage
+
 		client *minio.Client
 
 		config          *v1.DatabaseBackupSpec
 		storageLocation *v1.DatabaseBackupStorageLocation
 
 		storagePath string
-
-		labels *labelmanager.Manager
 	}
 )
 
+var _ helper.Backend = Storage{}
+
 // New creates a new S3 Storage
-func New(ctx context.Context, storageLocation *v1.DatabaseBackupStorageLocation, cfg *v1.DatabaseBackup) (*Storage, error) {
+func New(_ context.Context, storageLocation *v1.DatabaseBackupStorageLocation, cfg *v1.DatabaseBackup) (*Storage, error) {
+	if storageLocation.StorageObjectLockMode != "" && !objectLockMode(storageLocation).IsValid() {
+		return nil, errors.Errorf("invalid object lock mode %q", storageLocation.StorageObjectLockMode)
+	}
+
+	creds, err := newCredentials(storageLocation)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating credentials")
+	}
+
 	minioOpts := &minio.Options{
-		Creds:  credentials.NewStaticV4(storageLocation.StorageAccessKeyID.Value, storageLocation.StorageSecretAccessKey.Value, ""),
+		Creds:  creds,
 		Secure: storageLocation.StorageUseSSL,
 		Region: storageLocation.StorageLocation,
 	}
@@ -75,259 +86,279 @@ func New(ctx context.Context, storageLocation *v1.DatabaseBackupStorageLocation,
 
 		storagePath: strings.Join([]string{cfg.Namespace, cfg.Name}, "-"),
 	}
+	stor.LabeledStorage = helper.LabeledStorage{Backend: stor, Config: stor.config, StoragePath: stor.storagePath}
+
+	return stor, nil
+}
 
-	if cfg.Spec.UseSingleBackupTarget {
-		return stor, nil
+// DownloadAsReader fetches the given backup (must exist) and
+// returns an io.ReadCloser for it
+func (s Storage) DownloadAsReader(ctx context.Context, name string) (helper.ReaderAtCloser, int64, error) { //nolint:ireturn,lll // Interface is expecting this
+	obj, err := s.client.GetObject(ctx, s.storageLocation.StorageBucket, s.objectName(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "getting stored object")
+	}
+
+	// GetObject does not error when object does not exist, Stat does.
+	stat, err := obj.Stat()
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "getting stored object stat")
 	}
 
-	stor.labels, err = stor.loadLabelManager(ctx)
-	return stor, errors.Wrap(err, "loading label manager storage")
+	return obj, stat.Size, nil
 }
 
-// CleanupBackups takes care of removing expired backups from the
-// remote storage
-func (s Storage) CleanupBackups(ctx context.Context) (err error) {
-	if s.config.UseSingleBackupTarget {
-		// We don't clean that up.
-		return nil
+// Pin protects the given backup from being removed by the cleanup
+// until the given point in time (forever for the zero time). With
+// object lock enabled the backup is additionally put on legal hold.
+func (s Storage) Pin(ctx context.Context, backup, reason string, until time.Time) error {
+	if err := s.LabeledStorage.Pin(ctx, backup, reason, until); err != nil {
+		return err
 	}
 
-	// Let the label manager clean itself up
-	s.labels.CleanRetentions()
-
-	// Now we get all entries which should no longer exist and make sure they don't
-	for _, entry := range s.labels.GetUnretainedEntries() {
-		if err = s.client.RemoveObject(
-			ctx,
-			s.storageLocation.StorageBucket,
-			path.Join(s.storagePath, entry),
-			minio.RemoveObjectOptions{},
-		); err != nil {
-			return errors.Wrap(err, "deleting expired backup")
-		}
+	return s.setLegalHold(ctx, backup, "", minio.LegalHoldEnabled)
+}
 
-		// We've gotten rid of it, remove the knowledge about it
-		s.labels.Remove(entry)
+// Unpin removes the pin of the given backup and releases its legal
+// hold if object lock is enabled
+func (s Storage) Unpin(ctx context.Context, backup string) error {
+	if err := s.LabeledStorage.Unpin(ctx, backup); err != nil {
+		return err
 	}
 
-	for _, entry := range s.labels.GetRetainedEntries() {
-		_, err := s.client.StatObject(
-			ctx,
-			s.storageLocation.StorageBucket,
-			path.Join(s.storagePath, entry),
-			minio.StatObjectOptions{},
-		)
-		if err == nil {
-			// We got a stat, that object is there, skip
-			continue
-		}
+	return s.setLegalHold(ctx, backup, "", minio.LegalHoldDisabled)
+}
 
-		errResponse := minio.ToErrorResponse(err)
-		if errResponse.Code == "NoSuchKey" {
-			// Well, that backup is for sure gone...
-			s.labels.Remove(entry)
-			continue
+// ListEntries implements the labelmanager.Store interface listing
+// the objects inside the storage path
+func (s Storage) ListEntries(ctx context.Context) ([]string, error) {
+	var entries []string
+	for obj := range s.client.ListObjects(ctx, s.storageLocation.StorageBucket, minio.ListObjectsOptions{Prefix: s.storagePath + "/"}) {
+		if obj.Err != nil {
+			return nil, errors.Wrap(obj.Err, "listing objects")
 		}
-		return errors.Wrap(err, "fetching object stats for backup")
+
+		entries = append(entries, path.Base(obj.Key))
 	}
 
-	// And finally we store the state back to the bucket
-	return errors.Wrap(
-		s.saveLabelManager(ctx),
-		"storing label manager content",
-	)
+	return entries, nil
 }
 
-// DownloadAsReader fetches the given backup (must exist) and
-// returns an io.ReadCloser for it
-func (s Storage) DownloadAsReader(ctx context.Context, name string) (helper.ReaderAtCloser, int64, error) { //nolint:ireturn,lll // Interface is expecting this
-	sourceName := path.Join(s.storagePath, name)
-	if s.config.UseSingleBackupTarget {
-		sourceName = s.storagePath
-	}
-
-	obj, err := s.client.GetObject(ctx, s.storageLocation.StorageBucket, sourceName, minio.GetObjectOptions{})
+// ReadLabels implements the labelmanager.Store interface using the
+// ETag of the label object as version
+func (s Storage) ReadLabels(ctx context.Context) (io.ReadCloser, string, error) {
+	obj, err := s.client.GetObject(
+		ctx,
+		s.storageLocation.StorageBucket,
+		path.Join(s.storagePath, labelmanagerStorageFileName),
+		minio.GetObjectOptions{},
+	)
 	if err != nil {
-		return nil, 0, errors.Wrap(err, "getting stored object")
+		return nil, "", errors.Wrap(err, "fetching label storage object")
 	}
 
 	// GetObject does not error when object does not exist, Stat does.
-	stat, err := obj.Stat()
+	info, err := obj.Stat()
 	if err != nil {
-		return nil, 0, errors.Wrap(err, "getting stored object stat")
+		obj.Close() //nolint:errcheck,gosec // Object is not used, error is not relevant
+
+		if minio.ToErrorResponse(err).Code == errCodeNoSuchKey {
+			return nil, "", nil
+		}
+		return nil, "", errors.Wrap(err, "getting label storage object stat")
 	}
 
-	return obj, stat.Size, nil
+	return obj, info.ETag, nil
 }
 
-// DownloadToFile fetches the given backup (must exist) and saves
-// it to the given targetPath
-func (s Storage) DownloadToFile(ctx context.Context, name, targetPath string) error {
-	f, err := os.Create(targetPath) //#nosec:G304 // This library is intended to write to passed file
-	if err != nil {
-		return errors.Wrap(err, "creating target file")
+// WriteLabels implements the labelmanager.Store interface using
+// conditional writes on the ETag of the label object
+func (s Storage) WriteLabels(ctx context.Context, content io.Reader, version string) error {
+	opts := minio.PutObjectOptions{}
+	if version == "" {
+		opts.SetMatchETagExcept("*")
+	} else {
+		opts.SetMatchETag(version)
 	}
-	defer f.Close() //nolint:errcheck // This might leak FDs but this is a library and should not log
 
-	obj, _, err := s.DownloadAsReader(ctx, name)
+	// The labels are small, read them to have the size available for
+	// a single (and therefore atomic) PUT request
+	data, err := io.ReadAll(content)
 	if err != nil {
-		return errors.Wrap(err, "getting reader for objectg")
+		return errors.Wrap(err, "reading label content")
 	}
-	defer obj.Close() //nolint:errcheck // This might leak FDs but this is a library and should not log
 
-	_, err = io.Copy(f, obj)
-	return errors.Wrap(err, "copying object to target file")
+	err = s.uploadFromReader(ctx, path.Join(s.storagePath, labelmanagerStorageFileName), bytes.NewReader(data), int64(len(data)), opts)
+	switch minio.ToErrorResponse(errors.Cause(err)).Code {
+	case "PreconditionFailed", "ConditionalRequestConflict":
+		return labelmanager.ErrVersionConflict
+	default:
+		return err
+	}
 }
 
-// DownloadPITBackupAsReader takes a Point-in-Time, fetches the
-// closest older backup to that point-in-time and returns an
-// io.ReadCloser for it
-func (s Storage) DownloadPITBackupAsReader(ctx context.Context, pit time.Time) (helper.ReaderAtCloser, int64, error) { //nolint:ireturn,lll // Interface is expecting this
-	if s.config.UseSingleBackupTarget {
-		// There is no point in time, take the object or don't
-		return s.DownloadAsReader(ctx, "")
-	}
+// EntryExists checks whether the object for the given entry exists
+func (s Storage) EntryExists(ctx context.Context, name string) (bool, error) {
+	_, err := s.client.StatObject(ctx, s.storageLocation.StorageBucket, s.objectName(name), minio.StatObjectOptions{})
+	switch {
+	case err == nil:
+		return true, nil
 
-	backup, err := s.labels.GetClosestOlderBackup(pit)
-	if err != nil {
-		return nil, 0, errors.Wrap(err, "finding backup for given time")
-	}
+	case minio.ToErrorResponse(err).Code == errCodeNoSuchKey:
+		return false, nil
 
-	return s.DownloadAsReader(ctx, backup)
+	default:
+		return false, errors.Wrap(err, "fetching object stats for backup")
+	}
 }
 
-// DownloadPITBackupToFile takes a Point-in-Time and downloads the
-// closest older backup to that point-in-time to thhe given path
-func (s Storage) DownloadPITBackupToFile(ctx context.Context, pit time.Time, targetPath string) error {
-	if s.config.UseSingleBackupTarget {
-		// There is no point in time, take the object or don't
-		return s.DownloadToFile(ctx, "", targetPath)
+// backupPutOptions creates the options to upload the given backup
+// with the object lock retention derived from the labels it will get
+func (s Storage) backupPutOptions(ctx context.Context, name string) (minio.PutObjectOptions, error) {
+	if s.storageLocation.StorageObjectLockMode == "" {
+		return minio.PutObjectOptions{}, nil
 	}
 
-	backup, err := s.labels.GetClosestOlderBackup(pit)
+	// Labels are only added after the upload succeeded, so we need to
+	// predict them on the current state
+	labels, err := labelmanager.Load(ctx, s, s.config.RetentionPolicy())
 	if err != nil {
-		return errors.Wrap(err, "finding backup for given time")
+		return minio.PutObjectOptions{}, errors.Wrap(err, "loading labels")
 	}
 
-	return s.DownloadToFile(ctx, backup, targetPath)
-}
-
-// ListAvailableBackups fetches a list of backups stored on the
-// remote storage and returns the names suitable for DownloadToFile
-func (s Storage) ListAvailableBackups(ctx context.Context) ([]string, error) {
-	if s.config.UseSingleBackupTarget {
-		// Special case: There can be only one - or none - lets see what's the case
-		info, err := s.client.StatObject(ctx, s.storageLocation.StorageBucket, s.storagePath, minio.StatObjectOptions{})
-		if err != nil {
-			var mErr minio.ErrorResponse
-			if ok := errors.As(err, &mErr); ok && mErr.StatusCode == http.StatusNotFound {
-				// There is no backup but that's fine
-				return nil, nil
-			}
-
-			return nil, errors.Wrap(err, "checking object existence")
-		}
-		return []string{info.Key}, nil
+	if err = labels.Add(name); err != nil {
+		return minio.PutObjectOptions{}, errors.Wrap(err, "adding to label manager")
 	}
 
-	// Not a single target backup, lets ask the label manager
-	return s.labels.GetRetainedEntries(), nil
+	return retentionPutOptions(objectLockMode(s.storageLocation), labels.RetainedUntil(name), time.Now()), nil
 }
 
-// UploadFromFile takes a local file and uploads the contents under
-// the filename the file on the filesystem has
-func (s Storage) UploadFromFile(ctx context.Context, filePath string) error {
-	f, err := os.Open(filePath) //#nosec:G304 // This library is intended to work with the given file
-	if err != nil {
-		return errors.Wrap(err, "opening source file")
+// retentionPutOptions creates the options to lock an upload until
+// the given date. S3 rejects uploads with a retention date not in the
+// future, so backups only retained by count or not getting any label
+// are uploaded without lock.
+func retentionPutOptions(mode minio.RetentionMode, until, now time.Time) minio.PutObjectOptions {
+	if !until.After(now) {
+		return minio.PutObjectOptions{}
 	}
-	defer f.Close() //nolint:errcheck // This might leak FDs but this is a library and should not log
 
-	stat, err := f.Stat()
-	if err != nil {
-		return errors.Wrap(err, "getting file-stat")
+	return minio.PutObjectOptions{
+		Mode:            mode,
+		RetainUntilDate: until,
+		// Uploads with retention require an integrity checksum
+		SendContentMd5: true,
 	}
-
-	return s.UploadFromReader(ctx, path.Base(filePath), f, stat.Size())
 }
 
-// UploadFromReader takes a name and a reader to upload a backup
-// to the remote storage. The name is used as storage name and
-// later available in ListAvailableBackups and DownloadToFile
-func (s Storage) UploadFromReader(ctx context.Context, name string, data io.Reader, size int64) (err error) {
-	if err = s.uploadFromReader(ctx, name, data, size); err != nil {
-		return err
+// UploadEntry stores the data as the object for the given entry.
+// Backups are locked until their retention expires if object lock is
+// enabled.
+func (s Storage) UploadEntry(ctx context.Context, name string, data io.Reader, size int64) error {
+	var (
+		opts minio.PutObjectOptions
+		err  error
+	)
+
+	switch {
+	case s.config.UseSingleBackupTarget:
+		// Not using label manager, therefore no retention
+
+	case strings.HasSuffix(name, manifest.Suffix):
+		opts.ContentType = "application/json"
+
+	default:
+		if opts, err = s.backupPutOptions(ctx, name); err != nil {
+			return err
+		}
 	}
 
+	return s.uploadFromReader(ctx, s.objectName(name), data, size, opts)
+}
+
+// objectName returns the name of the object storing the given entry
+func (s Storage) objectName(name string) string {
 	if s.config.UseSingleBackupTarget {
-		// Not using label manager
-		return nil
+		return s.storagePath
 	}
 
-	// Add the new file to the label manager
-	if err = s.labels.Add(name); err != nil {
-		return errors.Wrap(err, "adding to label manager")
+	return path.Join(s.storagePath, name)
+}
+
+func (s Storage) uploadFromReader(ctx context.Context, name string, data io.Reader, size int64, opts minio.PutObjectOptions) error {
+	_, err := s.client.PutObject(ctx, s.storageLocation.StorageBucket, name, data, size, opts)
+	if err != nil {
+		return errors.Wrap(err, "uploading object")
 	}
 
-	// And finally we store the state back to the bucket
-	return errors.Wrap(
-		s.saveLabelManager(ctx),
-		"storing label manager content",
-	)
+	return nil
 }
 
-func (s Storage) loadLabelManager(ctx context.Context) (*labelmanager.Manager, error) {
-	var content io.Reader
-	obj, err := s.client.GetObject(
-		ctx,
-		s.storageLocation.StorageBucket,
-		path.Join(s.storagePath, labelmanagerStorageFileName),
-		minio.GetObjectOptions{},
-	)
-	if err != nil {
-		return nil, errors.Wrap(err, "fetching label storage object")
+// RemoveEntry deletes the object for the given entry and reports
+// false if the deletion was prevented by an object lock retention.
+// With object lock enabled the bucket is versioned, so the version is
+// deleted instead of placing a delete-marker on top of it.
+func (s Storage) RemoveEntry(ctx context.Context, entry string) (removed bool, err error) {
+	name := s.objectName(entry)
+
+	if s.storageLocation.StorageObjectLockMode == "" {
+		return true, errors.Wrap(
+			s.client.RemoveObject(ctx, s.storageLocation.StorageBucket, name, minio.RemoveObjectOptions{}),
+			"removing object",
+		)
 	}
 
-	// GetObject does not error when object does not exist, Stat does.
-	if _, err = obj.Stat(); err != nil {
-		var mErr minio.ErrorResponse
-		if ok := errors.As(err, &mErr); ok && mErr.Code == "NoSuchKey" {
-			goto parseAndReturn
+	info, err := s.client.StatObject(ctx, s.storageLocation.StorageBucket, name, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == errCodeNoSuchKey {
+			// Already gone
+			return true, nil
 		}
+		return false, errors.Wrap(err, "fetching object stats")
+	}
 
-		return nil, errors.Wrap(err, "getting label storage object stat")
+	// Pins are mirrored into legal holds and the backup is no longer
+	// pinned when it is to be removed (i.e. the pin expired)
+	if err = s.setLegalHold(ctx, entry, info.VersionID, minio.LegalHoldDisabled); err != nil {
+		return false, err
 	}
 
-	content = obj
+	_, until, err := s.client.GetObjectRetention(ctx, s.storageLocation.StorageBucket, name, info.VersionID)
+	switch {
+	case err == nil:
+		if until != nil && until.After(time.Now()) {
+			// The object lock does not allow us to delete the backup yet
+			return false, nil
+		}
 
-parseAndReturn:
-	lm, err := labelmanager.New(content, s.config.RetentionConfig)
-	return lm, errors.Wrap(err, "initializing label manager")
-}
+	case minio.ToErrorResponse(err).Code == "NoSuchObjectLockConfiguration":
+		// Object has no retention, we can delete it
 
-func (s Storage) saveLabelManager(ctx context.Context) (err error) {
-	labelContent := new(bytes.Buffer)
-	if err = s.labels.Save(labelContent); err != nil {
-		return errors.Wrap(err, "serializing label manager content")
+	default:
+		return false, errors.Wrap(err, "fetching object retention")
 	}
 
-	return errors.Wrap(
-		s.uploadFromReader(ctx, labelmanagerStorageFileName, labelContent, int64(labelContent.Len())),
-		"storing label manager content",
+	return true, errors.Wrap(
+		s.client.RemoveObject(ctx, s.storageLocation.StorageBucket, name, minio.RemoveObjectOptions{VersionID: info.VersionID}),
+		"removing object version",
 	)
 }
 
-//revive:disable-next-line:confusing-naming // That's the implementation, naming is intended to be the same
-func (s Storage) uploadFromReader(ctx context.Context, name string, data io.Reader, size int64) error {
-	targetName := path.Join(s.storagePath, name)
-	if s.config.UseSingleBackupTarget {
-		targetName = s.storagePath
+// setLegalHold sets the legal hold status of the given backup (the
+// latest version for an empty versionID) if object lock is enabled
+func (s Storage) setLegalHold(ctx context.Context, entry, versionID string, status minio.LegalHoldStatus) error {
+	if s.storageLocation.StorageObjectLockMode == "" {
+		return nil
 	}
 
-	_, err := s.client.PutObject(ctx, s.storageLocation.StorageBucket, targetName, data, size, minio.PutObjectOptions{})
-	if err != nil {
-		return errors.Wrap(err, "uploading object")
-	}
+	return errors.Wrap(
+		s.client.PutObjectLegalHold(ctx, s.storageLocation.StorageBucket, path.Join(s.storagePath, entry), minio.PutObjectLegalHoldOptions{
+			VersionID: versionID,
+			Status:    &status,
+		}),
+		"setting legal hold",
+	)
+}
 
-	return nil
+func objectLockMode(storageLocation *v1.DatabaseBackupStorageLocation) minio.RetentionMode {
+	return minio.RetentionMode(strings.ToUpper(storageLocation.StorageObjectLockMode))
 }
@@ -0,0 +1,110 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/NectGmbH/db-backup-controller/pkg/apis/v1"
+	"github.com/NectGmbH/db-backup-controller/pkg/labelmanager"
+)
+
+func TestRetentionPutOptions(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	opts := retentionPutOptions(minio.Governance, now.Add(time.Hour), now)
+	assert.Equal(t, minio.Governance, opts.Mode)
+	assert.Equal(t, now.Add(time.Hour), opts.RetainUntilDate)
+	assert.True(t, opts.SendContentMd5)
+
+	// No label (zero date) and expired dates must not be sent to S3
+	for _, until := range []time.Time{{}, now.Add(-time.Hour), now} {
+		opts = retentionPutOptions(minio.Compliance, until, now)
+		assert.Empty(t, opts.Mode, until)
+		assert.True(t, opts.RetainUntilDate.IsZero(), until)
+	}
+}
+
+func TestWriteLabels(t *testing.T) {
+	var (
+		ctx = context.Background()
+		cfg = &v1.DatabaseBackup{}
+
+		status  int
+		code    string
+		request *http.Request
+	)
+	cfg.Name, cfg.Namespace = "db", "test"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+
+		if status == http.StatusOK {
+			w.Header().Set("ETag", `"new"`)
+			w.WriteHeader(status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "<Error><Code>%s</Code><Message>stub</Message></Error>", code)
+	}))
+	t.Cleanup(srv.Close)
+
+	stor, err := New(ctx, &v1.DatabaseBackupStorageLocation{
+		StorageType:            "s3",
+		StorageEndpoint:        strings.TrimPrefix(srv.URL, "http://"),
+		StorageAccessKeyID:     v1.Secret{Value: "key"},
+		StorageSecretAccessKey: v1.Secret{Value: "secret"},
+		StorageBucket:          "backups",
+		StorageLocation:        "us-east-1",
+	}, cfg)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		status  int
+		code    string
+		version string
+		expect  error
+	}{
+		{http.StatusOK, "", "", nil},
+		{http.StatusOK, "", "old", nil},
+		{http.StatusPreconditionFailed, "PreconditionFailed", "", labelmanager.ErrVersionConflict},
+		{http.StatusPreconditionFailed, "PreconditionFailed", "old", labelmanager.ErrVersionConflict},
+		{http.StatusConflict, "ConditionalRequestConflict", "old", labelmanager.ErrVersionConflict},
+	} {
+		status, code = tc.status, tc.code
+
+		err = stor.WriteLabels(ctx, strings.NewReader("{}"), tc.version)
+		if tc.expect == nil {
+			assert.NoError(t, err, tc)
+		} else {
+			assert.ErrorIs(t, err, tc.expect, tc)
+		}
+
+		require.NotNil(t, request)
+		assert.Equal(t, http.MethodPut, request.Method)
+		assert.Equal(t, "/backups/test-db/.labels", request.URL.Path)
+
+		// The write must be conditional on the label object state
+		if tc.version == "" {
+			assert.Equal(t, "*", request.Header.Get("If-None-Match"), tc)
+		} else {
+			assert.Equal(t, `"old"`, request.Header.Get("If-Match"), tc)
+		}
+	}
+
+	// Other errors must be passed through
+	status, code = http.StatusForbidden, "AccessDenied"
+	err = stor.WriteLabels(ctx, strings.NewReader("{}"), "old")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, labelmanager.ErrVersionConflict)
+}